@@ -0,0 +1,85 @@
+// Copyright 2025 Brandon Epperson
+// SPDX-License-Identifier: Apache-2.0
+
+package roxi
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// AccessLogOption configures the AccessLog middleware.
+type AccessLogOption func(*accessLog)
+
+// WithAccessLogRemoteAddr adds the remote address of the request
+// to the access log as "remote_addr".
+func WithAccessLogRemoteAddr() AccessLogOption {
+	return func(a *accessLog) {
+		a.remoteAddr = true
+	}
+}
+
+// WithAccessLogUserAgent adds the User-Agent header of the request
+// to the access log as "user_agent".
+func WithAccessLogUserAgent() AccessLogOption {
+	return func(a *accessLog) {
+		a.userAgent = true
+	}
+}
+
+// accessLog holds the configuration of an AccessLog middleware.
+type accessLog struct {
+	remoteAddr bool
+	userAgent  bool
+}
+
+// AccessLog returns a middleware that logs each request with logfn as
+// "roxi: request", followed by the method, path, matched pattern, status,
+// duration and bytes written by the handler as key value pairs.
+//
+// The signature of logfn matches slog.Info and friends:
+//
+//	mux := roxi.New(roxi.WithMiddleware(roxi.AccessLog(slog.Info)))
+//
+// Requests whose handler returns an error are logged with the error,
+// and the status the mux responds with, see Metrics.
+func AccessLog(logfn func(msg string, args ...any), opts ...AccessLogOption) MiddlewareFunc {
+	cfg := &accessLog{}
+	for _, o := range opts {
+		o(cfg)
+	}
+
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, r *http.Request) error {
+			start := time.Now()
+
+			ctx, stats := WrapWriter(ctx)
+			err := next(ctx, r)
+
+			args := []any{
+				"method", r.Method,
+				"path", r.URL.Path,
+				"pattern", Pattern(ctx, r),
+				"status", stats.status(err),
+				"duration", time.Since(start),
+				"bytes", stats.Bytes,
+			}
+
+			if cfg.remoteAddr {
+				args = append(args, "remote_addr", r.RemoteAddr)
+			}
+
+			if cfg.userAgent {
+				args = append(args, "user_agent", r.UserAgent())
+			}
+
+			if err != nil {
+				args = append(args, "error", err)
+			}
+
+			logfn("roxi: request", args...)
+			return err
+		}
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2025 Brandon Epperson
+// SPDX-License-Identifier: Apache-2.0
+
+package roxi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_AccessLog(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   []AccessLogOption
+		path   string
+		want   map[string]string
+		absent []string
+	}{
+		{
+			"Default",
+			nil,
+			"/users/42",
+			map[string]string{
+				"method":  "GET",
+				"path":    "/users/42",
+				"pattern": "/users/:id",
+				"status":  "200",
+				"bytes":   "4",
+			},
+			[]string{"remote_addr", "user_agent", "error"},
+		},
+		{
+			"Error",
+			nil,
+			"/error",
+			map[string]string{
+				"pattern": "/error",
+				"status":  "500",
+				"bytes":   "0",
+				"error":   "failed",
+			},
+			nil,
+		},
+		{
+			"Options",
+			[]AccessLogOption{WithAccessLogRemoteAddr(), WithAccessLogUserAgent()},
+			"/users/42",
+			map[string]string{
+				"remote_addr": "192.0.2.1:1234",
+				"user_agent":  "roxi-test",
+			},
+			[]string{"error"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var msg string
+			fields := make(map[string]string)
+			logfn := func(m string, args ...any) {
+				msg = m
+				for i := 0; i+1 < len(args); i += 2 {
+					fields[fmt.Sprint(args[i])] = fmt.Sprint(args[i+1])
+				}
+			}
+
+			mux := New(WithMiddleware(AccessLog(logfn, tt.opts...)))
+			mux.GET("/users/:id", func(ctx context.Context, r *http.Request) error {
+				_, err := GetWriter(ctx).Write([]byte("user"))
+				return err
+			})
+			mux.GET("/error", func(ctx context.Context, r *http.Request) error {
+				return errors.New("failed")
+			})
+
+			req := httptest.NewRequest("GET", tt.path, nil)
+			req.Header.Set("User-Agent", "roxi-test")
+			mux.ServeHTTP(httptest.NewRecorder(), req)
+
+			if msg != "roxi: request" {
+				t.Errorf("expected: [roxi: request]; got: [%s]", msg)
+			}
+
+			for k, v := range tt.want {
+				if fields[k] != v {
+					t.Errorf("expected %s: [%s]; got: [%s]", k, v, fields[k])
+				}
+			}
+
+			for _, k := range tt.absent {
+				if _, ok := fields[k]; ok {
+					t.Errorf("expected %s to be absent", k)
+				}
+			}
+
+			if _, err := time.ParseDuration(fields["duration"]); err != nil {
+				t.Errorf("expected a duration; got: [%s]", fields["duration"])
+			}
+		})
+	}
+}
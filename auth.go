@@ -0,0 +1,134 @@
+// Copyright 2025 Brandon Epperson
+// SPDX-License-Identifier: Apache-2.0
+
+package roxi
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// BasicAuth returns a middleware that authenticates requests with HTTP
+// Basic authentication, calling verify with the credentials of the request.
+//
+// Requests without credentials, or whose credentials are rejected by
+// verify, are aborted with 401 Unauthorized and a WWW-Authenticate header
+// challenging the client for the realm. The handler is not called.
+//
+// verify should compare the credentials in constant time, such as with
+// BasicAuthUsers, to avoid leaking them through response timing.
+func BasicAuth(verify func(user, pass string) bool, realm string) MiddlewareFunc {
+	challenge := "Basic realm=" + strconv.Quote(realm) + `, charset="UTF-8"`
+
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, r *http.Request) error {
+			if user, pass, ok := r.BasicAuth(); ok && verify(user, pass) {
+				return next(ctx, r)
+			}
+
+			return unauthorized(ctx, challenge)
+		}
+	}
+}
+
+// BasicAuthUsers returns a verify function for BasicAuth accepting the
+// users and passwords of creds.
+//
+// Passwords are compared in constant time, and unknown users take as
+// long to reject as wrong passwords.
+func BasicAuthUsers(creds map[string]string) func(user, pass string) bool {
+	sums := make(map[string][sha256.Size]byte, len(creds))
+	for user, pass := range creds {
+		sums[user] = sha256.Sum256([]byte(pass))
+	}
+
+	return func(user, pass string) bool {
+		want, ok := sums[user]
+		got := sha256.Sum256([]byte(pass))
+		return subtle.ConstantTimeCompare(got[:], want[:]) == 1 && ok
+	}
+}
+
+// BearerAuth returns a middleware that authenticates requests with a
+// bearer token from the Authorization header, as described in RFC 6750.
+//
+// verify is called with the token, and returns a context carrying the
+// values of the authenticated client, such as its claims, or false to
+// reject the token. The values of the returned context are available to
+// the handler through its context, along with the values of the request.
+//
+// Requests without a token, or whose token is rejected by verify, are
+// aborted with 401 Unauthorized and a WWW-Authenticate header. The handler
+// is not called.
+func BearerAuth(verify func(token string) (context.Context, bool)) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, r *http.Request) error {
+			token, ok := bearerToken(r)
+			if !ok {
+				return unauthorized(ctx, "Bearer")
+			}
+
+			claims, ok := verify(token)
+			if !ok {
+				return unauthorized(ctx, `Bearer error="invalid_token"`)
+			}
+
+			if claims == nil {
+				return next(ctx, r)
+			}
+
+			v, ok := ctx.(*writerContext)
+			if !ok {
+				return next(&valuesContext{ctx, claims}, r)
+			}
+
+			// swap the values into the mux's context in place, so the
+			// writer and request scoped values remain available.
+			parent := v.Context
+			v.Context = &valuesContext{parent, claims}
+			defer func() { v.Context = parent }()
+
+			return next(v, r)
+		}
+	}
+}
+
+// bearerToken returns the token of a bearer Authorization header.
+func bearerToken(r *http.Request) (string, bool) {
+	const prefix = "Bearer "
+
+	auth := r.Header.Get("Authorization")
+	if len(auth) <= len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) {
+		return "", false
+	}
+
+	token := strings.TrimSpace(auth[len(prefix):])
+	return token, token != ""
+}
+
+// unauthorized sets the WWW-Authenticate challenge of the response and
+// returns an *AbortError responding with 401 Unauthorized.
+func unauthorized(ctx context.Context, challenge string) error {
+	if w := GetWriter(ctx); w != nil {
+		w.Header().Set("WWW-Authenticate", challenge)
+	}
+	return Abort(http.StatusUnauthorized, nil)
+}
+
+// valuesContext is a context whose values are looked up in values
+// before the parent context.
+type valuesContext struct {
+	context.Context
+	values context.Context
+}
+
+func (c *valuesContext) Value(key any) any {
+	if v := c.values.Value(key); v != nil {
+		return v
+	}
+	return c.Context.Value(key)
+}
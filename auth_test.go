@@ -0,0 +1,124 @@
+// Copyright 2025 Brandon Epperson
+// SPDX-License-Identifier: Apache-2.0
+
+package roxi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_BasicAuth(t *testing.T) {
+	tests := []struct {
+		name      string
+		user      string
+		pass      string
+		setAuth   bool
+		code      int
+		challenge string
+	}{
+		{"Valid", "gopher", "secret", true, http.StatusOK, ""},
+		{"WrongPassword", "gopher", "guess", true, http.StatusUnauthorized, `Basic realm="admin", charset="UTF-8"`},
+		{"UnknownUser", "rustacean", "secret", true, http.StatusUnauthorized, `Basic realm="admin", charset="UTF-8"`},
+		{"Missing", "", "", false, http.StatusUnauthorized, `Basic realm="admin", charset="UTF-8"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var called bool
+
+			verify := BasicAuthUsers(map[string]string{"gopher": "secret"})
+			mux := New(WithMiddleware(BasicAuth(verify, "admin")))
+			mux.GET("/", func(ctx context.Context, r *http.Request) error {
+				called = true
+				return nil
+			})
+
+			r := httptest.NewRequest("GET", "/", nil)
+			if tt.setAuth {
+				r.SetBasicAuth(tt.user, tt.pass)
+			}
+			w := httptest.NewRecorder()
+
+			mux.ServeHTTP(w, r)
+
+			if w.Code != tt.code {
+				t.Errorf("expected: [%d]; got: [%d]", tt.code, w.Code)
+			}
+
+			if want := tt.code == http.StatusOK; called != want {
+				t.Errorf("expected handler called: [%v]; got: [%v]", want, called)
+			}
+
+			if h := w.Header().Get("WWW-Authenticate"); h != tt.challenge {
+				t.Errorf("expected: [%s]; got: [%s]", tt.challenge, h)
+			}
+		})
+	}
+}
+
+func Test_BearerAuth(t *testing.T) {
+	type claimsKey struct{}
+
+	verify := func(token string) (context.Context, bool) {
+		if token != "valid" {
+			return nil, false
+		}
+		return context.WithValue(context.Background(), claimsKey{}, "gopher"), true
+	}
+
+	tests := []struct {
+		name      string
+		header    string
+		code      int
+		subject   string
+		challenge string
+	}{
+		{"Valid", "Bearer valid", http.StatusOK, "gopher", ""},
+		{"LowercaseScheme", "bearer valid", http.StatusOK, "gopher", ""},
+		{"Invalid", "Bearer expired", http.StatusUnauthorized, "", `Bearer error="invalid_token"`},
+		{"Empty", "Bearer ", http.StatusUnauthorized, "", "Bearer"},
+		{"OtherScheme", "Basic Z29waGVyOnNlY3JldA==", http.StatusUnauthorized, "", "Bearer"},
+		{"Missing", "", http.StatusUnauthorized, "", "Bearer"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var subject, requestID string
+
+			mux := New(WithMiddleware(RequestID(), BearerAuth(verify)))
+			mux.GET("/", func(ctx context.Context, r *http.Request) error {
+				subject, _ = ctx.Value(claimsKey{}).(string)
+				requestID = GetRequestID(ctx)
+				return nil
+			})
+
+			r := httptest.NewRequest("GET", "/", nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+
+			mux.ServeHTTP(w, r)
+
+			if w.Code != tt.code {
+				t.Errorf("expected: [%d]; got: [%d]", tt.code, w.Code)
+			}
+
+			if subject != tt.subject {
+				t.Errorf("expected: [%s]; got: [%s]", tt.subject, subject)
+			}
+
+			// values of the mux's context remain available.
+			if tt.code == http.StatusOK && requestID == "" {
+				t.Errorf("expected request ID to be set")
+			}
+
+			if h := w.Header().Get("WWW-Authenticate"); h != tt.challenge {
+				t.Errorf("expected: [%s]; got: [%s]", tt.challenge, h)
+			}
+		})
+	}
+}
@@ -34,6 +34,10 @@ func generateRoutes(prefix string, verbs []string) []string {
 
 	for _, v := range verbs {
 		for _, s := range verbs {
+			// path variable names must be unique within a route.
+			if s[0] == ':' && s == v {
+				s += "2"
+			}
 			routes = append(routes, fmt.Sprintf("%s/%s/%s", prefix, v, s))
 		}
 	}
@@ -74,6 +78,147 @@ func Test_MuxRoutingAllocs(t *testing.T) {
 	}
 }
 
+// discardWriter is an http.ResponseWriter that discards everything written.
+type discardWriter struct {
+	header http.Header
+}
+
+func (w *discardWriter) Header() http.Header         { return w.header }
+func (w *discardWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *discardWriter) WriteHeader(int)             {}
+
+func Test_StaticRouteAllocs(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping alloc tests in short mode.")
+	}
+
+	mux := New()
+	mux.Static("GET", "/pixel.gif", []byte("GIF89a"), "image/gif", 0)
+	mux.Static("GET", "/config.json", []byte(`{"debug":false}`), "application/json", 200)
+
+	w := &discardWriter{header: make(http.Header)}
+	req, _ := http.NewRequest("GET", "/", nil)
+
+	for _, path := range []string{"/pixel.gif", "/config.json"} {
+		req.URL.Path = path
+
+		allocs := testing.AllocsPerRun(100, func() { mux.ServeHTTP(w, req) })
+		if allocs > 0 {
+			t.Errorf("mux.ServeHTTP(): expected zero allocs; got [%v]", allocs)
+		}
+	}
+}
+
+func Test_ConstraintRouteAllocs(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping alloc tests in short mode.")
+	}
+
+	mux := New()
+	mux.GET(`/user/:id(\d+)`, func(ctx context.Context, r *http.Request) error { return nil })
+	mux.GET(`/tag/:tag([a-z]+)`, func(ctx context.Context, r *http.Request) error { return nil })
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+
+	for _, path := range []string{"/user/42", "/tag/golang"} {
+		req.URL.Path = path
+
+		allocs := testing.AllocsPerRun(100, func() { mux.ServeHTTP(w, req) })
+		if allocs > 0 {
+			t.Errorf("mux.ServeHTTP(): expected zero allocs; got [%v]", allocs)
+		}
+	}
+}
+
+func Test_WildcardRouteAllocs(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping alloc tests in short mode.")
+	}
+
+	mux := New()
+	mux.GET("/files/*file", func(ctx context.Context, r *http.Request) error {
+		if file := r.PathValue("file"); file != "/css/site.css" {
+			t.Errorf("expected: [/css/site.css]; got: [%s]", file)
+		}
+		return nil
+	})
+	mux.GET("/users/:id/*rest", func(ctx context.Context, r *http.Request) error { return nil })
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+
+	for _, path := range []string{"/files/css/site.css", "/users/42/posts/1"} {
+		req.URL.Path = path
+
+		allocs := testing.AllocsPerRun(100, func() { mux.ServeHTTP(w, req) })
+		if allocs > 0 {
+			t.Errorf("mux.ServeHTTP(): expected zero allocs; got [%v]", allocs)
+		}
+	}
+}
+
+func Test_MatchTimingAllocs(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping alloc tests in short mode.")
+	}
+
+	tests := []struct {
+		name string
+		opts []func(*Mux)
+	}{
+		{"Disabled", nil},
+		{"Enabled", []func(*Mux){WithMatchTiming()}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := New(tt.opts...)
+			mux.GET("/user/:id", func(ctx context.Context, r *http.Request) error { return nil })
+
+			w := &discardWriter{header: make(http.Header)}
+			req, _ := http.NewRequest("GET", "/user/42", nil)
+
+			allocs := testing.AllocsPerRun(100, func() { mux.ServeHTTP(w, req) })
+			if allocs > 0 {
+				t.Errorf("mux.ServeHTTP(): expected zero allocs; got [%v]", allocs)
+			}
+		})
+	}
+}
+
+func Test_ContextValueAllocs(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping alloc tests in short mode.")
+	}
+
+	type userKey struct{}
+	u := &user{Name: "gopher"}
+
+	mux := New(WithMiddleware(func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, r *http.Request) error {
+			return next(WithValue(ctx, userKey{}, u), r)
+		}
+	}))
+	mux.GET("/me", func(ctx context.Context, r *http.Request) error {
+		if Value(ctx, userKey{}) != u {
+			t.Error("value not found in context")
+		}
+		return nil
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/me", nil)
+
+	// the values slice is allocated once per pooled context.
+	mux.ServeHTTP(w, req)
+
+	allocs := testing.AllocsPerRun(100, func() { mux.ServeHTTP(w, req) })
+	if allocs > 0 {
+		t.Errorf("mux.ServeHTTP(): expected zero allocs; got [%v]", allocs)
+	}
+}
+
 func Benchmark_Mux(b *testing.B) {
 	muxes := []struct {
 		name   string
@@ -99,6 +244,12 @@ func Benchmark_Mux(b *testing.B) {
 			http.MethodGet,
 			"/path/banana/banana/banana/terracotta/pie",
 		},
+		{
+			"Wildcard",
+			buildMux(wildcardRoute{}),
+			http.MethodGet,
+			"/files/css/vendor/site.css",
+		},
 		{
 			"NotFound",
 			buildMux(singleRoute{}),
@@ -117,6 +268,18 @@ func Benchmark_Mux(b *testing.B) {
 			http.MethodPost,
 			"/path",
 		},
+		{
+			"MethodNotAllowedParams",
+			buildMux(paramsRoute{}),
+			http.MethodPost,
+			"/path/banana/banana/banana/terracotta/pie",
+		},
+		{
+			"NotFoundAllMethods",
+			buildMux(allbutPOST{}),
+			http.MethodPost,
+			"/foo/bar",
+		},
 		{
 			"OptionsAllMethods",
 			buildMux(allbutPOST{}, WithOptionsHandler(optHandler)),
@@ -136,6 +299,31 @@ func Benchmark_Mux(b *testing.B) {
 	}
 }
 
+func Benchmark_Allowed(b *testing.B) {
+	muxes := []struct {
+		name   string
+		mux    *Mux
+		method string
+		path   string
+	}{
+		{"MethodNotAllowed", buildMux(singleRoute{}), http.MethodPost, "/path"},
+		{"MethodNotAllowedAllMethods", buildMux(allbutPOST{}), http.MethodPost, "/path"},
+		{"MethodNotAllowedParams", buildMux(paramsRoute{}), http.MethodPost, "/path/banana/banana/banana/terracotta/pie"},
+		{"NotFoundAllMethods", buildMux(allbutPOST{}), http.MethodPost, "/foo/bar"},
+		{"NotFoundMany", buildMux(manyRoutes{}), http.MethodPost, "/v1/path/foo/bar"},
+	}
+
+	for _, tt := range muxes {
+		rt := tt.mux.routes.Load()
+		path := []byte(tt.path)
+		b.Run(tt.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_ = tt.mux.allowed(rt, tt.method, path)
+			}
+		})
+	}
+}
+
 func Benchmark_Parallel(b *testing.B) {
 	muxes := []struct {
 		name string
@@ -174,6 +362,12 @@ func (r paramsRoute) Add(mux *Mux) {
 	mux.GET("/path/:foo/:bar/:baz/:qux/:quux", func(ctx context.Context, r *http.Request) error { return nil })
 }
 
+type wildcardRoute struct{}
+
+func (r wildcardRoute) Add(mux *Mux) {
+	mux.GET("/files/*file", func(ctx context.Context, r *http.Request) error { return nil })
+}
+
 type manyRoutes struct{}
 
 func (r manyRoutes) Add(mux *Mux) {
@@ -0,0 +1,482 @@
+// Copyright 2025 Brandon Epperson
+// SPDX-License-Identifier: Apache-2.0
+
+package roxi
+
+import (
+	"bufio"
+	"encoding"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Binder is implemented by types that decode themselves from
+// a request body.
+type Binder interface {
+	Bind(data []byte) error
+}
+
+// StreamBinder is implemented by Binders that decode themselves directly
+// from the request body, without buffering it, such as with a
+// json.Decoder for large payloads.
+type StreamBinder interface {
+	BindReader(r io.Reader) error
+}
+
+// Validator is implemented by types that validate themselves
+// once they have been bound.
+type Validator interface {
+	Validate() error
+}
+
+// Bind reads the request body and decodes it into v.
+//
+// If v implements StreamBinder, the body is passed to BindReader instead
+// of being read into memory first.
+//
+// If v implements Validator, it is validated after decoding.
+func Bind(r *http.Request, v Binder) error {
+	return BindLimit(r, v, 0)
+}
+
+// BindLimit reads at most limit bytes of the request body and decodes it into v.
+// A limit of 0 or less reads the full body, as Bind does.
+//
+// If the body is larger than limit, the returned error wraps an
+// *http.MaxBytesError, which handlers can map to 413 Request Entity Too Large.
+//
+// If v implements Validator, it is validated after decoding.
+func BindLimit(r *http.Request, v Binder, limit int64) error {
+	if sb, ok := v.(StreamBinder); ok {
+		body := r.Body
+		if body == nil {
+			body = http.NoBody
+		}
+		if limit > 0 {
+			body = http.MaxBytesReader(nil, body, limit)
+		}
+
+		if err := sb.BindReader(body); err != nil {
+			return fmt.Errorf("bind: %w", err)
+		}
+		return validate(v)
+	}
+
+	data, err := readBody(r, limit)
+	if err != nil {
+		return err
+	}
+
+	if err := v.Bind(data); err != nil {
+		return fmt.Errorf("bind: %w", err)
+	}
+
+	return validate(v)
+}
+
+// BindJSON reads the request body and decodes it into v with encoding/json.
+//
+// If v implements Validator, it is validated after decoding.
+func BindJSON(r *http.Request, v any) error {
+	if err := decodeJSON(r, v); err != nil {
+		return err
+	}
+	return validate(v)
+}
+
+// BindXML reads the request body and decodes it into v with encoding/xml.
+//
+// If v implements Validator, it is validated after decoding.
+func BindXML(r *http.Request, v any) error {
+	if err := decodeXML(r, v); err != nil {
+		return err
+	}
+	return validate(v)
+}
+
+// BindForm parses the request form and sets the fields of the struct
+// pointed to by v from the values named by their form tag:
+//
+//	type signup struct {
+//		Email string   `form:"email"`
+//		Age   int      `form:"age"`
+//		Tags  []string `form:"tag"`
+//	}
+//
+// Both application/x-www-form-urlencoded and multipart/form-data bodies
+// are parsed, with multipart forms parsed as by ParseMultipartForm with
+// DefaultMultipartMemory. Values of the URL query are bound as well, after
+// the values of the body.
+//
+// Fields may be strings, booleans, numbers, types implementing
+// encoding.TextUnmarshaler, or slices of those. Fields without a form tag,
+// or with the tag "-", are left untouched, and fields of embedded structs
+// are bound as if they were fields of v.
+//
+// If v implements Validator, it is validated after decoding.
+func BindForm(r *http.Request, v any) error {
+	if err := decodeForm(r, v); err != nil {
+		return err
+	}
+	return validate(v)
+}
+
+// BindQuery sets the fields of the struct pointed to by v from the values
+// of the URL query named by their query tag:
+//
+//	type search struct {
+//		Term  string   `query:"q"`
+//		Page  int      `query:"page"`
+//		Sizes []string `query:"size"`
+//	}
+//
+// Fields are set as by BindForm, so repeated parameters are bound to
+// slices. Parameters without a field are ignored.
+//
+// If v implements Validator, it is validated after decoding.
+func BindQuery(r *http.Request, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("bind query: expected a non-nil struct pointer; got: %T", v)
+	}
+
+	query, err := url.ParseQuery(r.URL.RawQuery)
+	if err != nil {
+		return fmt.Errorf("bind query: %w", err)
+	}
+
+	if err := bindForm(rv.Elem(), query, "query"); err != nil {
+		return fmt.Errorf("bind query: %w", err)
+	}
+	return validate(v)
+}
+
+// BindRequest decodes the request body into v with the Decoder
+// registered in Decoders for its media type.
+//
+// The media type is read from the Content-Type header, or sniffed from the
+// body as by SniffContentType. Media types with a "+json" or "+xml" suffix,
+// such as application/problem+json, fall back to the decoder of
+// application/json or application/xml.
+//
+// If no decoder is registered for the media type, the returned error wraps
+// an *AbortError with the code 415 Unsupported Media Type, which the Mux
+// writes as the response if the handler returns it.
+//
+// If v implements Validator, it is validated after decoding.
+func BindRequest(r *http.Request, v any) error {
+	mt, err := SniffContentType(r)
+	if err != nil {
+		return fmt.Errorf("bind: %w", err)
+	}
+
+	decode, ok := Decoders[mt]
+	if !ok {
+		if i := strings.LastIndexByte(mt, '+'); i >= 0 {
+			decode, ok = Decoders["application/"+mt[i+1:]]
+		}
+	}
+
+	if !ok {
+		return fmt.Errorf("bind: unsupported media type %q: %w", mt, Abort(http.StatusUnsupportedMediaType, nil))
+	}
+
+	if err := decode(r, v); err != nil {
+		return err
+	}
+	return validate(v)
+}
+
+// Decoder decodes the body of a request into v.
+type Decoder func(r *http.Request, v any) error
+
+// Decoders maps media types to the Decoder used by BindRequest.
+//
+// Decoders can be added or replaced to support other media types, before
+// any request is served:
+//
+//	roxi.Decoders["application/yaml"] = func(r *http.Request, v any) error {
+//		return yaml.NewDecoder(r.Body).Decode(v)
+//	}
+var Decoders = map[string]Decoder{
+	"application/json":                  decodeJSON,
+	"application/xml":                   decodeXML,
+	"text/xml":                          decodeXML,
+	"application/x-www-form-urlencoded": decodeForm,
+	"multipart/form-data":               decodeForm,
+}
+
+// decodeJSON decodes the request body into v with encoding/json.
+func decodeJSON(r *http.Request, v any) error {
+	data, err := readBody(r, 0)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("bind json: %w", err)
+	}
+	return nil
+}
+
+// decodeXML decodes the request body into v with encoding/xml.
+func decodeXML(r *http.Request, v any) error {
+	data, err := readBody(r, 0)
+	if err != nil {
+		return err
+	}
+
+	if err := xml.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("bind xml: %w", err)
+	}
+	return nil
+}
+
+// decodeForm parses the request form into the struct pointed to by v.
+func decodeForm(r *http.Request, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("bind form: expected a non-nil struct pointer; got: %T", v)
+	}
+
+	mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mt == "multipart/form-data" {
+		if err := ParseMultipartForm(r, 0); err != nil {
+			return fmt.Errorf("bind form: %w", err)
+		}
+	} else if err := r.ParseForm(); err != nil {
+		return fmt.Errorf("bind form: %w", err)
+	}
+
+	if err := bindForm(rv.Elem(), r.Form, "form"); err != nil {
+		return fmt.Errorf("bind form: %w", err)
+	}
+	return nil
+}
+
+// textUnmarshalerType is the reflect type of encoding.TextUnmarshaler.
+var textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+
+// bindForm sets the fields of the struct value rv from form, by the
+// names in their struct tag with the given key.
+func bindForm(rv reflect.Value, form map[string][]string, key string) error {
+	rt := rv.Type()
+	for i := range rt.NumField() {
+		field := rt.Field(i)
+
+		tag, ok := field.Tag.Lookup(key)
+		if !ok && field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if err := bindForm(rv.Field(i), form, key); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !ok || tag == "-" || !field.IsExported() {
+			continue
+		}
+
+		values, ok := form[tag]
+		if !ok || len(values) == 0 {
+			continue
+		}
+
+		if err := setFormField(rv.Field(i), values); err != nil {
+			return fmt.Errorf("field %q: %w", tag, err)
+		}
+	}
+	return nil
+}
+
+// setFormField sets fv from the form values of its field. Slices are
+// set from every value, other types from the first.
+func setFormField(fv reflect.Value, values []string) error {
+	if fv.Kind() == reflect.Slice && !reflect.PointerTo(fv.Type()).Implements(textUnmarshalerType) {
+		s := reflect.MakeSlice(fv.Type(), len(values), len(values))
+		for i, val := range values {
+			if err := setFormValue(s.Index(i), val); err != nil {
+				return err
+			}
+		}
+		fv.Set(s)
+		return nil
+	}
+
+	return setFormValue(fv, values[0])
+}
+
+// setFormValue parses val into fv according to its kind.
+func setFormValue(fv reflect.Value, val string) error {
+	if fv.CanAddr() {
+		if u, ok := fv.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			return u.UnmarshalText([]byte(val))
+		}
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(val)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(val, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(val, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(val, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+	default:
+		return errors.New("unsupported type " + fv.Type().String())
+	}
+	return nil
+}
+
+// readBody reads the request body, limited to limit bytes if it is positive.
+func readBody(r *http.Request, limit int64) ([]byte, error) {
+	if r.Body == nil {
+		return nil, nil
+	}
+
+	body := r.Body
+	if limit > 0 {
+		body = http.MaxBytesReader(nil, body, limit)
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("bind: read body: %w", err)
+	}
+	return data, nil
+}
+
+// validate runs validation if v implements Validator.
+func validate(v any) error {
+	if val, ok := v.(Validator); ok {
+		if err := val.Validate(); err != nil {
+			return fmt.Errorf("validate: %w", err)
+		}
+	}
+	return nil
+}
+
+const (
+	// sniffLen is the number of body bytes inspected when sniffing a content type.
+	sniffLen = 64
+
+	// DefaultMultipartMemory is the maximum number of bytes of a multipart
+	// form stored in memory when no limit is provided.
+	DefaultMultipartMemory = 32 << 20
+)
+
+// ParseMultipartForm parses a multipart request body, storing up to
+// maxMemory bytes of its file parts in memory and the remainder in
+// temporary files on disk.
+//
+// If maxMemory is less than or equal to zero, DefaultMultipartMemory is used.
+//
+// When the request is served by a Mux, temporary files are removed once the
+// handler returns, including when it returns an error or panics.
+func ParseMultipartForm(r *http.Request, maxMemory int64) error {
+	if maxMemory <= 0 {
+		maxMemory = DefaultMultipartMemory
+	}
+
+	if err := r.ParseMultipartForm(maxMemory); err != nil {
+		return fmt.Errorf("parse multipart form: %w", err)
+	}
+	return nil
+}
+
+// removeMultipart removes any temporary files created while parsing
+// a multipart form.
+func removeMultipart(r *http.Request) {
+	if r.MultipartForm != nil {
+		_ = r.MultipartForm.RemoveAll()
+	}
+}
+
+// SniffContentType returns the media type of the request body.
+//
+// If the Content-Type header is present and unambiguous, its media type
+// is returned as is. Otherwise the leading bytes of the body are peeked to
+// detect JSON ('{' or '[') or XML ('<'). The body is wrapped so the peeked
+// bytes remain readable by subsequent calls.
+//
+// An empty string is returned if the media type cannot be determined.
+func SniffContentType(r *http.Request) (string, error) {
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		mt, _, err := mime.ParseMediaType(ct)
+		if err == nil && mt != "text/plain" && mt != "application/octet-stream" {
+			return mt, nil
+		}
+	}
+
+	b, err := peekBody(r, sniffLen)
+	if err != nil {
+		return "", err
+	}
+
+	for _, c := range b {
+		switch c {
+		case ' ', '\t', '\r', '\n':
+			continue
+		case '{', '[':
+			return "application/json", nil
+		case '<':
+			return "application/xml", nil
+		}
+		return "", nil
+	}
+
+	return "", nil
+}
+
+// peekedBody is a request body that allows peeking without consuming.
+type peekedBody struct {
+	*bufio.Reader
+	io.Closer
+}
+
+// peekBody returns up to n bytes from the beginning of the request body
+// without consuming them.
+func peekBody(r *http.Request, n int) ([]byte, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, nil
+	}
+
+	body, ok := r.Body.(*peekedBody)
+	if !ok {
+		body = &peekedBody{bufio.NewReaderSize(r.Body, n), r.Body}
+		r.Body = body
+	}
+
+	b, err := body.Peek(n)
+	if errors.Is(err, io.EOF) || errors.Is(err, bufio.ErrBufferFull) {
+		err = nil
+	}
+	return b, err
+}
@@ -0,0 +1,566 @@
+// Copyright 2025 Brandon Epperson
+// SPDX-License-Identifier: Apache-2.0
+
+package roxi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// user is a bindable test type.
+type user struct {
+	Name string `json:"name" xml:"name" form:"name"`
+}
+
+func (u *user) Bind(data []byte) error {
+	u.Name = string(data)
+	return nil
+}
+
+func (u *user) Validate() error {
+	if u.Name == "" {
+		return errors.New("name is required")
+	}
+	return nil
+}
+
+func Test_Bind(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+		err  bool
+	}{
+		{"Bind", "gopher", "gopher", false},
+		{"Invalid", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := http.NewRequest("POST", "/", strings.NewReader(tt.body))
+
+			var u user
+			if err := Bind(r, &u); (err != nil) != tt.err {
+				t.Errorf("unexpected error result: %v", err)
+			}
+
+			if u.Name != tt.want {
+				t.Errorf("expected: [%s]; got: [%s]", tt.want, u.Name)
+			}
+		})
+	}
+}
+
+func Test_BindJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+		err  bool
+	}{
+		{"Bind", `{"name":"gopher"}`, "gopher", false},
+		{"Malformed", `{"name":`, "", true},
+		{"Invalid", `{"name":""}`, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := http.NewRequest("POST", "/", strings.NewReader(tt.body))
+
+			var u user
+			if err := BindJSON(r, &u); (err != nil) != tt.err {
+				t.Errorf("unexpected error result: %v", err)
+			}
+
+			if u.Name != tt.want {
+				t.Errorf("expected: [%s]; got: [%s]", tt.want, u.Name)
+			}
+		})
+	}
+}
+
+func Test_BindXML(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+		err  bool
+	}{
+		{"Bind", `<user><name>gopher</name></user>`, "gopher", false},
+		{"Malformed", `<user><name>`, "", true},
+		{"Invalid", `<user><name></name></user>`, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := http.NewRequest("POST", "/", strings.NewReader(tt.body))
+
+			var u user
+			if err := BindXML(r, &u); (err != nil) != tt.err {
+				t.Errorf("unexpected error result: %v", err)
+			}
+
+			if u.Name != tt.want {
+				t.Errorf("expected: [%s]; got: [%s]", tt.want, u.Name)
+			}
+		})
+	}
+}
+
+// level is a form value implementing encoding.TextUnmarshaler.
+type level int
+
+func (l *level) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "low":
+		*l = 1
+	case "high":
+		*l = 2
+	default:
+		return errors.New("unknown level")
+	}
+	return nil
+}
+
+// signup is a form bindable test type.
+type signup struct {
+	user
+	Age      int      `form:"age"`
+	Score    float64  `form:"score"`
+	Admin    bool     `form:"admin"`
+	Tags     []string `form:"tag"`
+	IDs      []uint8  `form:"id"`
+	Level    level    `form:"level"`
+	Ignored  string   `form:"-"`
+	Untagged string
+}
+
+func Test_BindForm(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		body  string
+		want  signup
+		err   bool
+	}{
+		{
+			"Bind", "", "name=gopher&age=14&score=9.5&admin=true&tag=a&tag=b&id=1&id=2&level=high&Ignored=x&Untagged=y",
+			signup{user: user{"gopher"}, Age: 14, Score: 9.5, Admin: true, Tags: []string{"a", "b"}, IDs: []uint8{1, 2}, Level: 2},
+			false,
+		},
+		{"Query", "age=7", "name=gopher", signup{user: user{"gopher"}, Age: 7}, false},
+		{"BodyFirst", "name=query", "name=body", signup{user: user{"body"}}, false},
+		{"InvalidInt", "", "name=gopher&age=old", signup{user: user{"gopher"}}, true},
+		{"Overflow", "", "name=gopher&id=256", signup{user: user{"gopher"}}, true},
+		{"InvalidText", "", "name=gopher&level=max", signup{user: user{"gopher"}}, true},
+		{"Invalid", "", "age=14", signup{Age: 14}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := http.NewRequest("POST", "/?"+tt.query, strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+			var s signup
+			if err := BindForm(r, &s); (err != nil) != tt.err {
+				t.Errorf("unexpected error result: %v", err)
+			}
+
+			if !reflect.DeepEqual(s, tt.want) {
+				t.Errorf("expected: [%+v]; got: [%+v]", tt.want, s)
+			}
+		})
+	}
+}
+
+func Test_BindFormMultipart(t *testing.T) {
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	mw.WriteField("name", "gopher")
+	mw.WriteField("age", "14")
+	mw.Close()
+
+	r, _ := http.NewRequest("POST", "/", body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+
+	var s signup
+	if err := BindForm(r, &s); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := signup{user: user{"gopher"}, Age: 14}
+	if !reflect.DeepEqual(s, want) {
+		t.Errorf("expected: [%+v]; got: [%+v]", want, s)
+	}
+}
+
+// search is bound from the URL query.
+type search struct {
+	Term   string    `query:"q"`
+	Page   int       `query:"page"`
+	Exact  bool      `query:"exact"`
+	Min    float64   `query:"min"`
+	Sizes  []string  `query:"size"`
+	Scores []float32 `query:"score"`
+}
+
+func (s *search) Validate() error {
+	if s.Page < 0 {
+		return errors.New("page must not be negative")
+	}
+	return nil
+}
+
+func Test_BindQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  search
+		err   bool
+	}{
+		{
+			"Bind", "q=gopher&page=2&exact=true&min=1.5&size=s&size=m&score=1&score=2.5&unknown=x",
+			search{"gopher", 2, true, 1.5, []string{"s", "m"}, []float32{1, 2.5}},
+			false,
+		},
+		{"Empty", "", search{}, false},
+		{"FirstValue", "q=a&q=b", search{Term: "a"}, false},
+		{"InvalidInt", "page=two", search{}, true},
+		{"InvalidBool", "exact=maybe", search{}, true},
+		{"InvalidQuery", "q=%zz", search{}, true},
+		{"Invalid", "page=-1", search{Page: -1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := http.NewRequest("GET", "/search?"+tt.query, nil)
+
+			var s search
+			if err := BindQuery(r, &s); (err != nil) != tt.err {
+				t.Errorf("unexpected error result: %v", err)
+			}
+
+			if !reflect.DeepEqual(s, tt.want) {
+				t.Errorf("expected: [%+v]; got: [%+v]", tt.want, s)
+			}
+		})
+	}
+
+	var name string
+	for _, v := range []any{nil, name, &name, (*search)(nil)} {
+		if err := BindQuery(httptest.NewRequest("GET", "/?q=a", nil), v); err == nil {
+			t.Errorf("expected error binding [%T]", v)
+		}
+	}
+}
+
+func Test_BindFormNonStruct(t *testing.T) {
+	r, _ := http.NewRequest("POST", "/", strings.NewReader("name=gopher"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var name string
+	for _, v := range []any{nil, name, &name, (*signup)(nil)} {
+		if err := BindForm(r, v); err == nil {
+			t.Errorf("expected error binding [%T]", v)
+		}
+	}
+}
+
+func Test_BindRequest(t *testing.T) {
+	Decoders["text/csv"] = func(r *http.Request, v any) error {
+		data, err := io.ReadAll(r.Body)
+		v.(*user).Name = string(data)
+		return err
+	}
+	defer delete(Decoders, "text/csv")
+
+	tests := []struct {
+		name string
+		ct   string
+		body string
+		want string
+		code int
+	}{
+		{"JSON", "application/json", `{"name":"gopher"}`, "gopher", 0},
+		{"JSONSuffix", "application/vnd.api+json", `{"name":"gopher"}`, "gopher", 0},
+		{"SniffedJSON", "", `{"name":"gopher"}`, "gopher", 0},
+		{"XML", "text/xml; charset=utf-8", `<user><name>gopher</name></user>`, "gopher", 0},
+		{"Form", "application/x-www-form-urlencoded", "name=gopher", "gopher", 0},
+		{"Custom", "text/csv", "gopher", "gopher", 0},
+		{"Invalid", "application/json", `{"name":""}`, "", 0},
+		{"Unsupported", "application/yaml", "name: gopher", "", http.StatusUnsupportedMediaType},
+		{"Unknown", "", "gopher", "", http.StatusUnsupportedMediaType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := http.NewRequest("POST", "/", strings.NewReader(tt.body))
+			if tt.ct != "" {
+				r.Header.Set("Content-Type", tt.ct)
+			}
+
+			var u user
+			err := BindRequest(r, &u)
+			if (err != nil) != (tt.want == "") {
+				t.Errorf("unexpected error result: %v", err)
+			}
+
+			var code int
+			if ae := (*AbortError)(nil); errors.As(err, &ae) {
+				code = ae.Code
+			}
+
+			if code != tt.code {
+				t.Errorf("expected: [%d]; got: [%d]", tt.code, code)
+			}
+
+			if u.Name != tt.want {
+				t.Errorf("expected: [%s]; got: [%s]", tt.want, u.Name)
+			}
+		})
+	}
+}
+
+func Test_BindRequestUnsupportedResponse(t *testing.T) {
+	mux := New()
+	mux.POST("/users", func(ctx context.Context, r *http.Request) error {
+		var u user
+		return BindRequest(r, &u)
+	})
+
+	r, _ := http.NewRequest("POST", "/users", strings.NewReader("name: gopher"))
+	r.Header.Set("Content-Type", "application/yaml")
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("expected: [%d]; got: [%d]", http.StatusUnsupportedMediaType, w.Code)
+	}
+}
+
+func Test_SniffContentType(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		body   string
+		ct     string
+	}{
+		{"JSONObject", "", `{"foo": "bar"}`, "application/json"},
+		{"JSONArray", "", "\n  [1, 2, 3]", "application/json"},
+		{"XML", "", `<foo>bar</foo>`, "application/xml"},
+		{"Unknown", "", "foo=bar", ""},
+		{"Empty", "", "", ""},
+		{"AmbiguousHeader", "text/plain", `{"foo": "bar"}`, "application/json"},
+		{"Header", "application/x-www-form-urlencoded; charset=utf-8", `{"foo": "bar"}`, "application/x-www-form-urlencoded"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := http.NewRequest("POST", "/", strings.NewReader(tt.body))
+			if tt.header != "" {
+				r.Header.Set("Content-Type", tt.header)
+			}
+
+			ct, err := SniffContentType(r)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if ct != tt.ct {
+				t.Errorf("expected: [%s]; got: [%s]", tt.ct, ct)
+			}
+
+			// the full body must remain readable.
+			b, _ := io.ReadAll(r.Body)
+			if string(b) != tt.body {
+				t.Errorf("expected body: [%s]; got: [%s]", tt.body, b)
+			}
+		})
+	}
+}
+
+func Test_SniffContentTypeLargeBody(t *testing.T) {
+	body := `{"data": "` + strings.Repeat("a", 4096) + `"}`
+	r, _ := http.NewRequest("POST", "/", strings.NewReader(body))
+
+	if ct, _ := SniffContentType(r); ct != "application/json" {
+		t.Errorf("expected: [%s]; got: [%s]", "application/json", ct)
+	}
+
+	// sniffing twice must not consume the body.
+	if ct, _ := SniffContentType(r); ct != "application/json" {
+		t.Errorf("expected: [%s]; got: [%s]", "application/json", ct)
+	}
+
+	b, _ := io.ReadAll(r.Body)
+	if string(b) != body {
+		t.Errorf("body was not fully readable after sniffing: got [%d] bytes", len(b))
+	}
+}
+
+// multipartRequest returns a request with a multipart body containing
+// a single file part of the given size.
+func multipartRequest(t *testing.T, path string, size int) *http.Request {
+	t.Helper()
+
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	fw, err := mw.CreateFormFile("file", "upload.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write(bytes.Repeat([]byte("a"), size))
+	mw.Close()
+
+	r, _ := http.NewRequest("POST", path, body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	return r
+}
+
+func Test_ParseMultipartForm(t *testing.T) {
+	tests := []struct {
+		name      string
+		maxMemory int64
+		onDisk    bool
+		err       error
+	}{
+		{"SpillToDisk", 1024, true, nil},
+		{"InMemory", 0, false, nil},
+		{"HandlerError", 1024, true, errors.New("upload failed")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tmpFile string
+
+			mux := New()
+			mux.POST("/upload", func(ctx context.Context, r *http.Request) error {
+				if err := ParseMultipartForm(r, tt.maxMemory); err != nil {
+					return err
+				}
+
+				f, err := r.MultipartForm.File["file"][0].Open()
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+
+				if osf, ok := f.(*os.File); ok {
+					tmpFile = osf.Name()
+				}
+				return tt.err
+			})
+
+			mux.ServeHTTP(httptest.NewRecorder(), multipartRequest(t, "/upload", 4096))
+
+			if onDisk := tmpFile != ""; onDisk != tt.onDisk {
+				t.Fatalf("expected file on disk: [%v]; got: [%v]", tt.onDisk, onDisk)
+			}
+
+			if tmpFile != "" {
+				if _, err := os.Stat(tmpFile); !errors.Is(err, fs.ErrNotExist) {
+					t.Errorf("temporary file [%s] was not removed", tmpFile)
+				}
+			}
+		})
+	}
+}
+
+func Test_BindLimit(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		limit int64
+		want  string
+		large bool
+	}{
+		{"WithinLimit", "gopher", 16, "gopher", false},
+		{"ExactLimit", "gopher", 6, "gopher", false},
+		{"TooLarge", "gopher", 4, "", true},
+		{"NoLimit", "gopher", 0, "gopher", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := http.NewRequest("PUT", "/", strings.NewReader(tt.body))
+
+			var u user
+			err := BindLimit(r, &u, tt.limit)
+
+			var mbe *http.MaxBytesError
+			if errors.As(err, &mbe) != tt.large {
+				t.Errorf("unexpected error result: %v", err)
+			}
+
+			if u.Name != tt.want {
+				t.Errorf("expected: [%s]; got: [%s]", tt.want, u.Name)
+			}
+		})
+	}
+}
+
+// streamUser is a test type decoded from the request body by BindReader.
+type streamUser struct {
+	user
+	streamed bool
+}
+
+func (u *streamUser) BindReader(r io.Reader) error {
+	u.streamed = true
+	return json.NewDecoder(r).Decode(&u.user)
+}
+
+func Test_BindStream(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		limit int64
+		want  string
+		err   bool
+		large bool
+	}{
+		{"Bind", `{"name":"gopher"}`, 0, "gopher", false, false},
+		{"Malformed", `{"name":`, 0, "", true, false},
+		{"Invalid", `{"name":""}`, 0, "", true, false},
+		{"WithinLimit", `{"name":"gopher"}`, 32, "gopher", false, false},
+		{"TooLarge", `{"name":"gopher"}`, 8, "", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := http.NewRequest("POST", "/", strings.NewReader(tt.body))
+
+			var u streamUser
+			err := BindLimit(r, &u, tt.limit)
+			if (err != nil) != tt.err {
+				t.Errorf("unexpected error result: %v", err)
+			}
+
+			var mbe *http.MaxBytesError
+			if errors.As(err, &mbe) != tt.large {
+				t.Errorf("unexpected error result: %v", err)
+			}
+
+			if !u.streamed {
+				t.Errorf("expected body to be passed to BindReader")
+			}
+
+			if u.Name != tt.want {
+				t.Errorf("expected: [%s]; got: [%s]", tt.want, u.Name)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2025 Brandon Epperson
+// SPDX-License-Identifier: Apache-2.0
+
+package roxi
+
+import (
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Combine returns a new Mux containing the routes of each of the
+// provided muxes.
+//
+// The returned mux is configured with the options of the first mux.
+// Handlers keep the middleware they were registered with, the global
+// middleware of the first mux is not applied again. Error handlers
+// registered with HandleError and host muxes are kept from every mux,
+// the routes of a host registered in several muxes are combined.
+//
+// Combine panics if a method and route is registered in more than one mux,
+// or the muxes use different path variable characters, see WithParamChars.
+func Combine(muxes ...*Mux) *Mux {
+	if len(muxes) == 0 {
+		return New()
+	}
+
+	combined := muxes[0].clone()
+	combined.scopedErrHandler = nil
+	hosts := make(map[string]*Mux)
+
+	owners := make(map[string]int)
+	for i, m := range muxes {
+		combined.checkParamChars(m)
+		combined.scopedErrHandler = append(combined.scopedErrHandler, m.scopedErrHandler...)
+
+		rt := m.routes.Load()
+		for host, sub := range rt.hosts {
+			if prev, ok := hosts[host]; ok {
+				sub = Combine(prev, sub)
+			}
+			hosts[host] = sub
+		}
+
+		for _, method := range slices.Sorted(maps.Keys(rt.trees)) {
+			rt.trees[method].walk(func(route []byte, value HandlerFunc, priority int) {
+				key := method + " " + string(route)
+				if j, ok := owners[key]; ok {
+					panic("Route '" + key + "' conflicts between combined muxes [" +
+						strconv.Itoa(j) + "] and [" + strconv.Itoa(i) + "]")
+				}
+				owners[key] = i
+
+				if err := combined.insert(method, string(route), value, priority); err != nil {
+					panic(err)
+				}
+			})
+		}
+	}
+
+	if len(hosts) != 0 {
+		combined.routes.Load().hosts = hosts
+	}
+
+	return combined
+}
+
+// Mount registers the routes of sub on m under the given prefix, so they
+// are matched in a single lookup without stripping the prefix.
+//
+// Mounted handlers keep the middleware they were registered with on sub,
+// and are wrapped with the global middleware of m; MiddlewareDepth counts
+// both. Error handlers registered on sub with HandleError are registered
+// on m under the prefix, and the routes of sub's host muxes are mounted
+// on the host muxes of m, see Host.
+//
+// Mount panics if a mounted route conflicts with a route of m, or the muxes
+// use different path variable characters. The prefix must begin with '/',
+// any trailing '/' is removed.
+func (m *Mux) Mount(prefix string, sub *Mux) {
+	if len(prefix) == 0 || prefix[0] != '/' {
+		panic("mount prefix '" + prefix + "' does not begin with '/'")
+	}
+
+	if sub == nil || sub == m {
+		panic("cannot mount mux on itself or a nil mux")
+	}
+
+	m.mount(strings.TrimRight(prefix, "/"), sub)
+}
+
+// mount registers the routes of sub on m under a prefix without
+// a trailing '/'.
+func (m *Mux) mount(prefix string, sub *Mux) {
+	m.checkParamChars(sub)
+
+	rt := sub.routes.Load()
+	for _, method := range slices.Sorted(maps.Keys(rt.trees)) {
+		rt.trees[method].walk(func(route []byte, value HandlerFunc, priority int) {
+			if err := m.tryHandle(method, prefix+string(route), value, priority, nil); err != nil {
+				panic(err)
+			}
+		})
+	}
+
+	for _, s := range sub.scopedErrHandler {
+		m.HandleError(prefix+s.prefix, s.handler)
+	}
+
+	for _, host := range slices.Sorted(maps.Keys(rt.hosts)) {
+		m.Host(host).mount(prefix, rt.hosts[host])
+	}
+}
+
+// checkParamChars panics if the routes of other use different path
+// variable characters than m, as they would be parsed differently.
+func (m *Mux) checkParamChars(other *Mux) {
+	a, b := m.treeConfig, other.treeConfig
+	if a.param != b.param || a.wildcard != b.wildcard {
+		panic("cannot mix routes with path variable characters '" +
+			string(a.param) + "', '" + string(a.wildcard) + "' and '" +
+			string(b.param) + "', '" + string(b.wildcard) + "'")
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright 2025 Brandon Epperson
+// SPDX-License-Identifier: Apache-2.0
+
+package roxi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_Combine(t *testing.T) {
+	respond := func(code int) HandlerFunc {
+		return func(ctx context.Context, r *http.Request) error {
+			GetWriter(ctx).WriteHeader(code)
+			return nil
+		}
+	}
+
+	var calls []string
+
+	users := New(WithMiddleware(trace(&calls, "users")))
+	users.GET("/users/:id", respond(200))
+	users.POST("/users", respond(201))
+
+	articles := New()
+	articles.GET("/articles/:id?", respond(202))
+	articles.GET("/users", respond(203))
+
+	mux := Combine(users, articles)
+
+	tests := []struct {
+		method string
+		path   string
+		code   int
+		calls  string
+	}{
+		{"GET", "/users/1", 200, "users"},
+		{"POST", "/users", 201, "users"},
+		{"GET", "/articles", 202, ""},
+		{"GET", "/articles/1", 202, ""},
+		{"GET", "/users", 203, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+tt.path, func(t *testing.T) {
+			calls = calls[:0]
+
+			r, _ := http.NewRequest(tt.method, tt.path, nil)
+			w := httptest.NewRecorder()
+
+			mux.ServeHTTP(w, r)
+			if w.Code != tt.code {
+				t.Errorf("expected: [%d]; got: [%d]", tt.code, w.Code)
+			}
+
+			if got := strings.Join(calls, ","); got != tt.calls {
+				t.Errorf("expected middleware calls: [%s]; got: [%s]", tt.calls, got)
+			}
+		})
+	}
+}
+
+func Test_CombineConflict(t *testing.T) {
+	a := New()
+	a.GET("/users/:id", emptyHandler)
+
+	b := New()
+	b.GET("/users", emptyHandler)
+	b.GET("/users/:id", emptyHandler)
+
+	defer func() {
+		rec := recover()
+		if rec == nil {
+			t.Fatal("no panic when one was expected.")
+		}
+
+		if msg := fmt.Sprint(rec); !strings.Contains(msg, "GET /users/:id") {
+			t.Errorf("expected conflict to name the route; got: [%s]", msg)
+		}
+	}()
+
+	Combine(a, b)
+}
+
+func Test_Mount(t *testing.T) {
+	var calls []string
+
+	api := New()
+	api.GET("/", respondWith("index"))
+	api.GET("/users/:id", respondWith("user"), trace(&calls, "sub"))
+	api.GET(`/tags/:tag([a-z]+)`, respondWith("tag"))
+	api.HandleError("/fail", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "sub error", http.StatusTeapot)
+	}))
+	api.GET("/fail", func(ctx context.Context, r *http.Request) error {
+		return errors.New("failed")
+	})
+
+	mux := New(WithMiddleware(trace(&calls, "parent")))
+	mux.Mount("/api/", api)
+
+	tests := []struct {
+		name  string
+		path  string
+		code  int
+		body  string
+		calls string
+	}{
+		{"Root", "/api/", 200, "index", "parent"},
+		{"Params", "/api/users/42", 200, "user", "parent,sub"},
+		{"Constraint", "/api/tags/go", 200, "tag", "parent"},
+		{"ConstraintMismatch", "/api/tags/42", 404, "Not Found", ""},
+		{"ErrorHandler", "/api/fail", 418, "sub error\n", "parent"},
+		{"Unprefixed", "/users/42", 404, "Not Found", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls = calls[:0]
+
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+
+			if w.Code != tt.code {
+				t.Errorf("expected status: [%d]; got: [%d]", tt.code, w.Code)
+			}
+
+			if w.Body.String() != tt.body {
+				t.Errorf("expected: [%s]; got: [%s]", tt.body, w.Body.String())
+			}
+
+			if got := strings.Join(calls, ","); got != tt.calls {
+				t.Errorf("expected calls: [%s]; got: [%s]", tt.calls, got)
+			}
+		})
+	}
+}
+
+func Test_MountDepthAndHosts(t *testing.T) {
+	var calls []string
+	var depth int
+	handler := func(ctx context.Context, r *http.Request) error {
+		depth = MiddlewareDepth(ctx)
+		_, err := GetWriter(ctx).Write([]byte("ok"))
+		return err
+	}
+
+	api := New(WithMiddleware(trace(&calls, "sub")))
+	api.GET("/users", handler, trace(&calls, "route"))
+	api.Host("api.example.com").GET("/status", handler)
+
+	mux := New(WithMiddleware(trace(&calls, "parent")))
+	mux.Mount("/v1", api)
+
+	tests := []struct {
+		name  string
+		host  string
+		path  string
+		code  int
+		depth int
+		calls string
+	}{
+		{"Route", "example.com", "/v1/users", 200, 3, "parent,sub,route"},
+		{"Host", "api.example.com", "/v1/status", 200, 2, "parent,sub"},
+		{"HostUnmatched", "example.com", "/v1/status", 404, 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls, depth = calls[:0], 0
+
+			r := httptest.NewRequest("GET", tt.path, nil)
+			r.Host = tt.host
+
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, r)
+
+			if w.Code != tt.code {
+				t.Errorf("expected status: [%d]; got: [%d]", tt.code, w.Code)
+			}
+
+			if depth != tt.depth {
+				t.Errorf("expected depth: [%d]; got: [%d]", tt.depth, depth)
+			}
+
+			if got := strings.Join(calls, ","); got != tt.calls {
+				t.Errorf("expected calls: [%s]; got: [%s]", tt.calls, got)
+			}
+		})
+	}
+}
+
+func Test_MountConflict(t *testing.T) {
+	sub := New()
+	sub.GET("/users", respondWith("sub"))
+
+	mux := New()
+	mux.GET("/api/users", respondWith("parent"))
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for conflicting mounted route")
+		}
+	}()
+	mux.Mount("/api", sub)
+}
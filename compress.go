@@ -0,0 +1,329 @@
+// Copyright 2025 Brandon Epperson
+// SPDX-License-Identifier: Apache-2.0
+
+package roxi
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// DefaultCompressMinSize is the minimum response size compressed by
+// Compress unless set with WithCompressMinSize.
+const DefaultCompressMinSize = 1024
+
+// CompressOption configures the Compress middleware.
+type CompressOption func(*compressor)
+
+// WithCompressMinSize sets the minimum size of a response body, in bytes,
+// for it to be compressed.
+func WithCompressMinSize(n int) CompressOption {
+	return func(c *compressor) {
+		c.minSize = n
+	}
+}
+
+// WithCompressLevel sets the compression level, see compress/flate.
+func WithCompressLevel(level int) CompressOption {
+	return func(c *compressor) {
+		c.level = level
+	}
+}
+
+// compressor holds the configuration and writer pools of a
+// Compress middleware.
+type compressor struct {
+	minSize int
+	level   int
+
+	gzipPool sync.Pool
+	zlibPool sync.Pool
+}
+
+// Compress returns a middleware that compresses response bodies with gzip
+// or deflate, based on the request's Accept-Encoding header. Deflate
+// responses are zlib streams, as required for the deflate content coding.
+//
+// Responses smaller than the minimum size, responses that already have a
+// Content-Encoding and responses with content types that are typically
+// compressed already, such as images and archives, are written as is.
+func Compress(opts ...CompressOption) MiddlewareFunc {
+	c := &compressor{
+		minSize: DefaultCompressMinSize,
+		level:   flate.DefaultCompression,
+	}
+
+	for _, o := range opts {
+		o(c)
+	}
+
+	// validate the level once instead of on every response.
+	if _, err := gzip.NewWriterLevel(io.Discard, c.level); err != nil {
+		panic("invalid compression level: " + err.Error())
+	}
+
+	c.gzipPool.New = func() any {
+		zw, _ := gzip.NewWriterLevel(io.Discard, c.level)
+		return zw
+	}
+	c.zlibPool.New = func() any {
+		zw, _ := zlib.NewWriterLevel(io.Discard, c.level)
+		return zw
+	}
+
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, r *http.Request) error {
+			w := GetWriter(ctx)
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			encoding := acceptedEncoding(r.Header.Get("Accept-Encoding"))
+			if encoding == "" || r.Method == http.MethodHead {
+				return next(ctx, r)
+			}
+
+			cw := &compressWriter{ResponseWriter: w, c: c, encoding: encoding}
+
+			ctx = SetWriter(ctx, cw)
+			defer SetWriter(ctx, w)
+
+			if err := next(ctx, r); err != nil {
+				// leave an unwritten response to the error handler.
+				if cw.started {
+					_ = cw.close()
+				}
+				return err
+			}
+
+			return cw.close()
+		}
+	}
+}
+
+// acceptedEncoding returns the preferred supported encoding from
+// an Accept-Encoding header, or "" if none are accepted.
+func acceptedEncoding(header string) string {
+	var deflate bool
+
+	for _, name := range acceptable(header) {
+		switch strings.ToLower(name) {
+		case "gzip":
+			return "gzip"
+		case "deflate":
+			deflate = true
+		}
+	}
+
+	if deflate {
+		return "deflate"
+	}
+	return ""
+}
+
+// compressible reports whether a response of the content type
+// should be compressed.
+func compressible(contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	switch {
+	case strings.HasPrefix(mt, "image/") && mt != "image/svg+xml",
+		strings.HasPrefix(mt, "video/"),
+		strings.HasPrefix(mt, "audio/"),
+		strings.HasPrefix(mt, "font/woff"):
+		return false
+	}
+
+	switch mt {
+	case "application/gzip",
+		"application/x-gzip",
+		"application/zip",
+		"application/zstd",
+		"application/x-bzip2",
+		"application/x-7z-compressed",
+		"application/x-rar-compressed",
+		"application/octet-stream",
+		"application/pdf":
+		return false
+	}
+	return true
+}
+
+// compressWriter is a http.ResponseWriter that buffers the response
+// until the minimum size is reached, and then compresses it if possible.
+type compressWriter struct {
+	http.ResponseWriter
+	c        *compressor
+	encoding string
+
+	buf  []byte
+	code int
+
+	// started is set once the header has been written.
+	started bool
+
+	// hijacked is set once the connection has been taken over, after
+	// which nothing is written.
+	hijacked bool
+
+	zw interface {
+		io.WriteCloser
+		Reset(io.Writer)
+		Flush() error
+	}
+}
+
+// WriteHeader implements the http.ResponseWriter interface.
+//
+// The header is written with the first body write, or when the
+// handler returns.
+func (w *compressWriter) WriteHeader(code int) {
+	if w.started || w.hijacked || w.code != 0 {
+		return
+	}
+
+	// informational responses are written immediately.
+	if code >= 100 && code < 200 {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.code = code
+}
+
+// Write implements the http.ResponseWriter interface.
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if w.hijacked {
+		return 0, http.ErrHijacked
+	}
+
+	if w.started {
+		if w.zw != nil {
+			return w.zw.Write(b)
+		}
+		return w.ResponseWriter.Write(b)
+	}
+
+	w.buf = append(w.buf, b...)
+	if len(w.buf) < w.c.minSize {
+		return len(b), nil
+	}
+
+	if err := w.start(true); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// start writes the header, compressing the response if allowed,
+// and then writes the buffered body.
+func (w *compressWriter) start(allowCompress bool) error {
+	w.started = true
+
+	h := w.Header()
+	if h.Get("Content-Type") == "" && len(w.buf) > 0 {
+		h.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+
+	if allowCompress && h.Get("Content-Encoding") == "" && compressible(h.Get("Content-Type")) {
+		h.Set("Content-Encoding", w.encoding)
+		h.Del("Content-Length")
+
+		if w.encoding == "gzip" {
+			w.zw, _ = w.c.gzipPool.Get().(*gzip.Writer)
+		} else {
+			w.zw, _ = w.c.zlibPool.Get().(*zlib.Writer)
+		}
+		w.zw.Reset(w.ResponseWriter)
+	}
+
+	w.ResponseWriter.WriteHeader(w.code)
+
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+
+	var err error
+	if w.zw != nil {
+		_, err = w.zw.Write(buf)
+	} else {
+		_, err = w.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// Flush implements the http.Flusher interface.
+//
+// Flushing a response smaller than the minimum size writes it uncompressed.
+func (w *compressWriter) Flush() {
+	if w.hijacked {
+		return
+	}
+
+	if !w.started {
+		_ = w.start(false)
+	}
+
+	if w.zw != nil {
+		_ = w.zw.Flush()
+	}
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// close writes any buffered response and finishes the compressed stream.
+func (w *compressWriter) close() error {
+	if w.hijacked {
+		return nil
+	}
+
+	if !w.started {
+		// below the minimum size.
+		if err := w.start(false); err != nil {
+			return err
+		}
+	}
+
+	if w.zw == nil {
+		return nil
+	}
+
+	err := w.zw.Close()
+	w.zw.Reset(io.Discard)
+	if zw, ok := w.zw.(*gzip.Writer); ok {
+		w.c.gzipPool.Put(zw)
+	} else {
+		w.c.zlibPool.Put(w.zw)
+	}
+	w.zw = nil
+	return err
+}
+
+// Hijack implements the http.Hijacker interface.
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil {
+		w.hijacked = true
+	}
+	return conn, rw, err
+}
+
+// Unwrap returns the underlying http.ResponseWriter for use
+// with http.ResponseController.
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,121 @@
+// Copyright 2025 Brandon Epperson
+// SPDX-License-Identifier: Apache-2.0
+
+package roxi
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_Compress(t *testing.T) {
+	large := strings.Repeat(`{"name":"gopher"}`, 200)
+
+	mux := New(WithMiddleware(Compress()))
+	mux.GET("/:ct/:size", func(ctx context.Context, r *http.Request) error {
+		w := GetWriter(ctx)
+
+		body := "small"
+		if r.PathValue("size") == "large" {
+			body = large
+		}
+
+		w.Header().Set("Content-Type", strings.ReplaceAll(r.PathValue("ct"), "_", "/"))
+		// write in chunks to cross the minimum size.
+		for len(body) > 0 {
+			n := min(len(body), 100)
+			if _, err := io.WriteString(w, body[:n]); err != nil {
+				return err
+			}
+			body = body[n:]
+		}
+		return nil
+	})
+	mux.GET("/empty", func(ctx context.Context, r *http.Request) error {
+		GetWriter(ctx).WriteHeader(http.StatusNoContent)
+		return nil
+	})
+
+	tests := []struct {
+		name     string
+		path     string
+		accept   string
+		encoding string
+		code     int
+	}{
+		{"Gzip", "/application_json/large", "gzip, deflate", "gzip", 200},
+		{"Deflate", "/text_plain/large", "deflate", "deflate", 200},
+		{"Rejected", "/text_plain/large", "gzip;q=0", "", 200},
+		{"RejectedDecimal", "/text_plain/large", "gzip;q=0.000", "", 200},
+		{"RejectedFallback", "/text_plain/large", "gzip; q=0.0, deflate;q=0.5", "deflate", 200},
+		{"InvalidQuality", "/text_plain/large", "gzip;q=high", "", 200},
+		{"LowQuality", "/text_plain/large", "gzip;q=0.001", "gzip", 200},
+		{"NotAccepted", "/text_plain/large", "", "", 200},
+		{"Small", "/application_json/small", "gzip", "", 200},
+		{"Compressed", "/image_png/large", "gzip", "", 200},
+		{"NoContent", "/empty", "gzip", "", 204},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tt.path, nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept-Encoding", tt.accept)
+			}
+			w := httptest.NewRecorder()
+
+			mux.ServeHTTP(w, r)
+
+			if w.Code != tt.code {
+				t.Errorf("expected status: [%d]; got: [%d]", tt.code, w.Code)
+			}
+
+			if got := w.Header().Get("Content-Encoding"); got != tt.encoding {
+				t.Fatalf("expected encoding: [%s]; got: [%s]", tt.encoding, got)
+			}
+
+			if got := w.Header().Get("Vary"); got != "Accept-Encoding" {
+				t.Errorf("expected: [%s]; got: [%s]", "Accept-Encoding", got)
+			}
+
+			var body io.Reader = w.Body
+			switch tt.encoding {
+			case "gzip":
+				zr, err := gzip.NewReader(w.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				body = zr
+			case "deflate":
+				zr, err := zlib.NewReader(w.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				body = zr
+			}
+
+			b, err := io.ReadAll(body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := large
+			switch {
+			case tt.code == 204:
+				want = ""
+			case strings.HasSuffix(tt.path, "small"):
+				want = "small"
+			}
+
+			if string(b) != want {
+				t.Errorf("expected body of length: [%d]; got: [%d]", len(want), len(b))
+			}
+		})
+	}
+}
@@ -12,22 +12,115 @@ type ctxKey int
 
 const (
 	writerKey ctxKey = iota
+	requestIDKey
+	realIPKey
+	errorKey
 )
 
 // writerContext stores the http.ResponseWriter to pass to HandlerFuncs.
 type writerContext struct {
 	context.Context
 	value http.ResponseWriter
+
+	// mux serving the request, nil if not served by a Mux.
+	mux *Mux
+
+	// req is the request being served, nil if unknown.
+	req *http.Request
+
+	// depth is the number of middleware wrapping the matched handler.
+	depth int
+
+	// sw records the response status when panic recovery is enabled,
+	// see responseStarted.
+	sw statsWriter
+
+	// detached is set when the handler timed out and may still be
+	// running, see Timeout.
+	detached bool
+
+	// request scoped values, see WithValue.
+	values []ctxValue
+}
+
+// ctxValue is a key value pair stored with WithValue.
+type ctxValue struct {
+	key, val any
 }
 
 func (c writerContext) Value(key any) any {
 	if key == writerKey {
 		return c.value
 	}
+
+	for i := range c.values {
+		if c.values[i].key == key {
+			return c.values[i].val
+		}
+	}
 	return c.Context.Value(key)
 }
 
+// WithValue stores a request scoped value in the context, and returns
+// the context to pass on.
+//
+// For contexts created by the mux, the value is stored in place, so it is
+// visible to all middleware and handlers of the request without
+// allocating a new context. GetWriter continues to work as usual. Other
+// contexts are wrapped with context.WithValue.
+//
+// Values are not safe for concurrent use, and must not be retained
+// after the request completes.
+func WithValue(ctx context.Context, key, val any) context.Context {
+	if key == nil {
+		panic("nil key")
+	}
+
+	v, ok := ctx.(*writerContext)
+	if !ok {
+		return context.WithValue(ctx, key, val)
+	}
+
+	for i := range v.values {
+		if v.values[i].key == key {
+			v.values[i].val = val
+			return v
+		}
+	}
+
+	v.values = append(v.values, ctxValue{key, val})
+	return v
+}
+
+// Value returns the value stored in the context for key, or nil.
+func Value(ctx context.Context, key any) any {
+	return ctx.Value(key)
+}
+
+// GetError returns the error returned by the handler of the request, from
+// the context of an error handler, see WithErrorHandler and HandleError.
+// It returns nil if there is none.
+func GetError(ctx context.Context) error {
+	err, _ := ctx.Value(errorKey).(error)
+	return err
+}
+
+// NewTestContext returns a context carrying w, for calling a HandlerFunc
+// directly in tests:
+//
+//	rec := httptest.NewRecorder()
+//	err := handler(roxi.NewTestContext(rec), req)
+//
+// It's the context type used by the mux, so GetWriter, WithValue and
+// middleware behave as they do when serving a request.
+func NewTestContext(w http.ResponseWriter) context.Context {
+	return &writerContext{Context: context.Background(), value: w}
+}
+
 // GetWriter returns the http.ResponseWriter from the context.
+//
+// GetWriter returns nil if the context does not carry a writer, such as
+// when a HandlerFunc is called directly. See MustGetWriter and GetWriterOr.
 func GetWriter(ctx context.Context) http.ResponseWriter {
 	if v, ok := ctx.(*writerContext); ok {
 		return v.value
@@ -35,6 +128,25 @@ func GetWriter(ctx context.Context) http.ResponseWriter {
 	return getWriterFallback(ctx)
 }
 
+// MustGetWriter returns the http.ResponseWriter from the context, and
+// panics if the context does not carry a writer.
+func MustGetWriter(ctx context.Context) http.ResponseWriter {
+	w := GetWriter(ctx)
+	if w == nil {
+		panic("roxi: context does not contain an http.ResponseWriter")
+	}
+	return w
+}
+
+// GetWriterOr returns the http.ResponseWriter from the context, or
+// fallback if the context does not carry a writer.
+func GetWriterOr(ctx context.Context, fallback http.ResponseWriter) http.ResponseWriter {
+	if w := GetWriter(ctx); w != nil {
+		return w
+	}
+	return fallback
+}
+
 //go:noinline
 func getWriterFallback(ctx context.Context) http.ResponseWriter {
 	if v, ok := ctx.Value(writerKey).(http.ResponseWriter); ok {
@@ -50,9 +162,28 @@ func SetWriter(ctx context.Context, w http.ResponseWriter) context.Context {
 		if ctx == nil {
 			ctx = context.Background()
 		}
-		return writerContext{ctx, w}
+		return writerContext{Context: ctx, value: w}
 	}
 
 	v.value = w
 	return v
 }
+
+// responseStarted reports whether the response of the request served
+// with ctx has been started, or its connection hijacked, if it is known.
+func responseStarted(ctx context.Context) bool {
+	v, ok := ctx.(*writerContext)
+	return ok && v.sw.ResponseWriter != nil && (v.sw.stats.Status != 0 || v.sw.hijacked)
+}
+
+// logRecord logs a message with key value pairs using the logger of the
+// Mux serving the request.
+//
+// If no logger is configured, the record is printed to stdout.
+func logRecord(ctx context.Context, msg string, args ...any) {
+	if v, ok := ctx.(*writerContext); ok && v.mux != nil {
+		v.mux.log(msg, args...)
+		return
+	}
+	printRecord(msg, args...)
+}
@@ -2,6 +2,7 @@ package roxi
 
 import (
 	"context"
+	"net/http"
 	"net/http/httptest"
 	"testing"
 )
@@ -11,7 +12,7 @@ func Test_ContextValue(t *testing.T) {
 
 	ctx := context.WithValue(context.Background(), testKey(1), "test")
 
-	ctx = &writerContext{ctx, httptest.NewRecorder()}
+	ctx = &writerContext{Context: ctx, value: httptest.NewRecorder()}
 
 	v, ok := ctx.Value(testKey(1)).(string)
 	if !ok {
@@ -24,9 +25,99 @@ func Test_ContextValue(t *testing.T) {
 }
 
 func Test_ContextNilWriter(t *testing.T) {
-	ctx := &writerContext{context.Background(), nil}
+	ctx := &writerContext{Context: context.Background()}
 
 	if w := GetWriter(ctx); w != nil {
 		t.Errorf("unknown value returned from context: %v", w)
 	}
 }
+
+func Test_MustGetWriter(t *testing.T) {
+	w := httptest.NewRecorder()
+	if got := MustGetWriter(&writerContext{Context: context.Background(), value: w}); got != w {
+		t.Errorf("expected: [%v]; got: [%v]", w, got)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for context without a writer")
+		}
+	}()
+	MustGetWriter(context.Background())
+}
+
+func Test_GetWriterOr(t *testing.T) {
+	w := httptest.NewRecorder()
+	fallback := httptest.NewRecorder()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want http.ResponseWriter
+	}{
+		{"Writer", &writerContext{Context: context.Background(), value: w}, w},
+		{"NilWriter", &writerContext{Context: context.Background()}, fallback},
+		{"NoWriter", context.Background(), fallback},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GetWriterOr(tt.ctx, fallback); got != tt.want {
+				t.Errorf("expected: [%p]; got: [%p]", tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_WithValue(t *testing.T) {
+	type testKey int
+
+	w := httptest.NewRecorder()
+	base := context.WithValue(context.Background(), testKey(0), "base")
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+	}{
+		{"WriterContext", &writerContext{Context: base, value: w}},
+		{"Context", base},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := WithValue(tt.ctx, testKey(1), "a")
+			ctx = WithValue(ctx, testKey(2), "b")
+			ctx = WithValue(ctx, testKey(1), "c")
+
+			want := map[testKey]any{0: "base", 1: "c", 2: "b", 3: nil}
+			for k, v := range want {
+				if got := Value(ctx, k); got != v {
+					t.Errorf("key [%d] expected: [%v]; got: [%v]", k, v, got)
+				}
+			}
+
+			if _, ok := tt.ctx.(*writerContext); ok && GetWriter(ctx) != w {
+				t.Error("writer lost after storing values")
+			}
+		})
+	}
+}
+
+func Test_ContextValuesReset(t *testing.T) {
+	type testKey int
+
+	ctx := getContext()
+	ctx.Context = context.Background()
+	WithValue(ctx, testKey(1), "a")
+	putContext(ctx)
+
+	// a pooled context must not leak values between requests.
+	for range 10 {
+		ctx := getContext()
+		ctx.Context = context.Background()
+		if v := Value(ctx, testKey(1)); v != nil {
+			t.Fatalf("expected: [%v]; got: [%v]", nil, v)
+		}
+		putContext(ctx)
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright 2025 Brandon Epperson
+// SPDX-License-Identifier: Apache-2.0
+
+package roxi
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORS configures the Cross-Origin Resource Sharing headers written by
+// its HandlerFunc and Middleware.
+//
+// HandlerFunc answers preflight requests for paths without an OPTIONS
+// route, with WithOptionsHandler, and Middleware sets the headers of
+// the actual requests to routes:
+//
+//	cors := roxi.CORS{AllowedOrigins: []string{"https://*.example.com"}}
+//	mux := roxi.New(
+//		roxi.WithOptionsHandler(cors.HandlerFunc()),
+//		roxi.WithMiddleware(cors.Middleware()),
+//	)
+type CORS struct {
+	// AllowedOrigins are the origins allowed to make cross-origin requests,
+	// such as "https://example.com". "*" allows any origin without
+	// credentials, and a '*' matches any subdomain, such as in "https://*.example.com".
+	AllowedOrigins []string
+
+	// AllowOrigin reports whether origin is allowed, such as by looking it
+	// up in a database. If set, it's used instead of AllowedOrigins, and
+	// allowed origins are always echoed, never "*".
+	AllowOrigin func(origin string) bool
+
+	// AllowedMethods are the methods allowed in preflight responses. If
+	// empty, the methods of the Allow header set by the mux are allowed,
+	// or the requested method if there's none.
+	AllowedMethods []string
+
+	// AllowedHeaders are the request headers allowed in preflight
+	// responses. If empty, the requested headers are allowed.
+	AllowedHeaders []string
+
+	// ExposedHeaders are the response headers readable by the client.
+	ExposedHeaders []string
+
+	// AllowCredentials allows requests with cookies or HTTP authentication.
+	// It can't be combined with the "*" origin, which would let any site
+	// read credentialed responses: HandlerFunc and Middleware panic if
+	// both are set. List the trusted origins, or use AllowOrigin, instead.
+	AllowCredentials bool
+
+	// MaxAge is how long the client may cache a preflight response.
+	// If zero, the header is not set.
+	MaxAge time.Duration
+}
+
+// HandlerFunc returns a handler responding to preflight requests with
+// 204 No Content and the CORS headers allowing the request, or without
+// them if the origin is not allowed.
+func (c CORS) HandlerFunc() http.HandlerFunc {
+	c.check()
+	return func(w http.ResponseWriter, r *http.Request) {
+		c.preflight(w, r)
+	}
+}
+
+// Middleware returns a middleware that sets the CORS headers of
+// responses to allowed origins.
+//
+// Preflight requests reaching the middleware, such as for routes with an
+// OPTIONS handler, are answered as by HandlerFunc without calling the
+// handler. Other requests are handled as usual.
+func (c CORS) Middleware() MiddlewareFunc {
+	c.check()
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, r *http.Request) error {
+			w := GetWriter(ctx)
+			if w == nil {
+				return next(ctx, r)
+			}
+
+			if isPreflight(r) {
+				c.preflight(w, r)
+				return nil
+			}
+
+			h := w.Header()
+			h.Add("Vary", "Origin")
+
+			if origin, ok := c.allowOrigin(r.Header.Get("Origin")); ok {
+				c.setOrigin(h, origin)
+				if len(c.ExposedHeaders) > 0 {
+					h.Set("Access-Control-Expose-Headers", strings.Join(c.ExposedHeaders, ", "))
+				}
+			}
+			return next(ctx, r)
+		}
+	}
+}
+
+// check panics if c allows credentialed requests from any origin.
+func (c CORS) check() {
+	if c.AllowCredentials && c.AllowOrigin == nil && slices.Contains(c.AllowedOrigins, "*") {
+		panic("cors: AllowCredentials cannot be used with the '*' origin")
+	}
+}
+
+// preflight writes the response to a preflight request.
+func (c CORS) preflight(w http.ResponseWriter, r *http.Request) {
+	h := w.Header()
+	h.Add("Vary", "Origin")
+	h.Add("Vary", "Access-Control-Request-Method")
+	h.Add("Vary", "Access-Control-Request-Headers")
+
+	origin, ok := c.allowOrigin(r.Header.Get("Origin"))
+	if !ok || !isPreflight(r) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	c.setOrigin(h, origin)
+
+	switch {
+	case len(c.AllowedMethods) > 0:
+		h.Set("Access-Control-Allow-Methods", strings.Join(c.AllowedMethods, ", "))
+	case h.Get("Allow") != "":
+		h.Set("Access-Control-Allow-Methods", h.Get("Allow"))
+	default:
+		h.Set("Access-Control-Allow-Methods", r.Header.Get("Access-Control-Request-Method"))
+	}
+
+	if len(c.AllowedHeaders) > 0 {
+		h.Set("Access-Control-Allow-Headers", strings.Join(c.AllowedHeaders, ", "))
+	} else if reqHeaders := r.Header.Get("Access-Control-Request-Headers"); reqHeaders != "" {
+		h.Set("Access-Control-Allow-Headers", reqHeaders)
+	}
+
+	if c.MaxAge > 0 {
+		h.Set("Access-Control-Max-Age", strconv.Itoa(int(c.MaxAge.Seconds())))
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// setOrigin sets the allowed origin and credentials headers.
+func (c CORS) setOrigin(h http.Header, origin string) {
+	h.Set("Access-Control-Allow-Origin", origin)
+	if c.AllowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// allowOrigin returns the value of the Access-Control-Allow-Origin header
+// for origin, and reports whether the origin is allowed.
+func (c CORS) allowOrigin(origin string) (string, bool) {
+	if origin == "" {
+		return "", false
+	}
+
+	if c.AllowOrigin != nil {
+		return origin, c.AllowOrigin(origin)
+	}
+
+	for _, allowed := range c.AllowedOrigins {
+		if allowed == "*" {
+			return "*", true
+		}
+
+		if matchOrigin(allowed, origin) {
+			return origin, true
+		}
+	}
+	return "", false
+}
+
+// matchOrigin reports whether origin matches the allowed origin, in which
+// a '*' matches one or more characters.
+func matchOrigin(allowed, origin string) bool {
+	prefix, suffix, wildcard := strings.Cut(allowed, "*")
+	if !wildcard {
+		return strings.EqualFold(allowed, origin)
+	}
+
+	return len(origin) > len(prefix)+len(suffix) &&
+		strings.EqualFold(origin[:len(prefix)], prefix) &&
+		strings.EqualFold(origin[len(origin)-len(suffix):], suffix)
+}
+
+// isPreflight reports whether r is a CORS preflight request.
+func isPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions &&
+		r.Header.Get("Origin") != "" &&
+		r.Header.Get("Access-Control-Request-Method") != ""
+}
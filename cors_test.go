@@ -0,0 +1,270 @@
+// Copyright 2025 Brandon Epperson
+// SPDX-License-Identifier: Apache-2.0
+
+package roxi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_CORSMiddleware(t *testing.T) {
+	cors := CORS{
+		AllowedOrigins:   []string{"https://example.com", "https://*.example.org"},
+		ExposedHeaders:   []string{"X-Request-ID"},
+		AllowCredentials: true,
+	}
+
+	var called bool
+	mux := New(WithMiddleware(cors.Middleware()))
+	mux.GET("/users", func(ctx context.Context, r *http.Request) error {
+		called = true
+		return nil
+	})
+
+	tests := []struct {
+		name   string
+		origin string
+		allow  string
+	}{
+		{"Exact", "https://example.com", "https://example.com"},
+		{"Wildcard", "https://app.example.org", "https://app.example.org"},
+		{"WildcardApex", "https://example.org", ""},
+		{"NotAllowed", "https://evil.com", ""},
+		{"SameOrigin", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called = false
+
+			r := httptest.NewRequest("GET", "/users", nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			w := httptest.NewRecorder()
+
+			mux.ServeHTTP(w, r)
+
+			if !called {
+				t.Errorf("expected handler to be called")
+			}
+
+			if h := w.Header().Get("Access-Control-Allow-Origin"); h != tt.allow {
+				t.Errorf("expected: [%s]; got: [%s]", tt.allow, h)
+			}
+
+			// credentials and exposed headers are only set for allowed origins.
+			var creds, exposed string
+			if tt.allow != "" {
+				creds, exposed = "true", "X-Request-ID"
+			}
+
+			if h := w.Header().Get("Access-Control-Allow-Credentials"); h != creds {
+				t.Errorf("expected: [%s]; got: [%s]", creds, h)
+			}
+
+			if h := w.Header().Get("Access-Control-Expose-Headers"); h != exposed {
+				t.Errorf("expected: [%s]; got: [%s]", exposed, h)
+			}
+
+			if h := w.Header().Get("Vary"); h != "Origin" {
+				t.Errorf("expected: [%s]; got: [%s]", "Origin", h)
+			}
+		})
+	}
+}
+
+func Test_CORSPreflight(t *testing.T) {
+	tests := []struct {
+		name    string
+		cors    CORS
+		path    string
+		origin  string
+		method  string
+		headers string
+		want    map[string]string
+	}{
+		{
+			"AllowHeader", CORS{AllowedOrigins: []string{"*"}}, "/users", "https://example.com", "PUT", "",
+			map[string]string{
+				"Access-Control-Allow-Origin":  "*",
+				"Access-Control-Allow-Methods": "GET, PUT",
+			},
+		},
+		{
+			"Configured", CORS{
+				AllowedOrigins: []string{"https://example.com"},
+				AllowedMethods: []string{"GET", "POST"},
+				AllowedHeaders: []string{"Content-Type"},
+				MaxAge:         time.Hour,
+			}, "/users", "https://example.com", "POST", "Authorization",
+			map[string]string{
+				"Access-Control-Allow-Origin":  "https://example.com",
+				"Access-Control-Allow-Methods": "GET, POST",
+				"Access-Control-Allow-Headers": "Content-Type",
+				"Access-Control-Max-Age":       "3600",
+			},
+		},
+		{
+			"EchoHeaders", CORS{AllowedOrigins: []string{"*"}}, "/users", "https://example.com", "PUT", "Authorization",
+			map[string]string{
+				"Access-Control-Allow-Headers": "Authorization",
+			},
+		},
+		{
+			"OptionsRoute", CORS{AllowedOrigins: []string{"*"}}, "/items", "https://example.com", "DELETE", "",
+			map[string]string{
+				"Access-Control-Allow-Origin":  "*",
+				"Access-Control-Allow-Methods": "DELETE",
+			},
+		},
+		{
+			"NotAllowed", CORS{AllowedOrigins: []string{"https://example.com"}}, "/users", "https://evil.com", "PUT", "",
+			map[string]string{
+				"Access-Control-Allow-Origin":  "",
+				"Access-Control-Allow-Methods": "",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := New(
+				WithOptionsHandler(tt.cors.HandlerFunc()),
+				WithMiddleware(tt.cors.Middleware()),
+			)
+			mux.GET("/users", emptyHandler)
+			mux.PUT("/users", emptyHandler)
+			mux.OPTIONS("/items", func(ctx context.Context, r *http.Request) error {
+				t.Errorf("expected preflight to be answered by the middleware")
+				return nil
+			})
+
+			r := httptest.NewRequest("OPTIONS", tt.path, nil)
+			r.Header.Set("Origin", tt.origin)
+			r.Header.Set("Access-Control-Request-Method", tt.method)
+			if tt.headers != "" {
+				r.Header.Set("Access-Control-Request-Headers", tt.headers)
+			}
+			w := httptest.NewRecorder()
+
+			mux.ServeHTTP(w, r)
+
+			if w.Code != http.StatusNoContent {
+				t.Errorf("expected: [%d]; got: [%d]", http.StatusNoContent, w.Code)
+			}
+
+			for k, v := range tt.want {
+				if h := w.Header().Get(k); h != v {
+					t.Errorf("expected %s: [%s]; got: [%s]", k, v, h)
+				}
+			}
+		})
+	}
+}
+
+func Test_CORSAllowOrigin(t *testing.T) {
+	cors := CORS{
+		// the function takes precedence over the allowed origins.
+		AllowedOrigins: []string{"*"},
+		AllowOrigin: func(origin string) bool {
+			return strings.HasSuffix(origin, ".mycompany.com")
+		},
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		origin string
+		allow  string
+	}{
+		{"Allowed", "GET", "https://app.mycompany.com", "https://app.mycompany.com"},
+		{"Rejected", "GET", "https://example.com", ""},
+		{"Preflight", "OPTIONS", "https://app.mycompany.com", "https://app.mycompany.com"},
+		{"PreflightRejected", "OPTIONS", "https://example.com", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := New(
+				WithOptionsHandler(cors.HandlerFunc()),
+				WithMiddleware(cors.Middleware()),
+			)
+			mux.GET("/users", emptyHandler)
+
+			r := httptest.NewRequest(tt.method, "/users", nil)
+			r.Header.Set("Origin", tt.origin)
+			r.Header.Set("Access-Control-Request-Method", "GET")
+			w := httptest.NewRecorder()
+
+			mux.ServeHTTP(w, r)
+
+			if h := w.Header().Get("Access-Control-Allow-Origin"); h != tt.allow {
+				t.Errorf("expected: [%s]; got: [%s]", tt.allow, h)
+			}
+
+			if vary := w.Header().Values("Vary"); !slices.Contains(vary, "Origin") {
+				t.Errorf("expected Vary to contain Origin; got: [%v]", vary)
+			}
+		})
+	}
+}
+
+func Test_CORSCredentialsAnyOrigin(t *testing.T) {
+	tests := []struct {
+		name  string
+		cors  CORS
+		panic bool
+	}{
+		{"AnyOrigin", CORS{AllowedOrigins: []string{"https://example.com", "*"}, AllowCredentials: true}, true},
+		{"ListedOrigins", CORS{AllowedOrigins: []string{"https://*.example.com"}, AllowCredentials: true}, false},
+		{"NoCredentials", CORS{AllowedOrigins: []string{"*"}}, false},
+		{"AllowOrigin", CORS{AllowedOrigins: []string{"*"}, AllowOrigin: func(string) bool { return true }, AllowCredentials: true}, false},
+	}
+
+	for _, tt := range tests {
+		for _, build := range []func(CORS){
+			func(c CORS) { c.HandlerFunc() },
+			func(c CORS) { c.Middleware() },
+		} {
+			t.Run(tt.name, func(t *testing.T) {
+				defer func() {
+					if rec := recover(); (rec != nil) != tt.panic {
+						t.Errorf("expected panic: [%v]; got: [%v]", tt.panic, rec)
+					}
+				}()
+				build(tt.cors)
+			})
+		}
+	}
+}
+
+func Test_MatchOrigin(t *testing.T) {
+	tests := []struct {
+		allowed string
+		origin  string
+		match   bool
+	}{
+		{"https://example.com", "https://example.com", true},
+		{"https://example.com", "https://EXAMPLE.com", true},
+		{"https://example.com", "http://example.com", false},
+		{"https://*.example.com", "https://api.example.com", true},
+		{"https://*.example.com", "https://example.com", false},
+		{"https://*.example.com", "https://.example.com", false},
+		{"https://*.example.com", "https://api.example.com.evil.com", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.allowed+"/"+tt.origin, func(t *testing.T) {
+			if match := matchOrigin(tt.allowed, tt.origin); match != tt.match {
+				t.Errorf("expected: [%v]; got: [%v]", tt.match, match)
+			}
+		})
+	}
+}
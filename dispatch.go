@@ -0,0 +1,42 @@
+// Copyright 2025 Brandon Epperson
+// SPDX-License-Identifier: Apache-2.0
+
+package roxi
+
+import (
+	"context"
+	"maps"
+	"net/http"
+)
+
+// ByQuery returns a handler dispatching requests by the value of a query
+// parameter, for routes serving several representations at one path:
+//
+//	mux.GET("/reports/:id", roxi.ByQuery("format", map[string]roxi.HandlerFunc{
+//		"json": reportJSON,
+//		"csv":  reportCSV,
+//	}, reportJSON))
+//
+// Requests without the parameter, or with a value not in cases, are
+// handled by def. If def is nil, the parameter is required and such
+// requests are aborted with 400 Bad Request.
+func ByQuery(param string, cases map[string]HandlerFunc, def HandlerFunc) HandlerFunc {
+	cases = maps.Clone(cases)
+
+	return func(ctx context.Context, r *http.Request) error {
+		value := r.URL.Query().Get(param)
+		if h, ok := cases[value]; ok && value != "" {
+			return h(ctx, r)
+		}
+
+		if def != nil {
+			return def(ctx, r)
+		}
+
+		msg := "invalid query parameter '" + param + "'"
+		if value == "" {
+			msg = "missing query parameter '" + param + "'"
+		}
+		return Abort(http.StatusBadRequest, &errorResponse{http.StatusBadRequest, msg})
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2025 Brandon Epperson
+// SPDX-License-Identifier: Apache-2.0
+
+package roxi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_ByQuery(t *testing.T) {
+	write := func(s string) HandlerFunc {
+		return func(ctx context.Context, r *http.Request) error {
+			GetWriter(ctx).Write([]byte(s))
+			return nil
+		}
+	}
+	cases := map[string]HandlerFunc{"json": write("json"), "csv": write("csv")}
+
+	tests := []struct {
+		name  string
+		def   HandlerFunc
+		query string
+		code  int
+		body  string
+	}{
+		{"Case", write("default"), "?format=csv", 200, "csv"},
+		{"Default", write("default"), "", 200, "default"},
+		{"UnknownDefault", write("default"), "?format=xml", 200, "default"},
+		{"Required", nil, "?format=json", 200, "json"},
+		{"RequiredMissing", nil, "", 400, "missing query parameter 'format'"},
+		{"RequiredEmpty", nil, "?format=", 400, "missing query parameter 'format'"},
+		{"RequiredUnknown", nil, "?format=xml", 400, "invalid query parameter 'format'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := New()
+			mux.GET("/reports", ByQuery("format", cases, tt.def))
+
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest("GET", "/reports"+tt.query, nil))
+
+			if w.Code != tt.code {
+				t.Errorf("expected: [%d]; got: [%d]", tt.code, w.Code)
+			}
+			if w.Body.String() != tt.body {
+				t.Errorf("expected: [%s]; got: [%s]", tt.body, w.Body.String())
+			}
+		})
+	}
+}
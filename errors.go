@@ -0,0 +1,51 @@
+// Copyright 2025 Brandon Epperson
+// SPDX-License-Identifier: Apache-2.0
+
+package roxi
+
+import "errors"
+
+// Errors wrapped by a *RouteError, describing why a route could not
+// be registered.
+var (
+	// ErrInvalidRoute is wrapped when the method, path or handler of a
+	// route is not valid, such as a malformed path variable.
+	ErrInvalidRoute = errors.New("invalid route")
+
+	// ErrRouteExists is wrapped when a route has already been registered
+	// at the method and path.
+	ErrRouteExists = errors.New("route already registered")
+
+	// ErrRouteConflict is wrapped when a route's path variable or wildcard
+	// conflicts with one of a registered route in the same path segment.
+	ErrRouteConflict = errors.New("route conflicts with a registered route")
+)
+
+// RouteError is the error returned by TryHandle when a route cannot be
+// registered. Handle and the method helpers panic with it.
+type RouteError struct {
+	// Method and Path of the route, as passed to TryHandle.
+	Method string
+	Path   string
+
+	// Err is ErrInvalidRoute, ErrRouteExists or ErrRouteConflict.
+	Err error
+
+	// Detail describes the problem with the route.
+	Detail string
+}
+
+// Error implements the error interface.
+func (e *RouteError) Error() string {
+	return e.Detail
+}
+
+// Unwrap returns e.Err.
+func (e *RouteError) Unwrap() error {
+	return e.Err
+}
+
+// routeError returns a *RouteError for path.
+func routeError(err error, path []byte, detail string) *RouteError {
+	return &RouteError{Path: string(path), Err: err, Detail: detail}
+}
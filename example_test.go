@@ -7,6 +7,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -128,6 +129,17 @@ func ExampleWithRedirectCleanPath() {
 	// Output: /foo 301
 }
 
+func ExampleWithLogger() {
+	// Log recovered panics and file server errors with slog.
+	mux := roxi.New(roxi.WithLogger(slog.Error))
+
+	mux.GET("/panic", func(ctx context.Context, r *http.Request) error {
+		panic("at the disco")
+	})
+
+	log.Fatal(http.ListenAndServe(":8080", mux))
+}
+
 func ExampleWithPanicHandler() {
 	// Panic handler that returns the stack in the response
 	ph := func(ctx context.Context, r *http.Request, err interface{}) {
@@ -145,6 +157,22 @@ func ExampleWithPanicHandler() {
 	log.Fatal(http.ListenAndServe(":8080", mux))
 }
 
+func ExampleWithPanicHandlerStack() {
+	// Panic handler that logs the stack captured at recovery
+	ph := func(ctx context.Context, r *http.Request, err interface{}, stack []byte) {
+		log.Printf("panic: %v\n%s", err, stack)
+		roxi.GetWriter(ctx).WriteHeader(http.StatusInternalServerError)
+	}
+
+	mux := roxi.New(roxi.WithPanicHandlerStack(ph))
+
+	mux.GET("/panic", func(ctx context.Context, r *http.Request) error {
+		panic("at the disco")
+	})
+
+	log.Fatal(http.ListenAndServe(":8080", mux))
+}
+
 func ExampleWithPanicHandler_disabled() {
 	mux := roxi.New(roxi.WithPanicHandler(nil))
 
@@ -166,3 +194,54 @@ func ExampleMux_FileServer() {
 
 	log.Fatal(http.ListenAndServe(":8080", mux))
 }
+
+func ExampleAbort() {
+	// Middleware can short-circuit a request with a response.
+	auth := func(next roxi.HandlerFunc) roxi.HandlerFunc {
+		return func(ctx context.Context, r *http.Request) error {
+			if r.Header.Get("Authorization") == "" {
+				return roxi.Abort(http.StatusUnauthorized, roxi.JSON(http.StatusUnauthorized, map[string]string{
+					"error": "missing credentials",
+				}))
+			}
+			return next(ctx, r)
+		}
+	}
+
+	mux := roxi.New()
+	mux.GET("/private", func(ctx context.Context, r *http.Request) error {
+		roxi.GetWriter(ctx).WriteHeader(204)
+		return nil
+	}, auth)
+
+	r, _ := http.NewRequest("GET", "/private", nil)
+	w := httptest.NewRecorder()
+
+	mux.ServeHTTP(w, r)
+
+	fmt.Println(w.Code, w.Body.String())
+	// Output: 401 {"error":"missing credentials"}
+}
+
+func ExampleNewTestContext() {
+	handler := func(ctx context.Context, r *http.Request) error {
+		return roxi.Respond(ctx, roxi.JSON(http.StatusOK, map[string]string{
+			"request_id": roxi.GetRequestID(ctx),
+		}))
+	}
+
+	// Call the handler without a mux, with middleware applied as usual.
+	h := roxi.RequestID(roxi.WithRequestIDGenerator(func() string {
+		return "42"
+	}))(handler)
+
+	r, _ := http.NewRequest("GET", "/users/me", nil)
+	w := httptest.NewRecorder()
+
+	if err := h(roxi.NewTestContext(w), r); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(w.Code, w.Body.String())
+	// Output: 200 {"request_id":"42"}
+}
@@ -0,0 +1,367 @@
+// Copyright 2025 Brandon Epperson
+// SPDX-License-Identifier: Apache-2.0
+
+package roxi
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net/http"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FileServerOption configures a file server registered with Mux.FileServer.
+type FileServerOption func(*fileServer)
+
+// WithFileNotFound sets the handler that responds when a requested file
+// does not exist, in place of the mux's not found handler.
+//
+// This allows asset misses to respond differently than page misses.
+func WithFileNotFound(handler http.Handler) FileServerOption {
+	return func(f *fileServer) {
+		f.notFound = handler
+	}
+}
+
+// WithoutDirListing disables the listing of directories, see
+// FileServerConfig.DisableDirListing.
+func WithoutDirListing() FileServerOption {
+	return func(f *fileServer) {
+		f.noDirListing = true
+	}
+}
+
+// fileServer holds the configuration for a registered file server.
+type fileServer struct {
+	notFound     http.Handler
+	maxAge       time.Duration
+	etag         func(name string, info fs.FileInfo) string
+	filter       *regexp.Regexp
+	noDirListing bool
+}
+
+// FileServerConfig configures a file server registered with
+// Mux.FileServerWithConfig.
+type FileServerConfig struct {
+	// MaxAge sets the max-age of the Cache-Control header of served files.
+	// No Cache-Control header is set if it's zero.
+	MaxAge time.Duration
+
+	// ETag returns the entity tag of a file, including its quotes, or ""
+	// to omit it. Requests with a matching If-None-Match header
+	// receive 304 Not Modified.
+	ETag func(name string, info fs.FileInfo) string
+
+	// Filter restricts the files served to those whose path matches it,
+	// other files are treated as missing. The path is the value of the
+	// wildcard cleaned with CleanPath, relative to the root of the file
+	// system without a leading '/' and with no dot segments, such as
+	// "css/site.css" for "/static/css/../css/site.css".
+	Filter *regexp.Regexp
+
+	// NotFound handles requests for missing files, see WithFileNotFound.
+	NotFound http.Handler
+
+	// DisableDirListing treats directories without an index.html file as
+	// missing, instead of responding with a listing of their files.
+	// Directories with an index.html are served their index as usual.
+	DisableDirListing bool
+}
+
+// FileServer wraps http.FileServer to serve files from the provided http.FileSystem.
+//
+// The path must end in a wildcard with the name '*file'.
+//
+// Requests for files that do not exist are handled by the mux's not found
+// handler, unless one is provided with WithFileNotFound.
+func (m *Mux) FileServer(path string, fsys http.FileSystem, opts ...FileServerOption) {
+	// check path
+	if err := checkFSPath(path, m.treeConfig.wildcard); err != nil {
+		panic(err)
+	}
+
+	cfg := &fileServer{}
+	for _, o := range opts {
+		o(cfg)
+	}
+
+	m.registerFileServer(path, []http.FileSystem{fsys}, cfg)
+}
+
+// FileServerWithConfig registers a file server like FileServer, configured
+// with caching headers and a filter for the files served.
+//
+// Conditional requests are handled by http.FileServer, using the
+// ETag and the modification time of the file.
+func (m *Mux) FileServerWithConfig(path string, fsys http.FileSystem, cfg FileServerConfig) {
+	if err := checkFSPath(path, m.treeConfig.wildcard); err != nil {
+		panic(err)
+	}
+
+	m.registerFileServer(path, []http.FileSystem{fsys}, &fileServer{
+		notFound:     cfg.NotFound,
+		maxAge:       cfg.MaxAge,
+		etag:         cfg.ETag,
+		filter:       cfg.Filter,
+		noDirListing: cfg.DisableDirListing,
+	})
+}
+
+// FileServerFS registers a file server like FileServer, which serves each
+// file from the first of the file systems that contains it.
+//
+// Requests are handled by the mux's not found handler only if the file is
+// missing from every file system. Errors other than a missing file stop
+// the lookup: a permission error responds with 403 Forbidden, as with
+// http.FileServer, and others are returned to the mux's error handler.
+func (m *Mux) FileServerFS(path string, systems ...http.FileSystem) {
+	if err := checkFSPath(path, m.treeConfig.wildcard); err != nil {
+		panic(err)
+	}
+
+	if len(systems) == 0 {
+		panic("no file systems provided for path '" + path + "'")
+	}
+
+	m.registerFileServer(path, systems, &fileServer{})
+}
+
+// FileServerEmbed registers a file server like FileServer, serving the
+// files under root in fsys, such as an embed.FS:
+//
+//	//go:embed static
+//	var static embed.FS
+//
+//	mux.FileServerEmbed("/assets/*file", static, "static")
+//
+// A request for "/assets/css/site.css" serves "static/css/site.css".
+// A root of "." or "" serves the whole file system.
+//
+// FileServerEmbed panics if root is not a directory of fsys.
+func (m *Mux) FileServerEmbed(path string, fsys fs.FS, root string, opts ...FileServerOption) {
+	if err := checkFSPath(path, m.treeConfig.wildcard); err != nil {
+		panic(err)
+	}
+
+	if root == "" {
+		root = "."
+	}
+
+	info, err := fs.Stat(fsys, root)
+	if err != nil {
+		panic("file server root '" + root + "' for path '" + path + "' not found: " + err.Error())
+	}
+	if !info.IsDir() {
+		panic("file server root '" + root + "' for path '" + path + "' is not a directory")
+	}
+
+	sub, err := fs.Sub(fsys, root)
+	if err != nil {
+		panic("file server root '" + root + "' for path '" + path + "' is not valid: " + err.Error())
+	}
+
+	cfg := &fileServer{}
+	for _, o := range opts {
+		o(cfg)
+	}
+
+	m.registerFileServer(path, []http.FileSystem{http.FS(sub)}, cfg)
+}
+
+// registerFileServer registers a GET route at path serving files from
+// the first of the file systems that contains the file.
+func (m *Mux) registerFileServer(path string, systems []http.FileSystem, cfg *fileServer) {
+	if cfg.noDirListing {
+		systems = slices.Clone(systems)
+		for i, fsys := range systems {
+			systems[i] = noListingFS{fsys}
+		}
+	}
+
+	m.GET(path, func(ctx context.Context, r *http.Request) error {
+		w := GetWriter(ctx)
+		file := r.PathValue("file")
+		name := toString(CleanPath(file))
+
+		if cfg.filter != nil && !cfg.filter.MatchString(strings.TrimPrefix(name, "/")) {
+			m.fileNotFound(cfg, w, r)
+			return nil
+		}
+
+		// directories are opened without their trailing '/', which
+		// http.FS rejects.
+		open := name
+		if len(open) > 1 {
+			open = strings.TrimSuffix(open, "/")
+		}
+
+		for _, fsys := range systems {
+			f, err := fsys.Open(open)
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					continue
+				}
+
+				// respond like http.FileServer.
+				if errors.Is(err, fs.ErrPermission) {
+					http.Error(w, "403 Forbidden", http.StatusForbidden)
+					return nil
+				}
+
+				m.log("roxi: file server error", "file", file, "error", err)
+				return err
+			}
+
+			cfg.setHeaders(w, name, f)
+
+			opened := &openedFS{FileSystem: fsys, name: open, file: f}
+			r.URL.Path = file
+			http.FileServer(opened).ServeHTTP(w, r)
+
+			// http.FileServer redirected or opened another file.
+			if opened.file != nil {
+				_ = opened.file.Close()
+			}
+			return nil
+		}
+
+		m.fileNotFound(cfg, w, r)
+		return nil
+	})
+}
+
+// openedFS is an http.FileSystem handing the file already opened by the
+// file server to http.FileServer, so it's opened once per request.
+type openedFS struct {
+	http.FileSystem
+	name string
+	file http.File
+}
+
+// Open implements the http.FileSystem interface.
+func (fsys *openedFS) Open(name string) (http.File, error) {
+	if fsys.file == nil || name != fsys.name {
+		return fsys.FileSystem.Open(name)
+	}
+
+	f := fsys.file
+	fsys.file = nil
+	return f, nil
+}
+
+// setHeaders sets the caching headers of a served file.
+func (cfg *fileServer) setHeaders(w http.ResponseWriter, name string, f http.File) {
+	if cfg.maxAge == 0 && cfg.etag == nil {
+		return
+	}
+
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		return
+	}
+
+	if cfg.maxAge > 0 {
+		w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(int(cfg.maxAge.Seconds())))
+	}
+
+	if cfg.etag != nil {
+		if tag := cfg.etag(name, info); tag != "" {
+			w.Header().Set("ETag", tag)
+		}
+	}
+}
+
+// noListingFS is an http.FileSystem reporting directories without an
+// index.html file as missing, so they are never listed.
+type noListingFS struct {
+	http.FileSystem
+}
+
+// Open implements the http.FileSystem interface.
+func (fsys noListingFS) Open(name string) (http.File, error) {
+	f, err := fsys.FileSystem.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil || !info.IsDir() {
+		return f, nil
+	}
+
+	index, err := fsys.FileSystem.Open(path.Join(name, "index.html"))
+	if err != nil {
+		_ = f.Close()
+		return nil, fs.ErrNotExist
+	}
+	_ = index.Close()
+
+	return f, nil
+}
+
+// fileNotFound responds to a request for a missing file.
+func (m *Mux) fileNotFound(cfg *fileServer, w http.ResponseWriter, r *http.Request) {
+	switch {
+	case cfg.notFound != nil:
+		cfg.notFound.ServeHTTP(w, r)
+	case m.notFound != nil:
+		m.notFound.ServeHTTP(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func checkFSPath(path string, wildcard byte) error {
+	if len(path) == 0 {
+		return errors.New("cannot register empty path")
+	}
+
+	if len(path) > 0 && path[0] != '/' {
+		return errors.New("path '" + path + "' does not begin with '/'")
+	}
+
+	if suffix := "/" + string(wildcard) + "file"; !strings.HasSuffix(path, suffix) {
+		return errors.New("file server path must end in '" + suffix + "'")
+	}
+
+	return nil
+}
+
+// spaFallback serves the index file of a single-page app.
+type spaFallback struct {
+	fsys  http.FileSystem
+	index string
+}
+
+// serve writes the index file in response to r, and reports false if
+// the request is not eligible or the index could not be opened.
+func (s *spaFallback) serve(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	// asset requests should not receive the index.
+	if strings.Contains(path.Base(r.URL.Path), ".") {
+		return false
+	}
+
+	f, err := s.fsys.Open(s.index)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		return false
+	}
+
+	http.ServeContent(w, r, info.Name(), info.ModTime(), f)
+	return true
+}
@@ -0,0 +1,513 @@
+// Copyright 2025 Brandon Epperson
+// SPDX-License-Identifier: Apache-2.0
+
+package roxi
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+type mockFS struct {
+	opened bool
+}
+
+var mockFiles = http.FS(fstest.MapFS{
+	"test.html": {Data: []byte("<h1>Test</h1>")},
+	"index.js":  {Data: []byte("main()")},
+	"asset.png": {Data: []byte("png")},
+})
+
+func (f *mockFS) Open(name string) (http.File, error) {
+	switch name {
+	case "/test.html", "/index.js", "/asset.png":
+		f.opened = true
+		return mockFiles.Open(name)
+	case "/error.jpeg":
+		return nil, fmt.Errorf("diff error")
+	default:
+		return nil, fs.ErrNotExist
+	}
+}
+
+func Test_FileServer(t *testing.T) {
+	mux := NewWithDefaults()
+
+	fs := &mockFS{}
+	mux.FileServer("/files/*file", fs)
+
+	tests := []struct {
+		name       string
+		path       string
+		shouldOpen bool
+	}{
+		{"Match", "/files/test.html", true},
+		{"NoMatch", "/files/file.txt", false},
+		{"ReadError", "/files/error.jpeg", false},
+		{"CleanPath", "/files/../asset.png", true},
+	}
+
+	for _, tt := range tests {
+		fs.opened = false
+		t.Run(tt.name, func(t *testing.T) {
+			fs.opened = false
+			r, _ := http.NewRequest("GET", tt.path, nil)
+			w := httptest.NewRecorder()
+
+			mux.ServeHTTP(w, r)
+			if fs.opened != tt.shouldOpen {
+				t.Errorf("expected: [%v]; got: [%v]", tt.shouldOpen, fs.opened)
+				t.Errorf("file value (cleaned): [%v]", path.Clean(r.PathValue("file")))
+			}
+		})
+	}
+}
+
+func Test_FileServerNotFound(t *testing.T) {
+	page := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(404)
+		w.Write([]byte("<h1>Not Found</h1>"))
+	})
+
+	asset := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(404)
+		w.Write([]byte(`{"error":"asset not found"}`))
+	})
+
+	mux := New(WithNotFoundHandler(page))
+	mux.FileServer("/files/*file", &mockFS{}, WithFileNotFound(asset))
+	mux.FileServer("/pages/*file", &mockFS{})
+
+	tests := []struct {
+		name string
+		path string
+		body string
+	}{
+		{"FileServerNotFound", "/files/missing.png", `{"error":"asset not found"}`},
+		{"DefaultNotFound", "/pages/missing.html", "<h1>Not Found</h1>"},
+		{"UnmatchedRoute", "/missing", "<h1>Not Found</h1>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := http.NewRequest("GET", tt.path, nil)
+			w := httptest.NewRecorder()
+
+			mux.ServeHTTP(w, r)
+			if w.Code != 404 {
+				t.Errorf("expected: [%d]; got: [%d]", 404, w.Code)
+			}
+
+			if w.Body.String() != tt.body {
+				t.Errorf("expected: [%s]; got: [%s]", tt.body, w.Body.String())
+			}
+		})
+	}
+}
+
+// countingFS counts the files opened from an http.FileSystem.
+type countingFS struct {
+	http.FileSystem
+	opens int
+}
+
+func (f *countingFS) Open(name string) (http.File, error) {
+	f.opens++
+	return f.FileSystem.Open(name)
+}
+
+// countingWriter counts the calls to WriteHeader.
+type countingWriter struct {
+	*httptest.ResponseRecorder
+	headers int
+}
+
+func (w *countingWriter) WriteHeader(code int) {
+	w.headers++
+	w.ResponseRecorder.WriteHeader(code)
+}
+
+func Test_FileServerMissingFile(t *testing.T) {
+	fsys := &countingFS{FileSystem: http.FS(fstest.MapFS{"app.css": {Data: []byte("body {}")}})}
+
+	mux := New()
+	mux.FileServer("/static/*file", fsys)
+
+	w := &countingWriter{ResponseRecorder: httptest.NewRecorder()}
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/static/missing.css", nil))
+
+	if w.Code != 404 || w.Body.String() != "Not Found" {
+		t.Errorf("expected: [404 Not Found]; got: [%d %s]", w.Code, w.Body.String())
+	}
+
+	if w.headers != 1 {
+		t.Errorf("expected a single response; got: [%d]", w.headers)
+	}
+
+	// http.FileServer would open the file again.
+	if fsys.opens != 1 {
+		t.Errorf("expected: [1] open; got: [%d]", fsys.opens)
+	}
+}
+
+func Test_FileServerOpens(t *testing.T) {
+	fsys := &countingFS{FileSystem: http.FS(fstest.MapFS{
+		"app.css":         {Data: []byte("body {}")},
+		"docs/index.html": {Data: []byte("<h1>Docs</h1>")},
+	})}
+
+	mux := New()
+	mux.FileServer("/static/*file", fsys)
+	mux.FileServer("/assets/*file", fsys, WithoutDirListing())
+
+	tests := []struct {
+		name  string
+		path  string
+		opens int
+	}{
+		{"File", "/static/app.css", 1},
+		{"Index", "/static/docs/", 2},
+		{"NoListingIndex", "/assets/docs/", 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys.opens = 0
+
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+
+			if w.Code != 200 {
+				t.Errorf("expected: [%d]; got: [%d]", 200, w.Code)
+			}
+
+			if fsys.opens != tt.opens {
+				t.Errorf("expected: [%d] opens; got: [%d]", tt.opens, fsys.opens)
+			}
+		})
+	}
+}
+
+func Test_FileServerDirListing(t *testing.T) {
+	fsys := http.FS(fstest.MapFS{
+		"css/site.css":    {Data: []byte("body {}")},
+		"docs/index.html": {Data: []byte("<h1>Docs</h1>")},
+	})
+
+	mux := New()
+	mux.FileServer("/listed/*file", fsys)
+	mux.FileServer("/assets/*file", fsys, WithoutDirListing())
+	mux.FileServerWithConfig("/static/*file", fsys, FileServerConfig{DisableDirListing: true})
+
+	tests := []struct {
+		name string
+		path string
+		code int
+		body string
+	}{
+		{"Listed", "/listed/css/", 200, "<a href=\"site.css\">site.css</a>"},
+		{"Option", "/assets/css/", 404, "Not Found"},
+		{"OptionRoot", "/assets/", 404, "Not Found"},
+		{"OptionFile", "/assets/css/site.css", 200, "body {}"},
+		{"OptionIndex", "/assets/docs/", 200, "<h1>Docs</h1>"},
+		{"Config", "/static/css/", 404, "Not Found"},
+		{"ConfigNoSlash", "/static/css", 404, "Not Found"},
+		{"ConfigIndex", "/static/docs/", 200, "<h1>Docs</h1>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+
+			if w.Code != tt.code {
+				t.Errorf("expected: [%d]; got: [%d]", tt.code, w.Code)
+			}
+			if !strings.Contains(w.Body.String(), tt.body) {
+				t.Errorf("expected body containing: [%s]; got: [%s]", tt.body, w.Body.String())
+			}
+		})
+	}
+}
+
+func Test_FileServerEmbed(t *testing.T) {
+	fsys := fstest.MapFS{
+		"web/static/css/site.css": {Data: []byte("body {}")},
+		"web/static/index.html":   {Data: []byte("<h1>Home</h1>")},
+		"web/secret.db":           {Data: []byte("secret")},
+		"go.mod":                  {Data: []byte("module x")},
+	}
+
+	mux := New()
+	mux.FileServerEmbed("/assets/*file", fsys, "web/static")
+	mux.FileServerEmbed("/all/*file", fsys, "")
+
+	tests := []struct {
+		name string
+		path string
+		code int
+		body string
+	}{
+		{"File", "/assets/css/site.css", 200, "body {}"},
+		{"Index", "/assets/", 200, "<h1>Home</h1>"},
+		{"Missing", "/assets/missing.css", 404, "Not Found"},
+		{"OutsideRoot", "/assets/../secret.db", 404, "Not Found"},
+		{"WholeFS", "/all/go.mod", 200, "module x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.URL.Path = tt.path
+			w := httptest.NewRecorder()
+
+			mux.ServeHTTP(w, r)
+
+			if w.Code != tt.code {
+				t.Errorf("expected: [%d]; got: [%d]", tt.code, w.Code)
+			}
+			if w.Body.String() != tt.body {
+				t.Errorf("expected: [%s]; got: [%s]", tt.body, w.Body.String())
+			}
+		})
+	}
+}
+
+func Test_FileServerEmbedInvalidRoot(t *testing.T) {
+	fsys := fstest.MapFS{"static/site.css": {Data: []byte("body {}")}}
+
+	tests := []struct {
+		name string
+		root string
+		msg  string
+	}{
+		{"Missing", "public", "file server root 'public' for path '/*file' not found: open public: file does not exist"},
+		{"File", "static/site.css", "file server root 'static/site.css' for path '/*file' is not a directory"},
+		{"Invalid", "../static", "file server root '../static' for path '/*file' not found: open ../static: file does not exist"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if rec := recover(); fmt.Sprint(rec) != tt.msg {
+					t.Errorf("expected: [%v]; got: [%v]", tt.msg, rec)
+				}
+			}()
+			New().FileServerEmbed("/*file", fsys, tt.root)
+		})
+	}
+}
+
+func Test_SPAFallback(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "index.html"), []byte("<html>app</html>"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	mux := New(WithSPAFallback(http.Dir(dir), "/index.html"))
+	mux.GET("/api/users", func(ctx context.Context, r *http.Request) error {
+		_, err := GetWriter(ctx).Write([]byte("users"))
+		return err
+	})
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		code   int
+		body   string
+	}{
+		{"Route", "GET", "/api/users", 200, "users"},
+		{"Fallback", "GET", "/dashboard/settings", 200, "<html>app</html>"},
+		{"Asset", "GET", "/static/app.js", 404, "Not Found"},
+		{"Method", "POST", "/dashboard", 404, "Not Found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+
+			if w.Code != tt.code {
+				t.Errorf("expected status: [%d]; got: [%d]", tt.code, w.Code)
+			}
+
+			if w.Body.String() != tt.body {
+				t.Errorf("expected: [%s]; got: [%s]", tt.body, w.Body.String())
+			}
+		})
+	}
+}
+
+func Test_FileServerFS(t *testing.T) {
+	embedded := fstest.MapFS{
+		"app.css":  {Data: []byte("embedded css")},
+		"logo.svg": {Data: []byte("embedded logo")},
+	}
+	uploads := fstest.MapFS{
+		"logo.svg":   {Data: []byte("uploaded logo")},
+		"avatar.txt": {Data: []byte("uploaded avatar")},
+	}
+
+	mux := New()
+	mux.FileServerFS("/static/*file", http.FS(embedded), http.FS(uploads))
+	mux.FileServerFS("/broken/*file", &mockFS{}, http.FS(uploads))
+
+	tests := []struct {
+		name string
+		path string
+		code int
+		body string
+	}{
+		{"First", "/static/app.css", 200, "embedded css"},
+		{"FirstWins", "/static/logo.svg", 200, "embedded logo"},
+		{"Fallthrough", "/static/avatar.txt", 200, "uploaded avatar"},
+		{"Missing", "/static/missing.txt", 404, "Not Found"},
+		{"ReadError", "/broken/error.jpeg", 500, "Internal Server Error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+
+			if w.Code != tt.code {
+				t.Errorf("expected status: [%d]; got: [%d]", tt.code, w.Code)
+			}
+
+			if w.Body.String() != tt.body {
+				t.Errorf("expected: [%s]; got: [%s]", tt.body, w.Body.String())
+			}
+		})
+	}
+}
+
+func Test_FileServerWithConfig(t *testing.T) {
+	modTime := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	fsys := fstest.MapFS{
+		"app.css":   {Data: []byte("body {}"), ModTime: modTime},
+		"secret.db": {Data: []byte("secret"), ModTime: modTime},
+	}
+
+	mux := New()
+	mux.FileServerWithConfig("/static/*file", http.FS(fsys), FileServerConfig{
+		MaxAge: time.Hour,
+		ETag: func(name string, info fs.FileInfo) string {
+			return `"` + strconv.FormatInt(info.Size(), 10) + `"`
+		},
+		Filter: regexp.MustCompile(`\.css$`),
+	})
+
+	tests := []struct {
+		name   string
+		path   string
+		header map[string]string
+		code   int
+		cache  string
+		etag   string
+	}{
+		{"Headers", "/static/app.css", nil, 200, "public, max-age=3600", `"7"`},
+		{"IfNoneMatch", "/static/app.css", map[string]string{"If-None-Match": `"7"`}, 304, "public, max-age=3600", `"7"`},
+		{"IfModifiedSince", "/static/app.css", map[string]string{"If-Modified-Since": modTime.Format(http.TimeFormat)}, 304, "public, max-age=3600", `"7"`},
+		{"Filtered", "/static/secret.db", nil, 404, "", ""},
+		{"Missing", "/static/missing.css", nil, 404, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tt.path, nil)
+			for k, v := range tt.header {
+				r.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+
+			mux.ServeHTTP(w, r)
+
+			if w.Code != tt.code {
+				t.Errorf("expected status: [%d]; got: [%d]", tt.code, w.Code)
+			}
+
+			if got := w.Header().Get("Cache-Control"); got != tt.cache {
+				t.Errorf("expected Cache-Control: [%s]; got: [%s]", tt.cache, got)
+			}
+
+			if got := w.Header().Get("ETag"); got != tt.etag {
+				t.Errorf("expected ETag: [%s]; got: [%s]", tt.etag, got)
+			}
+		})
+	}
+}
+
+// brokenFS is an http.FileSystem failing to open "/broken.css".
+type brokenFS struct {
+	http.FileSystem
+}
+
+func (f brokenFS) Open(name string) (http.File, error) {
+	if name == "/broken.css" {
+		return nil, fs.ErrPermission
+	}
+	return f.FileSystem.Open(name)
+}
+
+func Test_FileServerFilter(t *testing.T) {
+	fsys := fstest.MapFS{
+		"css/site.css": {Data: []byte("body {}")},
+		"secret.db":    {Data: []byte("secret")},
+		"broken.css":   {Data: []byte("broken")},
+	}
+
+	mux := New(WithErrorHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "error", http.StatusInternalServerError)
+	})))
+	mux.FileServerWithConfig("/static/*file", brokenFS{http.FS(fsys)}, FileServerConfig{
+		Filter: regexp.MustCompile(`^(css/)?[a-z]+\.css$`),
+	})
+
+	tests := []struct {
+		name string
+		path string
+		code int
+		body string
+	}{
+		{"Match", "/static/css/site.css", 200, "body {}"},
+		{"NoMatch", "/static/secret.db", 404, "Not Found"},
+		{"MatchMissing", "/static/css/missing.css", 404, "Not Found"},
+		{"Forbidden", "/static/broken.css", 403, "403 Forbidden\n"},
+		{"CleanedMatch", "/static/css/../css/site.css", 200, "body {}"},
+		{"CleanedNoMatch", "/static/css/../secret.db", 404, "Not Found"},
+		{"CleanedTraversal", "/static/../../secret.db", 404, "Not Found"},
+		{"Root", "/static/", 404, "Not Found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.URL.Path = tt.path
+			w := httptest.NewRecorder()
+
+			mux.ServeHTTP(w, r)
+
+			if w.Code != tt.code {
+				t.Errorf("expected: [%d]; got: [%d]", tt.code, w.Code)
+			}
+			if w.Body.String() != tt.body {
+				t.Errorf("expected: [%s]; got: [%s]", tt.body, w.Body.String())
+			}
+		})
+	}
+}
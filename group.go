@@ -0,0 +1,126 @@
+// Copyright 2025 Brandon Epperson
+// SPDX-License-Identifier: Apache-2.0
+
+package roxi
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Group registers routes on a Mux that share a common path prefix
+// and middleware.
+type Group struct {
+	mux    *Mux
+	prefix string
+	mw     []MiddlewareFunc
+}
+
+// Group returns a Group that registers routes under the given prefix.
+//
+// The group's middleware wrap each route registered with the group,
+// inside of any global middleware registered on the mux.
+//
+// The prefix must begin with '/', any trailing '/' is removed.
+func (m *Mux) Group(prefix string, mw ...MiddlewareFunc) *Group {
+	return newGroup(m, "", nil, prefix, mw)
+}
+
+// Group returns a nested Group that registers routes under the
+// prefix of g joined with the given prefix.
+//
+// The nested group's middleware are applied inside of the middleware of g.
+func (g *Group) Group(prefix string, mw ...MiddlewareFunc) *Group {
+	return newGroup(g.mux, g.prefix, g.mw, prefix, mw)
+}
+
+func newGroup(m *Mux, base string, baseMW []MiddlewareFunc, prefix string, mw []MiddlewareFunc) *Group {
+	if len(prefix) == 0 {
+		panic("cannot register empty group prefix")
+	}
+
+	if prefix[0] != '/' {
+		panic("group prefix '" + prefix + "' does not begin with '/'")
+	}
+
+	stack := make([]MiddlewareFunc, 0, len(baseMW)+len(mw))
+	stack = append(stack, baseMW...)
+	stack = append(stack, mw...)
+
+	return &Group{
+		mux:    m,
+		prefix: base + strings.TrimRight(prefix, "/"),
+		mw:     stack,
+	}
+}
+
+// Prefix returns the path prefix of the group.
+func (g *Group) Prefix() string {
+	return g.prefix
+}
+
+// HandleError registers an error handler for routes registered under
+// the group prefix, see Mux.HandleError.
+func (g *Group) HandleError(handler http.Handler) {
+	// the prefix of a root group is empty.
+	prefix := g.prefix
+	if prefix == "" {
+		prefix = "/"
+	}
+	g.mux.HandleError(prefix, handler)
+}
+
+// Handle registers a HandlerFunc to handle requests at the given
+// method and path joined to the group prefix.
+//
+// The middleware provided are applied inside of the group's middleware.
+func (g *Group) Handle(method, path string, handlerFunc HandlerFunc, mw ...MiddlewareFunc) {
+	if len(path) == 0 {
+		panic("cannot register empty path")
+	}
+
+	if path[0] != '/' {
+		panic("path '" + path + "' does not begin with '/'")
+	}
+
+	stack := make([]MiddlewareFunc, 0, len(g.mw)+len(mw))
+	stack = append(stack, g.mw...)
+	stack = append(stack, mw...)
+
+	g.mux.Handle(method, g.prefix+path, handlerFunc, stack...)
+}
+
+// GET is a helper method for g.Handle("GET", path, handlerFunc, mw...).
+func (g *Group) GET(path string, handlerFunc HandlerFunc, mw ...MiddlewareFunc) {
+	g.Handle(http.MethodGet, path, handlerFunc, mw...)
+}
+
+// HEAD is a helper method for g.Handle("HEAD", path, handlerFunc, mw...).
+func (g *Group) HEAD(path string, handlerFunc HandlerFunc, mw ...MiddlewareFunc) {
+	g.Handle(http.MethodHead, path, handlerFunc, mw...)
+}
+
+// POST is a helper method for g.Handle("POST", path, handlerFunc, mw...).
+func (g *Group) POST(path string, handlerFunc HandlerFunc, mw ...MiddlewareFunc) {
+	g.Handle(http.MethodPost, path, handlerFunc, mw...)
+}
+
+// PUT is a helper method for g.Handle("PUT", path, handlerFunc, mw...).
+func (g *Group) PUT(path string, handlerFunc HandlerFunc, mw ...MiddlewareFunc) {
+	g.Handle(http.MethodPut, path, handlerFunc, mw...)
+}
+
+// PATCH is a helper method for g.Handle("PATCH", path, handlerFunc, mw...).
+func (g *Group) PATCH(path string, handlerFunc HandlerFunc, mw ...MiddlewareFunc) {
+	g.Handle(http.MethodPatch, path, handlerFunc, mw...)
+}
+
+// DELETE is a helper method for g.Handle("DELETE", path, handlerFunc, mw...).
+func (g *Group) DELETE(path string, handlerFunc HandlerFunc, mw ...MiddlewareFunc) {
+	g.Handle(http.MethodDelete, path, handlerFunc, mw...)
+}
+
+// OPTIONS is a helper method for g.Handle("OPTIONS", path, handlerFunc, mw...).
+func (g *Group) OPTIONS(path string, handlerFunc HandlerFunc, mw ...MiddlewareFunc) {
+	g.Handle(http.MethodOptions, path, handlerFunc, mw...)
+}
@@ -0,0 +1,102 @@
+// Copyright 2025 Brandon Epperson
+// SPDX-License-Identifier: Apache-2.0
+
+package roxi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_Group(t *testing.T) {
+	var calls []string
+
+	mux := New(WithMiddleware(trace(&calls, "global")))
+
+	api := mux.Group("/api/", trace(&calls, "api"))
+	v1 := api.Group("/v1", trace(&calls, "v1"))
+
+	h := func(ctx context.Context, r *http.Request) error {
+		calls = append(calls, "handler:"+r.PathValue("id"))
+		return nil
+	}
+
+	api.GET("/health", h)
+	v1.GET("/users/:id", h, trace(&calls, "route"))
+
+	tests := []struct {
+		name  string
+		path  string
+		calls string
+	}{
+		{"Group", "/api/health", "global,api,handler:"},
+		{"NestedGroup", "/api/v1/users/42", "global,api,v1,route,handler:42"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls = calls[:0]
+
+			r, _ := http.NewRequest("GET", tt.path, nil)
+			w := httptest.NewRecorder()
+
+			mux.ServeHTTP(w, r)
+			if w.Code != 200 {
+				t.Errorf("request failed with status [%d]", w.Code)
+			}
+
+			if got := strings.Join(calls, ","); got != tt.calls {
+				t.Errorf("expected: [%s]; got: [%s]", tt.calls, got)
+			}
+		})
+	}
+
+	if v1.Prefix() != "/api/v1" {
+		t.Errorf("expected: [%s]; got: [%s]", "/api/v1", v1.Prefix())
+	}
+}
+
+func Test_InvalidGroups(t *testing.T) {
+	tests := []struct {
+		name   string
+		prefix string
+		path   string
+	}{
+		{"EmptyPrefix", "", "/"},
+		{"InvalidPrefix", "api", "/"},
+		{"InvalidPath", "/api", "users"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if rec := recover(); rec == nil {
+					t.Error("failed to catch bad registration")
+				}
+			}()
+			New().Group(tt.prefix).GET(tt.path, emptyHandler)
+		})
+	}
+}
+
+func Test_GroupHandleErrorRoot(t *testing.T) {
+	mux := New()
+	root := mux.Group("/")
+	root.HandleError(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "root", http.StatusTeapot)
+	}))
+	root.GET("/users", func(ctx context.Context, r *http.Request) error {
+		return errors.New("failed")
+	})
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/users", nil))
+
+	if w.Code != http.StatusTeapot {
+		t.Errorf("expected: [%d]; got: [%d]", http.StatusTeapot, w.Code)
+	}
+}
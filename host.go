@@ -0,0 +1,154 @@
+// Copyright 2025 Brandon Epperson
+// SPDX-License-Identifier: Apache-2.0
+
+package roxi
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Host returns a Mux that handles requests whose Host header matches host.
+//
+// The returned mux is configured with the options and global middleware
+// of m. Requests that match no registered host are handled by m, or
+// rejected if WithStrictHost is set.
+//
+// Hosts are matched case insensitively, without the port or a trailing '.'.
+//
+// A host beginning with "*." matches any subdomain of the rest of the host,
+// so "*.example.com" matches "api.example.com" and "v1.api.example.com",
+// but not "example.com". Hosts registered without a wildcard take
+// precedence, followed by the wildcard host with the longest suffix.
+func (m *Mux) Host(host string) *Mux {
+	host = normalizeHost(host)
+	if host == "" {
+		panic("cannot register empty host")
+	}
+
+	if strings.Contains(host, "*") &&
+		(!strings.HasPrefix(host, "*.") || len(host) == 2 || strings.Contains(host[1:], "*")) {
+		panic("invalid host pattern '" + host + "'; a wildcard must be the first label of the host")
+	}
+
+	var sub *Mux
+	m.updateRoutes(func(rt *routeTable) error {
+		if sub = rt.hosts[host]; sub != nil {
+			return nil
+		}
+
+		sub = m.clone()
+		sub.scopedErrHandler = nil
+		// pre-routing and its middleware already ran on m.
+		sub.preRouting = nil
+		sub.preMiddleware = nil
+		sub.preHandler = nil
+
+		if rt.hosts == nil {
+			rt.hosts = make(map[string]*Mux)
+		}
+		rt.hosts[host] = sub
+		return nil
+	})
+	return sub
+}
+
+// host returns the Mux registered for a normalized host, or nil if the
+// host matches none.
+func (rt *routeTable) host(host string) *Mux {
+	if hm := rt.hosts[host]; hm != nil {
+		return hm
+	}
+
+	// try wildcards from the longest suffix, without allocating
+	// the keys for common host lengths.
+	var buf [256]byte
+	for i := strings.IndexByte(host, '.'); i >= 0; {
+		key := append(append(buf[:0], '*'), host[i:]...)
+		if hm := rt.hosts[string(key)]; hm != nil {
+			return hm
+		}
+
+		next := strings.IndexByte(host[i+1:], '.')
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+	return nil
+}
+
+// normalizeHost lowercases host and strips its port and trailing '.'.
+func normalizeHost(host string) string {
+	if i := strings.LastIndexByte(host, ':'); i >= 0 && !strings.Contains(host[i:], "]") {
+		host = host[:i]
+	}
+
+	host = strings.TrimPrefix(host, "[")
+	host = strings.TrimSuffix(host, "]")
+	host = strings.TrimSuffix(host, ".")
+	return strings.ToLower(host)
+}
+
+// HostGroup registers routes on the Mux of each of several hosts,
+// such as a canonical domain and its aliases.
+type HostGroup struct {
+	muxes []*Mux
+}
+
+// HostGroup returns a HostGroup that registers routes for each of the hosts,
+// see Host.
+func (m *Mux) HostGroup(hosts []string) *HostGroup {
+	if len(hosts) == 0 {
+		panic("cannot register empty host group")
+	}
+
+	g := &HostGroup{muxes: make([]*Mux, 0, len(hosts))}
+	for _, host := range hosts {
+		g.muxes = append(g.muxes, m.Host(host))
+	}
+	return g
+}
+
+// Handle registers a HandlerFunc to handle requests at the given
+// method and path for each host of the group.
+func (g *HostGroup) Handle(method, path string, handlerFunc HandlerFunc, mw ...MiddlewareFunc) {
+	for _, m := range g.muxes {
+		m.Handle(method, path, handlerFunc, mw...)
+	}
+}
+
+// GET is a helper method for g.Handle("GET", path, handlerFunc, mw...).
+func (g *HostGroup) GET(path string, handlerFunc HandlerFunc, mw ...MiddlewareFunc) {
+	g.Handle(http.MethodGet, path, handlerFunc, mw...)
+}
+
+// HEAD is a helper method for g.Handle("HEAD", path, handlerFunc, mw...).
+func (g *HostGroup) HEAD(path string, handlerFunc HandlerFunc, mw ...MiddlewareFunc) {
+	g.Handle(http.MethodHead, path, handlerFunc, mw...)
+}
+
+// POST is a helper method for g.Handle("POST", path, handlerFunc, mw...).
+func (g *HostGroup) POST(path string, handlerFunc HandlerFunc, mw ...MiddlewareFunc) {
+	g.Handle(http.MethodPost, path, handlerFunc, mw...)
+}
+
+// PUT is a helper method for g.Handle("PUT", path, handlerFunc, mw...).
+func (g *HostGroup) PUT(path string, handlerFunc HandlerFunc, mw ...MiddlewareFunc) {
+	g.Handle(http.MethodPut, path, handlerFunc, mw...)
+}
+
+// PATCH is a helper method for g.Handle("PATCH", path, handlerFunc, mw...).
+func (g *HostGroup) PATCH(path string, handlerFunc HandlerFunc, mw ...MiddlewareFunc) {
+	g.Handle(http.MethodPatch, path, handlerFunc, mw...)
+}
+
+// DELETE is a helper method for g.Handle("DELETE", path, handlerFunc, mw...).
+func (g *HostGroup) DELETE(path string, handlerFunc HandlerFunc, mw ...MiddlewareFunc) {
+	g.Handle(http.MethodDelete, path, handlerFunc, mw...)
+}
+
+// OPTIONS is a helper method for g.Handle("OPTIONS", path, handlerFunc, mw...).
+func (g *HostGroup) OPTIONS(path string, handlerFunc HandlerFunc, mw ...MiddlewareFunc) {
+	g.Handle(http.MethodOptions, path, handlerFunc, mw...)
+}
@@ -0,0 +1,191 @@
+// Copyright 2025 Brandon Epperson
+// SPDX-License-Identifier: Apache-2.0
+
+package roxi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// respondWith returns a HandlerFunc writing body.
+func respondWith(body string) HandlerFunc {
+	return func(ctx context.Context, r *http.Request) error {
+		_, err := GetWriter(ctx).Write([]byte(body))
+		return err
+	}
+}
+
+func Test_Host(t *testing.T) {
+	mux := New()
+	mux.GET("/", respondWith("default"))
+	mux.Host("api.example.com").GET("/", respondWith("api"))
+	mux.Host("Admin.Example.com").GET("/users", respondWith("admin"))
+
+	tests := []struct {
+		name string
+		host string
+		path string
+		code int
+		body string
+	}{
+		{"Host", "api.example.com", "/", 200, "api"},
+		{"Normalized", "ADMIN.example.com.:8443", "/users", 200, "admin"},
+		{"Default", "example.com", "/", 200, "default"},
+		{"HostNotFound", "admin.example.com", "/", 404, "Not Found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tt.path, nil)
+			r.Host = tt.host
+			w := httptest.NewRecorder()
+
+			mux.ServeHTTP(w, r)
+
+			if w.Code != tt.code {
+				t.Errorf("expected status: [%d]; got: [%d]", tt.code, w.Code)
+			}
+
+			if w.Body.String() != tt.body {
+				t.Errorf("expected: [%s]; got: [%s]", tt.body, w.Body.String())
+			}
+		})
+	}
+}
+
+func Test_HostGroup(t *testing.T) {
+	mux := New()
+	mux.HostGroup([]string{"app.com", "www.app.com"}).GET("/", respondWith("app"))
+
+	tests := []struct {
+		name string
+		host string
+		code int
+	}{
+		{"Canonical", "app.com", 200},
+		{"Alias", "www.app.com:80", 200},
+		{"Unlisted", "other.com", 404},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.Host = tt.host
+			w := httptest.NewRecorder()
+
+			mux.ServeHTTP(w, r)
+
+			if w.Code != tt.code {
+				t.Errorf("expected: [%d]; got: [%d]", tt.code, w.Code)
+			}
+		})
+	}
+}
+
+func Test_NormalizeHost(t *testing.T) {
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"example.com", "example.com"},
+		{"Example.COM:8080", "example.com"},
+		{"example.com.", "example.com"},
+		{"[::1]:8080", "::1"},
+		{"[::1]", "::1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			if got := normalizeHost(tt.host); got != tt.want {
+				t.Errorf("expected: [%s]; got: [%s]", tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_StrictHost(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   []func(*Mux)
+		host   string
+		noHost bool
+		code   int
+	}{
+		{"Strict", []func(*Mux){WithStrictHost()}, "other.com", false, 421},
+		{"StrictMatch", []func(*Mux){WithStrictHost()}, "api.example.com", false, 200},
+		{"StrictNoHosts", []func(*Mux){WithStrictHost()}, "other.com", true, 200},
+		{"Default", nil, "other.com", false, 200},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := New(tt.opts...)
+			mux.GET("/", respondWith("default"))
+			if !tt.noHost {
+				mux.Host("api.example.com").GET("/", respondWith("api"))
+			}
+
+			r := httptest.NewRequest("GET", "/", nil)
+			r.Host = tt.host
+			w := httptest.NewRecorder()
+
+			mux.ServeHTTP(w, r)
+
+			if w.Code != tt.code {
+				t.Errorf("expected: [%d]; got: [%d]", tt.code, w.Code)
+			}
+		})
+	}
+}
+
+func Test_WildcardHost(t *testing.T) {
+	mux := New()
+	mux.GET("/", respondWith("default"))
+	mux.Host("*.example.com").GET("/", respondWith("tenant"))
+	mux.Host("*.eu.example.com").GET("/", respondWith("eu"))
+	mux.Host("admin.example.com").GET("/", respondWith("admin"))
+
+	tests := []struct {
+		name string
+		host string
+		body string
+	}{
+		{"Subdomain", "acme.example.com", "tenant"},
+		{"Nested", "v1.acme.example.com", "tenant"},
+		{"LongestSuffix", "acme.eu.example.com", "eu"},
+		{"ExactFirst", "admin.example.com", "admin"},
+		{"Port", "ACME.example.com:8080", "tenant"},
+		{"Apex", "example.com", "default"},
+		{"OtherDomain", "acme.example.org", "default"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.Host = tt.host
+			w := httptest.NewRecorder()
+
+			mux.ServeHTTP(w, r)
+
+			if w.Body.String() != tt.body {
+				t.Errorf("expected: [%s]; got: [%s]", tt.body, w.Body.String())
+			}
+		})
+	}
+}
+
+func Test_WildcardHostInvalid(t *testing.T) {
+	for _, host := range []string{"*", "*.", "api.*.com", "*example.com", "*.*.example.com"} {
+		t.Run(host, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected panic")
+				}
+			}()
+			New().Host(host)
+		})
+	}
+}
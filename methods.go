@@ -6,6 +6,7 @@ package roxi
 import (
 	"net/http"
 	"strings"
+	"sync/atomic"
 )
 
 // method flags for caching allowed methods for routes.
@@ -66,3 +67,46 @@ func (m methodFlag) String() string {
 	}
 	return strings.Join(methods, ", ")
 }
+
+// allowHeaders caches the Allow header value of each set of methods.
+var allowHeaders [TRACE << 1]atomic.Pointer[string]
+
+// header returns the methods formatted for the Allow header, without
+// allocating after the first call for a set of methods.
+func (m methodFlag) header() string {
+	if s := allowHeaders[m].Load(); s != nil {
+		return *s
+	}
+
+	s := m.String()
+	allowHeaders[m].Store(&s)
+	return s
+}
+
+// AutoOptions returns a handler for WithOptionsHandler that responds to
+// OPTIONS requests with 204 No Content and an Allow header listing the
+// methods registered for the path, including OPTIONS:
+//
+//	mux := roxi.New(roxi.WithOptionsHandler(roxi.AutoOptions()))
+//
+// The mux only calls the handler for paths with a registered route,
+// other OPTIONS requests are handled by the not found handler.
+func AutoOptions() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		h.Set("Allow", (parseAllow(h.Get("Allow")) | OPTIONS).header())
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// parseAllow returns the methods listed in an Allow header value.
+// Unknown methods are ignored.
+func parseAllow(allow string) methodFlag {
+	var flags methodFlag
+	for allow != "" {
+		var method string
+		method, allow, _ = strings.Cut(allow, ",")
+		flags |= httpMethods[strings.TrimSpace(method)]
+	}
+	return flags
+}
@@ -0,0 +1,73 @@
+// Copyright 2025 Brandon Epperson
+// SPDX-License-Identifier: Apache-2.0
+
+package roxi
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// MetricsSink receives an observation for each request handled by the
+// Metrics middleware.
+type MetricsSink interface {
+	// Observe records a request for the matched route pattern, such
+	// as "/users/:id", with its response status and duration.
+	Observe(method, pattern string, status int, dur time.Duration)
+}
+
+// Metrics returns a middleware that reports the method, route pattern,
+// response status and duration of each request to the sink.
+//
+// If the handler returns an error before writing a status, the status is
+// reported as 500 Internal Server Error, or the code of an *AbortError.
+// Handlers that write nothing are reported as 200 OK.
+func Metrics(sink MetricsSink) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, r *http.Request) error {
+			start := time.Now()
+
+			ctx, stats := WrapWriter(ctx)
+			err := next(ctx, r)
+
+			sink.Observe(r.Method, Pattern(ctx, r), stats.status(err), time.Since(start))
+			return err
+		}
+	}
+}
+
+// MetricsKey identifies the requests tallied by a MetricsCounter.
+type MetricsKey struct {
+	Method  string
+	Pattern string
+	Status  int
+}
+
+// MetricsCounter is a MetricsSink that counts requests by method, route
+// pattern and status. The zero value is ready to use.
+type MetricsCounter struct {
+	mu     sync.Mutex
+	counts map[MetricsKey]uint64
+}
+
+// Observe implements the MetricsSink interface.
+func (c *MetricsCounter) Observe(method, pattern string, status int, _ time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.counts == nil {
+		c.counts = make(map[MetricsKey]uint64)
+	}
+	c.counts[MetricsKey{method, pattern, status}]++
+}
+
+// Counts returns a copy of the request counts.
+func (c *MetricsCounter) Counts() map[MetricsKey]uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return maps.Clone(c.counts)
+}
@@ -0,0 +1,70 @@
+// Copyright 2025 Brandon Epperson
+// SPDX-License-Identifier: Apache-2.0
+
+package roxi
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_Metrics(t *testing.T) {
+	var sink MetricsCounter
+
+	mux := New(WithMiddleware(Metrics(&sink)))
+	mux.GET("/users/:id", func(ctx context.Context, r *http.Request) error {
+		_, err := GetWriter(ctx).Write([]byte("user"))
+		return err
+	})
+	mux.POST("/users", func(ctx context.Context, r *http.Request) error {
+		GetWriter(ctx).WriteHeader(http.StatusCreated)
+		return nil
+	})
+	mux.GET("/empty", func(ctx context.Context, r *http.Request) error {
+		return nil
+	})
+	mux.GET("/error", func(ctx context.Context, r *http.Request) error {
+		return errors.New("failed")
+	})
+	mux.GET("/abort", func(ctx context.Context, r *http.Request) error {
+		return Abort(http.StatusUnauthorized, nil)
+	})
+	mux.GET("/written", func(ctx context.Context, r *http.Request) error {
+		GetWriter(ctx).WriteHeader(http.StatusAccepted)
+		return errors.New("failed after writing")
+	})
+
+	requests := []struct {
+		method string
+		path   string
+	}{
+		{"GET", "/users/1"},
+		{"GET", "/users/2"},
+		{"POST", "/users"},
+		{"GET", "/empty"},
+		{"GET", "/error"},
+		{"GET", "/abort"},
+		{"GET", "/written"},
+	}
+
+	for _, req := range requests {
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(req.method, req.path, nil))
+	}
+
+	want := map[MetricsKey]uint64{
+		{"GET", "/users/:id", http.StatusOK}:              2,
+		{"POST", "/users", http.StatusCreated}:            1,
+		{"GET", "/empty", http.StatusOK}:                  1,
+		{"GET", "/error", http.StatusInternalServerError}: 1,
+		{"GET", "/abort", http.StatusUnauthorized}:        1,
+		{"GET", "/written", http.StatusAccepted}:          1,
+	}
+
+	if got := sink.Counts(); !maps.Equal(got, want) {
+		t.Errorf("expected: [%v]; got: [%v]", want, got)
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2025 Brandon Epperson
+// SPDX-License-Identifier: Apache-2.0
+
+package roxi
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// MiddlewareFunc wraps a HandlerFunc to execute logic before and/or after
+// the next HandlerFunc in the chain.
+//
+// A middleware may stop the chain by returning without calling next.
+type MiddlewareFunc func(next HandlerFunc) HandlerFunc
+
+// MiddlewareStack wraps the handler with the provided middleware.
+//
+// Middleware are applied outer-to-inner, so the first middleware
+// provided is the first to execute:
+//
+//	MiddlewareStack(h, a, b, c) == a(b(c(h)))
+//
+// Nil middleware are skipped.
+func MiddlewareStack(handler HandlerFunc, mw ...MiddlewareFunc) HandlerFunc {
+	for i := len(mw) - 1; i >= 0; i-- {
+		if mw[i] != nil {
+			handler = mw[i](handler)
+		}
+	}
+	return handler
+}
+
+// Chain is a reusable list of middleware, applied to routes in the same
+// order as MiddlewareStack:
+//
+//	chain := roxi.NewChain(logging, auth)
+//	mux.GET("/x", h, chain.Middleware()...)
+//
+// A Chain is immutable, so it's safe to share between routes and to
+// extend with Append.
+type Chain struct {
+	mw []MiddlewareFunc
+}
+
+// NewChain returns a Chain of the provided middleware.
+func NewChain(mw ...MiddlewareFunc) Chain {
+	return Chain{slices.Clone(mw)}
+}
+
+// Append returns a new Chain with mw added after the middleware of c,
+// so they execute closer to the handler. c is not modified.
+func (c Chain) Append(mw ...MiddlewareFunc) Chain {
+	return Chain{slices.Concat(c.mw, mw)}
+}
+
+// Then wraps the handler with the middleware of the chain, see
+// MiddlewareStack.
+func (c Chain) Then(handler HandlerFunc) HandlerFunc {
+	return MiddlewareStack(handler, c.mw...)
+}
+
+// Middleware returns a copy of the middleware of the chain, such as to
+// register a route with.
+func (c Chain) Middleware() []MiddlewareFunc {
+	return slices.Clone(c.mw)
+}
+
+// MiddlewareDepth returns the number of middleware wrapping the handler
+// of the matched route, including global and group middleware, and the
+// middleware of both muxes for routes registered with Mount.
+//
+// It returns 0 if the context does not belong to a request served by a Mux.
+func MiddlewareDepth(ctx context.Context) int {
+	if v, ok := ctx.(*writerContext); ok {
+		return v.depth
+	}
+	return 0
+}
+
+// withDepth wraps a handler to add the number of non-nil middleware in
+// stack to the depth in the request context, so the middleware of a
+// mounted mux add to those of the parent.
+func withDepth(handler HandlerFunc, stack []MiddlewareFunc) HandlerFunc {
+	var depth int
+	for _, mw := range stack {
+		if mw != nil {
+			depth++
+		}
+	}
+
+	if depth == 0 {
+		return handler
+	}
+
+	return func(ctx context.Context, r *http.Request) error {
+		if v, ok := ctx.(*writerContext); ok {
+			v.depth += depth
+		}
+		return handler(ctx, r)
+	}
+}
+
+// RequireHeader returns a middleware that rejects requests without a
+// non-empty value for the named header, responding with the given status.
+//
+// The response is written with Abort, so it bypasses the error handler.
+func RequireHeader(name string, status int) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, r *http.Request) error {
+			if r.Header.Get(name) == "" {
+				return Abort(status, nil)
+			}
+			return next(ctx, r)
+		}
+	}
+}
+
+// MethodOverrideHeader is the header read by MethodOverride.
+const MethodOverrideHeader = "X-HTTP-Method-Override"
+
+// MethodOverride returns a pre-routing function, see WithPreRouting, that
+// lets clients limited to GET and POST, such as HTML forms, tunnel
+// PUT, PATCH and DELETE requests through POST.
+//
+// The method is read from the X-HTTP-Method-Override header, or the form
+// field if formField is not empty. Reading the form field parses the
+// request body, as with r.PostFormValue.
+//
+// Only POST requests are overridden, and only to PUT, PATCH or DELETE.
+func MethodOverride(formField string) func(r *http.Request) {
+	return func(r *http.Request) {
+		if r.Method != http.MethodPost {
+			return
+		}
+
+		method := r.Header.Get(MethodOverrideHeader)
+		if method == "" && formField != "" {
+			method = r.PostFormValue(formField)
+		}
+
+		switch method = strings.ToUpper(method); method {
+		case http.MethodPut, http.MethodPatch, http.MethodDelete:
+			r.Method = method
+		}
+	}
+}
@@ -0,0 +1,214 @@
+// Copyright 2025 Brandon Epperson
+// SPDX-License-Identifier: Apache-2.0
+
+package roxi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// trace returns a middleware that appends name to calls before calling next.
+func trace(calls *[]string, name string) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, r *http.Request) error {
+			*calls = append(*calls, name)
+			return next(ctx, r)
+		}
+	}
+}
+
+func Test_MiddlewareStack(t *testing.T) {
+	var calls []string
+
+	h := MiddlewareStack(func(ctx context.Context, r *http.Request) error {
+		calls = append(calls, "handler")
+		return nil
+	}, trace(&calls, "a"), nil, trace(&calls, "b"))
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	_ = h(context.Background(), r)
+
+	if got := strings.Join(calls, ","); got != "a,b,handler" {
+		t.Errorf("expected: [%s]; got: [%s]", "a,b,handler", got)
+	}
+}
+
+func Test_Chain(t *testing.T) {
+	var calls []string
+
+	handler := func(ctx context.Context, r *http.Request) error {
+		calls = append(calls, "handler")
+		return nil
+	}
+
+	base := NewChain(trace(&calls, "a"), nil, trace(&calls, "b"))
+	ext := base.Append(trace(&calls, "c"))
+	other := base.Append(trace(&calls, "d"))
+
+	mux := New()
+	mux.GET("/base", base.Then(handler))
+	mux.GET("/ext", ext.Then(handler))
+	mux.GET("/other", handler, other.Middleware()...)
+
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{"Base", "/base", "a,b,handler"},
+		{"Append", "/ext", "a,b,c,handler"},
+		{"AppendCopy", "/other", "a,b,d,handler"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls = nil
+			mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", tt.path, nil))
+
+			if got := strings.Join(calls, ","); got != tt.want {
+				t.Errorf("expected: [%s]; got: [%s]", tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_MuxMiddleware(t *testing.T) {
+	var calls []string
+
+	mux := New(
+		WithMiddleware(trace(&calls, "global1")),
+		WithMiddleware(trace(&calls, "global2")),
+	)
+
+	mux.GET("/foo", func(ctx context.Context, r *http.Request) error {
+		calls = append(calls, "handler")
+		return nil
+	}, trace(&calls, "route"))
+
+	r, _ := http.NewRequest("GET", "/foo", nil)
+	mux.ServeHTTP(httptest.NewRecorder(), r)
+
+	want := "global1,global2,route,handler"
+	if got := strings.Join(calls, ","); got != want {
+		t.Errorf("expected: [%s]; got: [%s]", want, got)
+	}
+}
+
+func Test_RequireHeader(t *testing.T) {
+	mux := New()
+	mux.GET("/", func(ctx context.Context, r *http.Request) error {
+		GetWriter(ctx).WriteHeader(204)
+		return nil
+	}, RequireHeader("X-Api-Key", http.StatusUnauthorized))
+
+	tests := []struct {
+		name   string
+		header string
+		code   int
+	}{
+		{"Present", "secret", 204},
+		{"Missing", "", 401},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			if tt.header != "" {
+				r.Header.Set("X-Api-Key", tt.header)
+			}
+			w := httptest.NewRecorder()
+
+			mux.ServeHTTP(w, r)
+
+			if w.Code != tt.code {
+				t.Errorf("expected: [%d]; got: [%d]", tt.code, w.Code)
+			}
+		})
+	}
+}
+
+func Test_MethodOverride(t *testing.T) {
+	mux := New(WithPreRouting(MethodOverride("_method")))
+	for _, method := range []string{"GET", "POST", "PUT", "PATCH", "DELETE"} {
+		mux.Handle(method, "/items/:id", respondWith(method))
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		header string
+		form   string
+		want   string
+	}{
+		{"Header", "POST", "DELETE", "", "DELETE"},
+		{"LowerCase", "POST", "patch", "", "PATCH"},
+		{"FormField", "POST", "", "_method=PUT", "PUT"},
+		{"NoOverride", "POST", "", "", "POST"},
+		{"OnlyFromPost", "GET", "DELETE", "", "GET"},
+		{"OnlyToUnsafe", "POST", "GET", "", "POST"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/items/1", strings.NewReader(tt.form))
+			if tt.form != "" {
+				r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+			if tt.header != "" {
+				r.Header.Set(MethodOverrideHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+
+			mux.ServeHTTP(w, r)
+
+			if w.Body.String() != tt.want {
+				t.Errorf("expected: [%s]; got: [%s]", tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func Test_MiddlewareDepth(t *testing.T) {
+	var calls []string
+	var depth int
+
+	h := func(ctx context.Context, r *http.Request) error {
+		depth = MiddlewareDepth(ctx)
+		return nil
+	}
+
+	mux := New(WithMiddleware(trace(&calls, "a"), trace(&calls, "b")))
+	mux.GET("/global", h)
+	mux.GET("/route", h, trace(&calls, "c"), nil)
+	mux.Group("/group", trace(&calls, "d")).GET("/", h, trace(&calls, "e"))
+
+	bare := New()
+	bare.GET("/", h)
+
+	tests := []struct {
+		name  string
+		mux   *Mux
+		path  string
+		depth int
+	}{
+		{"Global", mux, "/global", 2},
+		{"Route", mux, "/route", 3},
+		{"Group", mux, "/group/", 4},
+		{"None", bare, "/", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			depth = -1
+			tt.mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", tt.path, nil))
+
+			if depth != tt.depth {
+				t.Errorf("expected: [%d]; got: [%d]", tt.depth, depth)
+			}
+		})
+	}
+}
@@ -11,3 +11,8 @@ import (
 // PanicHandler represents a function to recover from panics that may
 // occur during the lifecycle of the mux.
 type PanicHandler func(ctx context.Context, r *http.Request, err interface{})
+
+// PanicStackHandler represents a function to recover from panics that
+// receives the stack trace of the panicking goroutine, captured when
+// the panic is recovered.
+type PanicStackHandler func(ctx context.Context, r *http.Request, err interface{}, stack []byte)
@@ -0,0 +1,157 @@
+// Copyright 2025 Brandon Epperson
+// SPDX-License-Identifier: Apache-2.0
+
+package roxi
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// Params returns the values of all path variables and wildcards of the
+// route matched for the request, keyed by name.
+//
+// The names are read from the matched pattern, so calling Params has no
+// cost for requests that don't use it. Optional variables that are absent
+// from the request path have an empty value.
+//
+// The pattern is parsed with the path variable characters of the mux
+// serving the request with ctx, see WithParamChars. If ctx was not
+// created by a Mux, the default characters are used.
+//
+// Nil is returned if the matched route has no path variables.
+func Params(ctx context.Context, r *http.Request) map[string]string {
+	c := &defaultTreeConfig
+	if v, ok := ctx.(*writerContext); ok && v.mux != nil {
+		c = &v.mux.treeConfig
+	}
+	return c.params(r)
+}
+
+// params returns the values of the path variables of the pattern
+// matched for r, see Params.
+func (c *treeConfig) params(r *http.Request) map[string]string {
+	var params map[string]string
+
+	pattern := toBytes(r.Pattern)
+	for i := 0; i < len(pattern); i++ {
+		if p := pattern[i]; p != c.param && p != c.wildcard {
+			continue
+		}
+
+		start := i + 1
+		end := start
+		for end < len(pattern) && pattern[end] != '/' && pattern[end] != '(' && pattern[end] != '?' {
+			end++
+		}
+
+		if params == nil {
+			params = make(map[string]string)
+		}
+
+		name := toString(pattern[start:end])
+		params[name] = r.PathValue(name)
+
+		i = end
+		if end < len(pattern) && pattern[end] == '(' {
+			if i = constraintEnd(pattern, end); i < 0 {
+				break
+			}
+		}
+	}
+
+	return params
+}
+
+// clearParams clears the path values and pattern set on r by matching
+// a route.
+func (c *treeConfig) clearParams(r *http.Request) {
+	for name := range c.params(r) {
+		r.SetPathValue(name, "")
+	}
+	r.Pattern = ""
+}
+
+// Pattern returns the route matched for the request as it was registered,
+// such as "/users/:id", for use as a low cardinality label by metrics
+// and logging middleware.
+//
+// The pattern is set before any middleware of the route runs, and keeps
+// its registered casing with case insensitive routing. If r was not
+// matched by a Mux, the pattern of the request being served with ctx is
+// returned, or "" if there's none.
+func Pattern(ctx context.Context, r *http.Request) string {
+	if r != nil && r.Pattern != "" {
+		return r.Pattern
+	}
+
+	if v, ok := ctx.(*writerContext); ok && v.req != nil {
+		return v.req.Pattern
+	}
+	return ""
+}
+
+// PathDefault returns the value of the named path variable, or def
+// if it is empty or not part of the matched route.
+//
+// It's useful with optional path variables:
+//
+//	mux.GET("/articles/:page?", func(ctx context.Context, r *http.Request) error {
+//		page := roxi.PathDefault(r, "page", "1")
+//		...
+//	})
+func PathDefault(r *http.Request, name, def string) string {
+	if v := r.PathValue(name); v != "" {
+		return v
+	}
+	return def
+}
+
+// MatchedPrefix returns the part of the request path matched before the
+// wildcard of the route, such as "/v1" for "/v1/accounts/5" matching
+// the route "/v1/*path".
+//
+// If the route has no wildcard, the full path is returned. It returns ""
+// if the context does not belong to a request served by a Mux.
+func MatchedPrefix(ctx context.Context) string {
+	prefix, _ := splitWildcard(ctx)
+	return prefix
+}
+
+// Remainder returns the part of the request path matched by the wildcard
+// of the route, such as "/accounts/5" for "/v1/accounts/5" matching
+// the route "/v1/*path".
+//
+// It returns "" if the route has no wildcard, or the context does not
+// belong to a request served by a Mux.
+func Remainder(ctx context.Context) string {
+	_, rem := splitWildcard(ctx)
+	return rem
+}
+
+// splitWildcard splits the request path at the start of the
+// value captured by the wildcard of the matched route.
+func splitWildcard(ctx context.Context) (string, string) {
+	v, ok := ctx.(*writerContext)
+	if !ok || v.req == nil {
+		return "", ""
+	}
+	r := v.req
+
+	wildcard := defaultTreeConfig.wildcard
+	if v.mux != nil {
+		wildcard = v.mux.treeConfig.wildcard
+	}
+
+	i := strings.LastIndexByte(r.Pattern, wildcard)
+	if i < 0 {
+		return r.URL.Path, ""
+	}
+
+	rem := r.PathValue(r.Pattern[i+1:])
+	if !strings.HasSuffix(r.URL.Path, rem) {
+		return r.URL.Path, ""
+	}
+	return r.URL.Path[:len(r.URL.Path)-len(rem)], rem
+}
@@ -0,0 +1,209 @@
+// Copyright 2025 Brandon Epperson
+// SPDX-License-Identifier: Apache-2.0
+
+package roxi
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_Params(t *testing.T) {
+	tests := []struct {
+		name  string
+		route string
+		path  string
+		want  map[string]string
+	}{
+		{"Static", "/foo", "/foo", nil},
+		{"Params", "/users/:id/posts/:post", "/users/1/posts/2", map[string]string{"id": "1", "post": "2"}},
+		{"Wildcard", "/files/:dir/*path", "/files/docs/a/b.txt", map[string]string{"dir": "docs", "path": "/a/b.txt"}},
+		{"Constraint", `/user/:id(\d+)/:name`, "/user/42/gopher", map[string]string{"id": "42", "name": "gopher"}},
+		{"Optional", "/articles/:id?", "/articles", map[string]string{"id": ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got map[string]string
+
+			mux := New()
+			mux.GET(tt.route, func(ctx context.Context, r *http.Request) error {
+				got = Params(ctx, r)
+				return nil
+			})
+			mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", tt.path, nil))
+
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("expected: [%v]; got: [%v]", tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_Pattern(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  []func(*Mux)
+		route string
+		path  string
+		want  string
+		param string
+	}{
+		{"Static", nil, "/health", "/health", "/health", ""},
+		{"Params", nil, "/users/:id", "/users/42", "/users/:id", "42"},
+		{"Constraint", nil, `/users/:id(\d+)`, "/users/42", `/users/:id(\d+)`, "42"},
+		{"CaseInsensitive", []func(*Mux){WithCaseInsensitiveRouting()}, "/Users/:ID", "/users/42", "/Users/:ID", "42"},
+		{"CaseInsensitiveConstraint", []func(*Mux){WithCaseInsensitiveRouting()}, "/Tags/:Tag([A-Z]+)", "/tags/GO", "/Tags/:Tag([A-Z]+)", "GO"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var inMiddleware, inHandler, param string
+
+			// metrics middleware reads the pattern before the handler runs.
+			mw := func(next HandlerFunc) HandlerFunc {
+				return func(ctx context.Context, r *http.Request) error {
+					inMiddleware = Pattern(ctx, r)
+					return next(ctx, r)
+				}
+			}
+
+			mux := New(append(tt.opts, WithMiddleware(mw))...)
+			mux.GET(tt.route, func(ctx context.Context, r *http.Request) error {
+				inHandler = Pattern(ctx, nil)
+				for _, v := range Params(ctx, r) {
+					param = v
+				}
+				return nil
+			})
+
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected: [%d]; got: [%d]", http.StatusOK, w.Code)
+			}
+
+			if inMiddleware != tt.want {
+				t.Errorf("expected: [%s]; got: [%s]", tt.want, inMiddleware)
+			}
+
+			if inHandler != tt.want {
+				t.Errorf("expected: [%s]; got: [%s]", tt.want, inHandler)
+			}
+
+			if param != tt.param {
+				t.Errorf("expected: [%s]; got: [%s]", tt.param, param)
+			}
+		})
+	}
+}
+
+func Test_PathDefault(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{"Captured", "/articles/2", "2"},
+		{"Absent", "/articles", "1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got, unset string
+
+			mux := New()
+			mux.GET("/articles/:page?", func(ctx context.Context, r *http.Request) error {
+				got = PathDefault(r, "page", "1")
+				unset = PathDefault(r, "missing", "none")
+				return nil
+			})
+			mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", tt.path, nil))
+
+			if got != tt.want {
+				t.Errorf("expected: [%s]; got: [%s]", tt.want, got)
+			}
+
+			if unset != "none" {
+				t.Errorf("expected: [%s]; got: [%s]", "none", unset)
+			}
+		})
+	}
+}
+
+func Test_MatchedPrefix(t *testing.T) {
+	tests := []struct {
+		name   string
+		route  string
+		path   string
+		prefix string
+		rem    string
+	}{
+		{"Wildcard", "/v1/*path", "/v1/accounts/5", "/v1", "/accounts/5"},
+		{"WildcardEmpty", "/v1/*path", "/v1/", "/v1", "/"},
+		{"Params", "/v1/:org/*path", "/v1/acme/accounts", "/v1/acme", "/accounts"},
+		{"NoWildcard", "/v1/accounts", "/v1/accounts", "/v1/accounts", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var prefix, rem string
+
+			mux := New()
+			mux.GET(tt.route, func(ctx context.Context, r *http.Request) error {
+				prefix, rem = MatchedPrefix(ctx), Remainder(ctx)
+				return nil
+			})
+			mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", tt.path, nil))
+
+			if prefix != tt.prefix {
+				t.Errorf("expected prefix: [%s]; got: [%s]", tt.prefix, prefix)
+			}
+
+			if rem != tt.rem {
+				t.Errorf("expected remainder: [%s]; got: [%s]", tt.rem, rem)
+			}
+		})
+	}
+
+	if p := MatchedPrefix(context.Background()); p != "" {
+		t.Errorf("expected: [%s]; got: [%s]", "", p)
+	}
+}
+
+func Test_ParamsParamChars(t *testing.T) {
+	tests := []struct {
+		name  string
+		route string
+		path  string
+		want  map[string]string
+	}{
+		{"Param", "/books/urn:isbn:$isbn", "/books/urn:isbn:978", map[string]string{"isbn": "978"}},
+		{"Wildcard", "/files/$dir/~path", "/files/docs/a:b", map[string]string{"dir": "docs", "path": "/a:b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got map[string]string
+
+			mux := New(WithParamChars('$', '~'))
+			mux.GET(tt.route, func(ctx context.Context, r *http.Request) error {
+				got = Params(ctx, r)
+				return nil
+			})
+			mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", tt.path, nil))
+
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("expected: [%v]; got: [%v]", tt.want, got)
+			}
+
+			_, params, found := mux.Lookup("GET", tt.path)
+			if !found || !maps.Equal(params, tt.want) {
+				t.Errorf("expected: [%v]; got: [%v]", tt.want, params)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2025 Brandon Epperson
+// SPDX-License-Identifier: Apache-2.0
+
+package roxi
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimit returns a middleware that limits each client to perSecond
+// requests per second, allowing bursts of up to burst requests.
+//
+// Clients are identified by keyFn, such as by API key or user ID. If keyFn
+// is nil, clients are identified by the IP address of r.RemoteAddr, so
+// behind proxies RealIP should run first.
+//
+// Each client has a token bucket holding up to burst tokens, which refills
+// at perSecond tokens per second. Requests take a token, or are aborted with
+// 429 Too Many Requests and a Retry-After header if the bucket is empty.
+//
+// Buckets that have refilled are evicted while requests are served, so
+// idle clients don't hold memory.
+func RateLimit(perSecond float64, burst int, keyFn func(*http.Request) string) MiddlewareFunc {
+	if perSecond <= 0 || burst < 1 {
+		panic("invalid rate limit: " + strconv.FormatFloat(perSecond, 'g', -1, 64) +
+			" per second with a burst of " + strconv.Itoa(burst))
+	}
+
+	if keyFn == nil {
+		keyFn = remoteIP
+	}
+
+	l := newLimiter(perSecond, burst)
+
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, r *http.Request) error {
+			ok, retry := l.allow(keyFn(r), time.Now())
+			if ok {
+				return next(ctx, r)
+			}
+
+			if w := GetWriter(ctx); w != nil {
+				secs := int(math.Ceil(retry.Seconds()))
+				w.Header().Set("Retry-After", strconv.Itoa(secs))
+			}
+			return Abort(http.StatusTooManyRequests, nil)
+		}
+	}
+}
+
+// remoteIP returns the IP address of the client of r,
+// or r.RemoteAddr if it has no port.
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// limiter is a set of token buckets keyed by client.
+type limiter struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   float64
+	buckets map[string]*bucket
+
+	// refill is the time for an empty bucket to refill, after which idle
+	// buckets are evicted, as they're equivalent to a new bucket.
+	refill    time.Duration
+	lastSweep time.Time
+}
+
+// bucket holds the tokens of a client as of last.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// minSweepInterval is the minimum time between evictions of idle buckets.
+const minSweepInterval = time.Minute
+
+func newLimiter(perSecond float64, burst int) *limiter {
+	return &limiter{
+		rate:    perSecond,
+		burst:   float64(burst),
+		buckets: make(map[string]*bucket),
+		refill:  time.Duration(float64(burst) / perSecond * float64(time.Second)),
+	}
+}
+
+// allow takes a token from the bucket of key at now. If the bucket is
+// empty, it reports false and the time until a token is available.
+func (l *limiter) allow(key string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = min(l.burst, b.tokens+elapsed.Seconds()*l.rate)
+		b.last = now
+	}
+
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+
+	b.tokens--
+	return true, 0
+}
+
+// sweep evicts the buckets that have refilled by now, at most once
+// every refill time or minSweepInterval, whichever is longer.
+func (l *limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < max(l.refill, minSweepInterval) {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= l.refill {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright 2025 Brandon Epperson
+// SPDX-License-Identifier: Apache-2.0
+
+package roxi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_RateLimit(t *testing.T) {
+	mux := New(WithMiddleware(RateLimit(1, 3, nil)))
+	mux.GET("/", func(ctx context.Context, r *http.Request) error {
+		return nil
+	})
+
+	codes := make(map[int]int)
+	for range 5 {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = "192.0.2.1:1234"
+		w := httptest.NewRecorder()
+
+		mux.ServeHTTP(w, r)
+		codes[w.Code]++
+
+		if w.Code == http.StatusTooManyRequests {
+			if h := w.Header().Get("Retry-After"); h != "1" {
+				t.Errorf("expected: [%s]; got: [%s]", "1", h)
+			}
+		}
+	}
+
+	if codes[http.StatusOK] != 3 || codes[http.StatusTooManyRequests] != 2 {
+		t.Errorf("expected: [%v]; got: [%v]", map[int]int{200: 3, 429: 2}, codes)
+	}
+
+	// other clients have their own bucket.
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "192.0.2.2:1234"
+	w := httptest.NewRecorder()
+
+	mux.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected: [%d]; got: [%d]", http.StatusOK, w.Code)
+	}
+}
+
+func Test_RateLimitKey(t *testing.T) {
+	mux := New(WithMiddleware(RateLimit(1, 1, func(r *http.Request) string {
+		return r.Header.Get("X-API-Key")
+	})))
+	mux.GET("/", func(ctx context.Context, r *http.Request) error {
+		return nil
+	})
+
+	tests := []struct {
+		name string
+		key  string
+		code int
+	}{
+		{"First", "a", http.StatusOK},
+		{"Limited", "a", http.StatusTooManyRequests},
+		{"OtherKey", "b", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.Header.Set("X-API-Key", tt.key)
+			w := httptest.NewRecorder()
+
+			mux.ServeHTTP(w, r)
+
+			if w.Code != tt.code {
+				t.Errorf("expected: [%d]; got: [%d]", tt.code, w.Code)
+			}
+		})
+	}
+}
+
+func Test_Limiter(t *testing.T) {
+	l := newLimiter(2, 2)
+	start := time.Now()
+
+	tests := []struct {
+		name  string
+		at    time.Duration
+		ok    bool
+		retry time.Duration
+	}{
+		{"Burst", 0, true, 0},
+		{"BurstLast", 0, true, 0},
+		{"Empty", 0, false, 500 * time.Millisecond},
+		{"PartialRefill", 250 * time.Millisecond, false, 250 * time.Millisecond},
+		{"Refilled", 500 * time.Millisecond, true, 0},
+		{"EmptyAgain", 500 * time.Millisecond, false, 500 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, retry := l.allow("client", start.Add(tt.at))
+			if ok != tt.ok {
+				t.Errorf("expected: [%v]; got: [%v]", tt.ok, ok)
+			}
+
+			if retry != tt.retry {
+				t.Errorf("expected: [%v]; got: [%v]", tt.retry, retry)
+			}
+		})
+	}
+}
+
+func Test_LimiterEviction(t *testing.T) {
+	l := newLimiter(1, 1)
+	start := time.Now()
+
+	l.allow("idle", start)
+	l.allow("active", start.Add(minSweepInterval-500*time.Millisecond))
+
+	// the next request after the sweep interval evicts the idle bucket only.
+	l.allow("other", start.Add(minSweepInterval))
+
+	if _, ok := l.buckets["idle"]; ok {
+		t.Errorf("expected idle bucket to be evicted")
+	}
+
+	if _, ok := l.buckets["active"]; !ok {
+		t.Errorf("expected active bucket to be kept")
+	}
+}
+
+func Test_RateLimitInvalid(t *testing.T) {
+	tests := []struct {
+		name      string
+		perSecond float64
+		burst     int
+	}{
+		{"ZeroRate", 0, 1},
+		{"ZeroBurst", 1, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected panic")
+				}
+			}()
+			RateLimit(tt.perSecond, tt.burst, nil)
+		})
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2025 Brandon Epperson
+// SPDX-License-Identifier: Apache-2.0
+
+package roxi
+
+import (
+	"context"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// RealIP returns a middleware that resolves the IP address of the client
+// behind trusted proxies, such as load balancers.
+//
+// If the peer of the connection is in one of the trusted prefixes, the
+// X-Forwarded-For header is read from right to left, skipping trusted
+// addresses, and the first untrusted address is the client. If the
+// header is absent, X-Real-IP is used. Headers sent by untrusted peers
+// are ignored, so clients can't spoof their address.
+//
+// r.RemoteAddr is replaced with the resolved address, without a port,
+// and the address is stored in the context, see GetRealIP. RealIP should
+// run before middleware using the client's address, such as RateLimit.
+func RealIP(trustedProxies []netip.Prefix) MiddlewareFunc {
+	trusted := func(ip netip.Addr) bool {
+		for _, p := range trustedProxies {
+			if p.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, r *http.Request) error {
+			ip, ok := parseIP(remoteIP(r))
+			if !ok {
+				return next(ctx, r)
+			}
+
+			if trusted(ip) {
+				ip = forwardedIP(r.Header, ip, trusted)
+			}
+
+			r.RemoteAddr = ip.String()
+			return next(WithValue(ctx, realIPKey, ip), r)
+		}
+	}
+}
+
+// GetRealIP returns the client address resolved by the RealIP middleware,
+// or the zero netip.Addr if there is none.
+func GetRealIP(ctx context.Context) netip.Addr {
+	ip, _ := ctx.Value(realIPKey).(netip.Addr)
+	return ip
+}
+
+// forwardedIP returns the client address forwarded to the trusted peer,
+// or peer if the headers don't hold a valid one.
+func forwardedIP(h http.Header, peer netip.Addr, trusted func(netip.Addr) bool) netip.Addr {
+	values := h.Values("X-Forwarded-For")
+	if len(values) == 0 {
+		if ip, ok := parseIP(h.Get("X-Real-IP")); ok {
+			return ip
+		}
+		return peer
+	}
+
+	// the rightmost addresses were added by the closest proxies.
+	client := peer
+	for i := len(values) - 1; i >= 0; i-- {
+		hops := values[i]
+		for hops != "" {
+			var hop string
+			if j := strings.LastIndexByte(hops, ','); j >= 0 {
+				hops, hop = hops[:j], hops[j+1:]
+			} else {
+				hops, hop = "", hops
+			}
+
+			ip, ok := parseIP(strings.TrimSpace(hop))
+			if !ok {
+				// addresses left of an invalid one can't be trusted.
+				return client
+			}
+
+			client = ip
+			if !trusted(ip) {
+				return client
+			}
+		}
+	}
+	return client
+}
+
+// parseIP parses an IP address, unmapping IPv4-mapped IPv6 addresses.
+func parseIP(s string) (netip.Addr, bool) {
+	ip, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return ip.Unmap(), true
+}
@@ -0,0 +1,86 @@
+// Copyright 2025 Brandon Epperson
+// SPDX-License-Identifier: Apache-2.0
+
+package roxi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func Test_RealIP(t *testing.T) {
+	trusted := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("fd00::/8"),
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		xff        []string
+		xRealIP    string
+		want       string
+	}{
+		{"Direct", "203.0.113.7:5000", nil, "", "203.0.113.7"},
+		{"UntrustedSpoof", "203.0.113.7:5000", []string{"198.51.100.1"}, "198.51.100.2", "203.0.113.7"},
+		{"TrustedForwarded", "10.0.0.1:5000", []string{"198.51.100.1"}, "", "198.51.100.1"},
+		{"RightmostUntrusted", "10.0.0.1:5000", []string{"1.1.1.1, 198.51.100.1, 10.0.0.2"}, "", "198.51.100.1"},
+		{"MultipleHeaders", "10.0.0.1:5000", []string{"1.1.1.1, 198.51.100.1", "10.0.0.2"}, "", "198.51.100.1"},
+		{"AllTrusted", "10.0.0.1:5000", []string{"10.0.0.3, 10.0.0.2"}, "", "10.0.0.3"},
+		{"InvalidHop", "10.0.0.1:5000", []string{"1.1.1.1, garbage, 10.0.0.2"}, "", "10.0.0.2"},
+		{"RealIPHeader", "10.0.0.1:5000", nil, "198.51.100.2", "198.51.100.2"},
+		{"InvalidRealIP", "10.0.0.1:5000", nil, "unknown", "10.0.0.1"},
+		{"IPv6", "[fd00::1]:5000", []string{"2001:db8::1"}, "", "2001:db8::1"},
+		{"IPv4Mapped", "[::ffff:10.0.0.1]:5000", []string{"198.51.100.1"}, "", "198.51.100.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got netip.Addr
+			var remoteAddr string
+			h := RealIP(trusted)(func(ctx context.Context, r *http.Request) error {
+				got, remoteAddr = GetRealIP(ctx), r.RemoteAddr
+				return nil
+			})
+
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, v := range tt.xff {
+				r.Header.Add("X-Forwarded-For", v)
+			}
+			if tt.xRealIP != "" {
+				r.Header.Set("X-Real-IP", tt.xRealIP)
+			}
+
+			if err := h(NewTestContext(httptest.NewRecorder()), r); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got.String() != tt.want {
+				t.Errorf("expected: [%s]; got: [%s]", tt.want, got)
+			}
+			if remoteAddr != tt.want {
+				t.Errorf("expected RemoteAddr: [%s]; got: [%s]", tt.want, remoteAddr)
+			}
+		})
+	}
+}
+
+func Test_RealIPInvalidRemoteAddr(t *testing.T) {
+	h := RealIP(nil)(func(ctx context.Context, r *http.Request) error {
+		if ip := GetRealIP(ctx); ip.IsValid() {
+			t.Errorf("expected no address; got: [%s]", ip)
+		}
+		if r.RemoteAddr != "pipe" {
+			t.Errorf("expected: [pipe]; got: [%s]", r.RemoteAddr)
+		}
+		return nil
+	})
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "pipe"
+	_ = h(NewTestContext(httptest.NewRecorder()), r)
+}
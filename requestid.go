@@ -0,0 +1,90 @@
+// Copyright 2025 Brandon Epperson
+// SPDX-License-Identifier: Apache-2.0
+
+package roxi
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// RequestIDHeader is the header the request ID is read from and
+// written to by the RequestID middleware.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLen is the maximum length of an incoming request ID.
+const maxRequestIDLen = 128
+
+// RequestIDOption configures the RequestID middleware.
+type RequestIDOption func(*requestID)
+
+// WithRequestIDGenerator sets the function used to generate request IDs.
+func WithRequestIDGenerator(fn func() string) RequestIDOption {
+	return func(c *requestID) {
+		c.generate = fn
+	}
+}
+
+type requestID struct {
+	generate func() string
+}
+
+// RequestID returns a middleware that assigns an ID to each request.
+//
+// The ID is read from the X-Request-ID request header, or generated as
+// 16 random bytes in hex if it's absent or invalid. It's stored in the
+// context, see GetRequestID, and set on the X-Request-ID response header.
+//
+// Incoming IDs are only accepted if they are at most 128 printable
+// ASCII characters, so they are safe to log.
+func RequestID(opts ...RequestIDOption) MiddlewareFunc {
+	cfg := &requestID{generate: newRequestID}
+	for _, o := range opts {
+		o(cfg)
+	}
+
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, r *http.Request) error {
+			id := r.Header.Get(RequestIDHeader)
+			if !validRequestID(id) {
+				id = cfg.generate()
+			}
+
+			if w := GetWriter(ctx); w != nil {
+				w.Header().Set(RequestIDHeader, id)
+			}
+			return next(WithValue(ctx, requestIDKey, id), r)
+		}
+	}
+}
+
+// GetRequestID returns the ID assigned to the request by the RequestID
+// middleware, or "" if there is none.
+func GetRequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// newRequestID returns 16 random bytes encoded in hex.
+func newRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// validRequestID reports whether an incoming request ID is non-empty
+// and only contains a limited number of printable ASCII characters.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
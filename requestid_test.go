@@ -0,0 +1,71 @@
+// Copyright 2025 Brandon Epperson
+// SPDX-License-Identifier: Apache-2.0
+
+package roxi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_RequestID(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{"Incoming", "abc-123", "abc-123"},
+		{"Generated", "", "generated"},
+		{"Invalid", "bad id\n", "generated"},
+		{"TooLong", strings.Repeat("a", maxRequestIDLen+1), "generated"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+
+			mux := New(WithMiddleware(RequestID(WithRequestIDGenerator(func() string {
+				return "generated"
+			}))))
+			mux.GET("/", func(ctx context.Context, r *http.Request) error {
+				got = GetRequestID(ctx)
+				return nil
+			})
+
+			r := httptest.NewRequest("GET", "/", nil)
+			if tt.header != "" {
+				r.Header.Set(RequestIDHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+
+			mux.ServeHTTP(w, r)
+
+			if got != tt.want {
+				t.Errorf("expected: [%s]; got: [%s]", tt.want, got)
+			}
+
+			if h := w.Header().Get(RequestIDHeader); h != tt.want {
+				t.Errorf("expected header: [%s]; got: [%s]", tt.want, h)
+			}
+		})
+	}
+}
+
+func Test_NewRequestID(t *testing.T) {
+	a, b := newRequestID(), newRequestID()
+
+	if len(a) != 32 || !validRequestID(a) {
+		t.Errorf("invalid generated request id: [%s]", a)
+	}
+
+	if a == b {
+		t.Errorf("expected unique request ids; got: [%s] twice", a)
+	}
+
+	if id := GetRequestID(context.Background()); id != "" {
+		t.Errorf("expected: [%s]; got: [%s]", "", id)
+	}
+}
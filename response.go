@@ -4,18 +4,33 @@
 package roxi
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"html/template"
+	"io"
 	"net/http"
 	"runtime"
+	"strconv"
+	"strings"
+	"sync"
 )
 
 // Default error response handlers.
 var (
 	// NotFound is a default 404 handler.
+	//
+	// It responds with an HTML page to requests accepting text/html, such
+	// as from browsers, and with plain text otherwise.
 	NotFound = func(ctx context.Context, r *http.Request) error {
-		return respond(ctx, &errorResponse{
+		if acceptsHTML(r.Header.Get("Accept")) {
+			return Respond(ctx, HTML(http.StatusNotFound, notFoundPage))
+		}
+
+		return Respond(ctx, &errorResponse{
 			http.StatusNotFound,
 			http.StatusText(http.StatusNotFound),
 		})
@@ -23,7 +38,7 @@ var (
 
 	// MethodNotAllowed is a default 405 handler.
 	MethodNotAllowed = func(ctx context.Context, r *http.Request) error {
-		return respond(ctx, &errorResponse{
+		return Respond(ctx, &errorResponse{
 			http.StatusMethodNotAllowed,
 			http.StatusText(http.StatusMethodNotAllowed),
 		})
@@ -31,35 +46,287 @@ var (
 
 	// MethodNotAllowed is a default 500 handler.
 	InternalServerError = func(ctx context.Context, r *http.Request) error {
-		return respond(ctx, &errorResponse{
+		return Respond(ctx, &errorResponse{
 			http.StatusInternalServerError,
 			http.StatusText(http.StatusInternalServerError),
 		})
 	}
 
 	// DefaultPanicHandler is a default handler that executes when a panic is recovered.
+	//
+	// It logs the recovered value, the request details and the stack
+	// as a structured record with the mux logger, see WithLogger, then
+	// responds with 500 Internal Server Error unless the handler had
+	// already started the response.
 	DefaultPanicHandler = func(ctx context.Context, r *http.Request, err any) {
 		buf := make([]byte, 65536)
 		buf = buf[:runtime.Stack(buf, false)]
-		fmt.Printf("roxi: recovered panic %v: %s\n", err, buf)
-		GetWriter(ctx).WriteHeader(http.StatusInternalServerError)
+		logRecord(ctx, "roxi: recovered panic", panicRecord(ctx, r, err, buf)...)
+		if !responseStarted(ctx) {
+			GetWriter(ctx).WriteHeader(http.StatusInternalServerError)
+		}
 	}
 )
 
-func respond(ctx context.Context, data *errorResponse) error {
-	w := GetWriter(ctx)
+// notFoundPage is the page written by NotFound to browsers.
+const notFoundPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>404 Not Found</title>
+<style>
+body{margin:0;min-height:100vh;display:flex;align-items:center;justify-content:center;font-family:system-ui,sans-serif;color:#333;background:#fafafa}
+main{text-align:center}
+h1{margin:0;font-size:4rem;font-weight:600}
+p{margin:.5rem 0 0;color:#777}
+</style>
+</head>
+<body>
+<main>
+<h1>404</h1>
+<p>The page you requested could not be found.</p>
+</main>
+</body>
+</html>
+`
+
+// acceptsHTML reports whether an Accept header explicitly accepts
+// text/html. Wildcards such as */* don't count, so clients sending
+// them by default, like curl, get plain text.
+func acceptsHTML(header string) bool {
+	for _, mt := range acceptable(header) {
+		if strings.EqualFold(mt, "text/html") {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptable returns the values of an Accept or Accept-Encoding header,
+// such as "text/html" or "gzip", in order and without their parameters,
+// leaving out those rejected with a q-value of 0.
+func acceptable(header string) []string {
+	values := strings.Split(header, ",")
+
+	n := 0
+	for _, v := range values {
+		value, params, _ := strings.Cut(strings.TrimSpace(v), ";")
+		if quality(params) == 0 {
+			continue
+		}
+		values[n] = strings.TrimSpace(value)
+		n++
+	}
+	return values[:n]
+}
+
+// quality returns the q-value in the parameters of an element of an
+// Accept or Accept-Encoding header, 1 if there's none, or 0 if it's not
+// a valid number so the element is ignored.
+func quality(params string) float64 {
+	for _, p := range strings.Split(params, ";") {
+		k, v, _ := strings.Cut(strings.TrimSpace(p), "=")
+		if !strings.EqualFold(strings.TrimSpace(k), "q") {
+			continue
+		}
+
+		q, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil || q < 0 || q > 1 {
+			return 0
+		}
+		return q
+	}
+	return 1
+}
+
+// panicRecord returns the key value pairs describing a recovered panic.
+//
+// The request ID is taken from the RequestID middleware, or the
+// X-Request-ID header if it's not in use.
+func panicRecord(ctx context.Context, r *http.Request, err any, stack []byte) []any {
+	args := []any{
+		"error", err,
+		"method", r.Method,
+		"url", r.URL.String(),
+		"pattern", r.Pattern,
+	}
+
+	id := GetRequestID(ctx)
+	if id == "" {
+		id = r.Header.Get(RequestIDHeader)
+	}
+
+	if id != "" {
+		args = append(args, "request_id", id)
+	}
+
+	return append(args, "stack", string(stack))
+}
 
+// printRecord prints a message followed by key value pairs in the
+// format: msg key=value key2=value2.
+func printRecord(msg string, args ...any) {
+	var b strings.Builder
+	b.WriteString(msg)
+
+	for i := 0; i+1 < len(args); i += 2 {
+		fmt.Fprintf(&b, " %v=%q", args[i], fmt.Sprint(args[i+1]))
+	}
+
+	fmt.Println(b.String())
+}
+
+// Responder represents a response body written with Respond.
+type Responder interface {
+	// Response returns the encoded response body and its content type.
+	Response() ([]byte, string, error)
+}
+
+// StatusSetter is implemented by Responders that set the
+// status code of the response.
+//
+// Responders that don't implement StatusSetter respond with 200 OK.
+type StatusSetter interface {
+	StatusCode() int
+}
+
+// StreamResponder is implemented by Responders that write their body
+// directly to the response, such as large downloads or server-sent events.
+//
+// Respond uses the content type returned by Response, ignoring its body,
+// and then calls Stream with the http.ResponseWriter. If the writer
+// supports flushing, it's flushed after every write.
+//
+// Stream doesn't return the content type itself: the header must be
+// set before the first write, so it's taken from Response instead.
+type StreamResponder interface {
+	Responder
+	Stream(w io.Writer) error
+}
+
+// Respond writes the Responder to the http.ResponseWriter in the context.
+//
+// StreamResponders are streamed to the writer, and an error returned
+// by Stream is returned after the partial body has been written. As the
+// response has started, the mux logs such errors with its logger instead
+// of calling the error handler.
+//
+// If the mux was created with WithRespondFlush, the response is flushed
+// after it's written.
+//
+// If the Responder returns an error, nothing is written and the error
+// is returned so it can be handled by the mux's error handler.
+//
+// If the Responder returns an empty content type, the Content-Type header
+// is set to the mux's default, see WithDefaultContentType. Without a
+// default, or if the handler set the header itself, it's left as is and
+// net/http detects it from the body when unset.
+func Respond(ctx context.Context, data Responder) error {
+	if data == nil {
+		return errors.New("respond: data is nil")
+	}
+
+	code := http.StatusOK
+	if s, ok := data.(StatusSetter); ok {
+		code = s.StatusCode()
+	}
+	return respondContext(ctx, code, data)
+}
+
+// RespondStatus writes the Responder like Respond, with the given status
+// code instead of the one reported by a StatusSetter:
+//
+//	return roxi.RespondStatus(ctx, http.StatusCreated, user)
+func RespondStatus(ctx context.Context, code int, data Responder) error {
 	if data == nil {
 		return errors.New("respond: data is nil")
 	}
+	return respondContext(ctx, code, data)
+}
 
+// Status responds with the given status code and no body, such as
+// 202 Accepted.
+func Status(ctx context.Context, code int) error {
+	return respondContext(ctx, code, emptyResponse{code: code})
+}
+
+// respondContext writes the Responder to the writer in the context with
+// the given status code, and flushes it if configured by the mux.
+func respondContext(ctx context.Context, code int, data Responder) error {
+	w := GetWriter(ctx)
+	if err := respondType(w, code, data, defaultContentType(ctx)); err != nil {
+		return err
+	}
+
+	if v, ok := ctx.(*writerContext); ok && v.mux != nil && v.mux.flushResponses {
+		if err := Flush(ctx); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Flush sends the response written so far to the client, such as from
+// streaming handlers that need events delivered as they're written.
+//
+// The returned error wraps http.ErrNotSupported if the writer in the
+// context can't be flushed, or there's no writer.
+func Flush(ctx context.Context) error {
+	w := GetWriter(ctx)
+	if w == nil {
+		return fmt.Errorf("flush: no writer in context: %w", http.ErrNotSupported)
+	}
+	return http.NewResponseController(w).Flush()
+}
+
+// respond writes the Responder to w with the given status code.
+func respond(w http.ResponseWriter, code int, data Responder) error {
+	return respondType(w, code, data, "")
+}
+
+// respondType writes the Responder like respond, setting the Content-Type
+// header to defaultType if neither the Responder nor the handler set one
+// for the body.
+func respondType(w http.ResponseWriter, code int, data Responder, defaultType string) error {
 	v, ct, err := data.Response()
 	if err != nil {
 		return err
 	}
+	return writeResponse(w, code, data, v, ct, defaultType)
+}
+
+// writeResponse writes the header and the body v returned by the
+// Responder's Response method with the content type ct, see respondType.
+func writeResponse(w http.ResponseWriter, code int, data Responder, v []byte, ct, defaultType string) error {
+	_, stream := data.(StreamResponder)
+	if ct == "" && defaultType != "" && (len(v) != 0 || stream) && w.Header().Get("Content-Type") == "" {
+		ct = defaultType
+	}
+
+	if hr, ok := data.(headerResponse); ok {
+		for k, vs := range hr.headers() {
+			w.Header()[k] = append([]string(nil), vs...)
+		}
+	}
 
-	w.Header().Set("Content-Type", ct)
-	w.WriteHeader(data.StatusCode())
+	// leave an empty content type unset so net/http sniffs it.
+	if ct != "" {
+		w.Header().Set("Content-Type", ct)
+	}
+	w.WriteHeader(code)
+
+	if stream {
+		if err := data.(StreamResponder).Stream(&flushWriter{w, http.NewResponseController(w)}); err != nil {
+			return &streamError{err}
+		}
+		return nil
+	}
+
+	// bodiless statuses such as 204 reject any write.
+	if len(v) == 0 {
+		return nil
+	}
 
 	if _, err := w.Write(v); err != nil {
 		return err
@@ -68,9 +335,228 @@ func respond(ctx context.Context, data *errorResponse) error {
 	return nil
 }
 
+// streamError is an error returned by a StreamResponder after the
+// response has been started.
+type streamError struct {
+	err error
+}
+
+func (e *streamError) Error() string { return e.err.Error() }
+
+func (e *streamError) Unwrap() error { return e.err }
+
+// streamFailed reports whether err was returned by a StreamResponder
+// after the response had started, logging it if so. Such errors can't
+// be handled by writing another response.
+func streamFailed(ctx context.Context, r *http.Request, err error) bool {
+	var se *streamError
+	if !errors.As(err, &se) {
+		return false
+	}
+
+	logRecord(ctx, "roxi: stream error", "error", se.err, "method", r.Method, "url", r.URL.String())
+	return true
+}
+
+// JSON returns a Responder that encodes v with encoding/json and responds
+// with the given status code.
+func JSON(code int, v any) Responder {
+	return jsonResponse{code, v}
+}
+
+// Text returns a Responder that responds with s as text/plain and the
+// given status code.
+func Text(code int, s string) Responder {
+	return stringResponse{code, s, "text/plain; charset=utf-8"}
+}
+
+// HTML returns a Responder that responds with s as text/html and the
+// given status code. The string is written as is, see RenderHTML for
+// executing templates.
+func HTML(code int, s string) Responder {
+	return stringResponse{code, s, "text/html; charset=utf-8"}
+}
+
+// AbortError is an error that stops the handling of a request and is
+// rendered as a response by the mux, instead of calling the error handler.
+//
+// It's typically returned by middleware with Abort:
+//
+//	return roxi.Abort(http.StatusUnauthorized, roxi.JSON(http.StatusUnauthorized, msg))
+type AbortError struct {
+	// Code is the status code of the response.
+	Code int
+
+	// Body is the response body. If nil, the status text of Code
+	// is written as plain text.
+	Body Responder
+}
+
+// Abort returns an *AbortError responding with the status code and body.
+func Abort(code int, body Responder) error {
+	return &AbortError{Code: code, Body: body}
+}
+
+// Error implements the error interface.
+func (e *AbortError) Error() string {
+	return "abort: " + strconv.Itoa(e.Code) + " " + http.StatusText(e.Code)
+}
+
+// defaultContentType returns the default content type of the mux
+// serving ctx, or "" if it's not served by a Mux.
+func defaultContentType(ctx context.Context) string {
+	if v, ok := ctx.(*writerContext); ok && v.mux != nil {
+		return v.mux.defaultContentType
+	}
+	return ""
+}
+
+// writeAbort writes the response of an *AbortError wrapped in err, with
+// the content type defaulting to defaultType as for Respond.
+//
+// It reports false if err is not an *AbortError or its body fails to
+// render, before anything is written. Once the header is written, the
+// response is handled even if writing the body fails.
+func writeAbort(w http.ResponseWriter, err error, defaultType string) bool {
+	var ae *AbortError
+	if !errors.As(err, &ae) {
+		return false
+	}
+
+	body := ae.Body
+	if body == nil {
+		body = &errorResponse{ae.Code, http.StatusText(ae.Code)}
+	}
+
+	v, ct, err := body.Response()
+	if err != nil {
+		return false
+	}
+
+	_ = writeResponse(w, ae.Code, body, v, ct, defaultType)
+	return true
+}
+
+// gzipPool pools gzip writers for StreamJSON.
+var gzipPool = sync.Pool{
+	New: func() any {
+		return gzip.NewWriter(io.Discard)
+	},
+}
+
+// StreamJSON encodes v as JSON and writes it to the response with 200 OK.
+//
+// If the request accepts gzip, the encoding is compressed as it's written,
+// so only the uncompressed JSON is held in memory, as with json.Encoder,
+// and never the compressed body.
+//
+// An encoding error is returned before anything is written, so it can be
+// handled by the mux's error handler. Once the response has started, a
+// write error is logged with the mux logger instead, and the compressed
+// body is left unterminated so the client can't mistake it for a
+// complete response.
+func StreamJSON(ctx context.Context, r *http.Request, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("stream json: %w", err)
+	}
+	b = append(b, '\n')
+
+	w := GetWriter(ctx)
+
+	h := w.Header()
+	h.Set("Content-Type", "application/json; charset=utf-8")
+	h.Add("Vary", "Accept-Encoding")
+
+	if acceptedEncoding(r.Header.Get("Accept-Encoding")) != "gzip" {
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(b); err != nil {
+			logRecord(ctx, "roxi: stream json", "error", err, "method", r.Method, "url", r.URL.String())
+		}
+		return nil
+	}
+
+	h.Set("Content-Encoding", "gzip")
+	h.Del("Content-Length")
+	w.WriteHeader(http.StatusOK)
+
+	zw, _ := gzipPool.Get().(*gzip.Writer)
+	zw.Reset(w)
+	defer func() {
+		zw.Reset(io.Discard)
+		gzipPool.Put(zw)
+	}()
+
+	if _, err = zw.Write(b); err == nil {
+		err = zw.Close()
+	}
+	if err != nil {
+		logRecord(ctx, "roxi: stream json", "error", err, "method", r.Method, "url", r.URL.String())
+	}
+	return nil
+}
+
+// NoContent is a Responder that responds with 204 No Content.
+var NoContent Responder = emptyResponse{code: http.StatusNoContent}
+
+// NoContentWith returns a Responder that responds with 204 No Content
+// and sets the given headers, such as Location, before writing the status.
+func NoContentWith(headers http.Header) Responder {
+	return emptyResponse{code: http.StatusNoContent, header: headers.Clone()}
+}
+
+// RenderHTML executes the named template with data and responds with
+// the result as text/html with 200 OK.
+//
+// The template is fully executed before anything is written, so a template
+// error is returned without writing a partial page.
+func RenderHTML(ctx context.Context, tmpl *template.Template, name string, data any) error {
+	return Respond(ctx, htmlResponse{tmpl, name, data})
+}
+
 // ----------------------------------------------------------------------
 // helper types
 
+// flushWriter flushes the response after every write, if supported.
+type flushWriter struct {
+	w  io.Writer
+	rc *http.ResponseController
+}
+
+func (f *flushWriter) Write(b []byte) (int, error) {
+	n, err := f.w.Write(b)
+	if err != nil {
+		return n, err
+	}
+
+	if err := f.rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return n, err
+	}
+	return n, nil
+}
+
+// headerResponse is implemented by Responders that set response headers.
+type headerResponse interface {
+	headers() http.Header
+}
+
+type emptyResponse struct {
+	code   int
+	header http.Header
+}
+
+func (r emptyResponse) Response() ([]byte, string, error) {
+	return nil, "", nil
+}
+
+func (r emptyResponse) StatusCode() int {
+	return r.code
+}
+
+func (r emptyResponse) headers() http.Header {
+	return r.header
+}
+
 type errorResponse struct {
 	code    int
 	message string
@@ -83,3 +569,48 @@ func (r errorResponse) Response() ([]byte, string, error) {
 func (r errorResponse) StatusCode() int {
 	return r.code
 }
+
+type stringResponse struct {
+	code        int
+	body        string
+	contentType string
+}
+
+func (r stringResponse) Response() ([]byte, string, error) {
+	return toBytes(r.body), r.contentType, nil
+}
+
+func (r stringResponse) StatusCode() int {
+	return r.code
+}
+
+type jsonResponse struct {
+	code  int
+	value any
+}
+
+func (r jsonResponse) Response() ([]byte, string, error) {
+	b, err := json.Marshal(r.value)
+	if err != nil {
+		return nil, "", fmt.Errorf("json response: %w", err)
+	}
+	return b, "application/json; charset=utf-8", nil
+}
+
+func (r jsonResponse) StatusCode() int {
+	return r.code
+}
+
+type htmlResponse struct {
+	tmpl *template.Template
+	name string
+	data any
+}
+
+func (r htmlResponse) Response() ([]byte, string, error) {
+	var buf bytes.Buffer
+	if err := r.tmpl.ExecuteTemplate(&buf, r.name, r.data); err != nil {
+		return nil, "", fmt.Errorf("render html: %w", err)
+	}
+	return buf.Bytes(), "text/html; charset=utf-8", nil
+}
@@ -0,0 +1,661 @@
+// Copyright 2025 Brandon Epperson
+// SPDX-License-Identifier: Apache-2.0
+
+package roxi
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// textResponse is a Responder without a status code.
+type textResponse string
+
+func (r textResponse) Response() ([]byte, string, error) {
+	return []byte(r), "text/plain", nil
+}
+
+func Test_Respond(t *testing.T) {
+	tests := []struct {
+		name string
+		data Responder
+		code int
+		ct   string
+		body string
+	}{
+		{"DefaultStatus", textResponse("hello"), 200, "text/plain", "hello"},
+		{"StatusSetter", &errorResponse{404, "gone"}, 404, "text/plain", "gone"},
+		{"JSON", JSON(201, map[string]int{"id": 1}), 201, "application/json; charset=utf-8", `{"id":1}`},
+		{"Text", Text(202, "queued"), 202, "text/plain; charset=utf-8", "queued"},
+		{"HTML", HTML(200, "<h1>Home</h1>"), 200, "text/html; charset=utf-8", "<h1>Home</h1>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			ctx := NewTestContext(w)
+
+			if err := Respond(ctx, tt.data); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if w.Code != tt.code {
+				t.Errorf("expected: [%d]; got: [%d]", tt.code, w.Code)
+			}
+
+			if ct := w.Header().Get("Content-Type"); ct != tt.ct {
+				t.Errorf("expected: [%s]; got: [%s]", tt.ct, ct)
+			}
+
+			if w.Body.String() != tt.body {
+				t.Errorf("expected: [%s]; got: [%s]", tt.body, w.Body.String())
+			}
+		})
+	}
+}
+
+func Test_RespondStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		data Responder
+		code int
+		body string
+	}{
+		{"NoStatusSetter", textResponse("created"), 201, "created"},
+		{"OverridesStatusSetter", JSON(200, map[string]int{"id": 1}), 202, `{"id":1}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+
+			if err := RespondStatus(NewTestContext(w), tt.code, tt.data); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if w.Code != tt.code {
+				t.Errorf("expected: [%d]; got: [%d]", tt.code, w.Code)
+			}
+
+			if w.Body.String() != tt.body {
+				t.Errorf("expected: [%s]; got: [%s]", tt.body, w.Body.String())
+			}
+		})
+	}
+
+	if err := RespondStatus(NewTestContext(httptest.NewRecorder()), 201, nil); err == nil {
+		t.Errorf("expected error for nil data")
+	}
+}
+
+func Test_Status(t *testing.T) {
+	w := httptest.NewRecorder()
+
+	if err := Status(NewTestContext(w), http.StatusAccepted); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if w.Code != http.StatusAccepted {
+		t.Errorf("expected: [%d]; got: [%d]", http.StatusAccepted, w.Code)
+	}
+
+	if ct := w.Header().Get("Content-Type"); ct != "" {
+		t.Errorf("expected: []; got: [%s]", ct)
+	}
+
+	if w.Body.Len() != 0 {
+		t.Errorf("expected empty body; got: [%s]", w.Body.String())
+	}
+}
+
+func Test_RespondJSONError(t *testing.T) {
+	mux := New()
+	mux.GET("/json", func(ctx context.Context, r *http.Request) error {
+		return Respond(ctx, JSON(200, make(chan int)))
+	})
+
+	r, _ := http.NewRequest("GET", "/json", nil)
+	w := httptest.NewRecorder()
+
+	mux.ServeHTTP(w, r)
+	if w.Code != 500 {
+		t.Errorf("expected: [%d]; got: [%d]", 500, w.Code)
+	}
+}
+
+// sniffResponse is a Responder without a content type.
+type sniffResponse string
+
+func (r sniffResponse) Response() ([]byte, string, error) {
+	return []byte(r), "", nil
+}
+
+func Test_RespondEmptyContentType(t *testing.T) {
+	mux := New()
+	mux.GET("/html", func(ctx context.Context, r *http.Request) error {
+		return Respond(ctx, sniffResponse("<!DOCTYPE html><html><body>hi</body></html>"))
+	})
+	mux.GET("/text", func(ctx context.Context, r *http.Request) error {
+		return Respond(ctx, sniffResponse("hello"))
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	tests := []struct {
+		name string
+		path string
+		ct   string
+	}{
+		{"HTML", "/html", "text/html; charset=utf-8"},
+		{"Text", "/text", "text/plain; charset=utf-8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := http.Get(srv.URL + tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res.Body.Close()
+
+			if ct := res.Header.Get("Content-Type"); ct != tt.ct {
+				t.Errorf("expected: [%s]; got: [%s]", tt.ct, ct)
+			}
+		})
+	}
+}
+
+func Test_Abort(t *testing.T) {
+	auth := func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, r *http.Request) error {
+			switch r.Header.Get("Authorization") {
+			case "":
+				return Abort(http.StatusUnauthorized, JSON(http.StatusOK, map[string]string{"error": "unauthorized"}))
+			case "wrapped":
+				return fmt.Errorf("auth: %w", Abort(http.StatusForbidden, nil))
+			case "invalid":
+				return Abort(http.StatusUnauthorized, JSON(http.StatusOK, make(chan int)))
+			case "stream":
+				return Abort(http.StatusBadRequest, csvStream{rows: []string{"a,b", "c,d"}, failAfter: 1})
+			}
+			return next(ctx, r)
+		}
+	}
+
+	ok := func(ctx context.Context, r *http.Request) error {
+		return Respond(ctx, textResponse("ok"))
+	}
+
+	mux := New()
+	mux.GET("/", ok, auth)
+
+	tests := []struct {
+		name   string
+		header string
+		code   int
+		body   string
+	}{
+		{"Authorized", "token", 200, "ok"},
+		{"Abort", "", 401, `{"error":"unauthorized"}`},
+		{"WrappedNilBody", "wrapped", 403, "Forbidden"},
+		{"InvalidBody", "invalid", 500, "Internal Server Error"},
+		{"FailedBody", "stream", 400, "a,b\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+
+			for name, h := range map[string]http.Handler{"Mux": mux, "HandlerFunc": HandlerFunc(auth(ok))} {
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, r)
+
+				if w.Code != tt.code {
+					t.Errorf("%s expected: [%d]; got: [%d]", name, tt.code, w.Code)
+				}
+
+				if name == "Mux" && w.Body.String() != tt.body {
+					t.Errorf("%s expected: [%s]; got: [%s]", name, tt.body, w.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func Test_DefaultContentType(t *testing.T) {
+	mux := New(WithDefaultContentType("application/json"))
+	mux.GET("/empty", func(ctx context.Context, r *http.Request) error {
+		return Respond(ctx, sniffResponse(`{"name":"gopher"}`))
+	})
+	mux.GET("/typed", func(ctx context.Context, r *http.Request) error {
+		return Respond(ctx, textResponse("hello"))
+	})
+	mux.GET("/header", func(ctx context.Context, r *http.Request) error {
+		GetWriter(ctx).Header().Set("Content-Type", "text/csv")
+		return Respond(ctx, sniffResponse("a,b"))
+	})
+	mux.GET("/status", func(ctx context.Context, r *http.Request) error {
+		return Status(ctx, http.StatusAccepted)
+	})
+	mux.GET("/abort", func(ctx context.Context, r *http.Request) error {
+		return Abort(http.StatusBadRequest, sniffResponse(`{"error":"bad"}`))
+	})
+	mux.GET("/abort-typed", func(ctx context.Context, r *http.Request) error {
+		return Abort(http.StatusBadRequest, textResponse("bad"))
+	})
+	mux.GET("/abort-middleware", func(ctx context.Context, r *http.Request) error {
+		return nil
+	}, func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, r *http.Request) error {
+			return Abort(http.StatusUnauthorized, sniffResponse(`{"error":"unauthorized"}`))
+		}
+	})
+
+	tests := []struct {
+		name string
+		path string
+		ct   string
+	}{
+		{"Default", "/empty", "application/json"},
+		{"Responder", "/typed", "text/plain"},
+		{"Handler", "/header", "text/csv"},
+		{"NoBody", "/status", ""},
+		{"Abort", "/abort", "application/json"},
+		{"AbortResponder", "/abort-typed", "text/plain"},
+		{"AbortMiddleware", "/abort-middleware", "application/json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+
+			if ct := w.Header().Get("Content-Type"); ct != tt.ct {
+				t.Errorf("expected: [%s]; got: [%s]", tt.ct, ct)
+			}
+		})
+	}
+}
+
+// csvStream is a StreamResponder writing rows, failing after
+// failAfter rows if set.
+type csvStream struct {
+	rows      []string
+	failAfter int
+}
+
+func (s csvStream) Response() ([]byte, string, error) {
+	return nil, "text/csv", nil
+}
+
+func (s csvStream) StatusCode() int {
+	return http.StatusAccepted
+}
+
+func (s csvStream) Stream(w io.Writer) error {
+	for i, row := range s.rows {
+		if s.failAfter > 0 && i == s.failAfter {
+			return errors.New("stream failed")
+		}
+
+		if _, err := io.WriteString(w, row+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func Test_RespondStream(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream csvStream
+		body   string
+		err    bool
+	}{
+		{"Stream", csvStream{rows: []string{"a,b", "c,d"}}, "a,b\nc,d\n", false},
+		{"Error", csvStream{rows: []string{"a,b", "c,d"}, failAfter: 1}, "a,b\n", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			ctx := NewTestContext(w)
+
+			if err := Respond(ctx, tt.stream); (err != nil) != tt.err {
+				t.Errorf("unexpected error result: %v", err)
+			}
+
+			if w.Code != http.StatusAccepted {
+				t.Errorf("expected: [%d]; got: [%d]", http.StatusAccepted, w.Code)
+			}
+
+			if ct := w.Header().Get("Content-Type"); ct != "text/csv" {
+				t.Errorf("expected: [%s]; got: [%s]", "text/csv", ct)
+			}
+
+			if w.Body.String() != tt.body {
+				t.Errorf("expected: [%s]; got: [%s]", tt.body, w.Body.String())
+			}
+
+			if !w.Flushed {
+				t.Error("expected response to be flushed")
+			}
+		})
+	}
+}
+
+func Test_RespondStreamError(t *testing.T) {
+	var msgs []string
+	logger := func(msg string, args ...any) {
+		msgs = append(msgs, msg)
+	}
+
+	var handled bool
+	mux := New(WithLogger(logger), WithErrorHandler(HandlerFunc(func(ctx context.Context, r *http.Request) error {
+		handled = true
+		return InternalServerError(ctx, r)
+	})))
+	mux.GET("/", func(ctx context.Context, r *http.Request) error {
+		return Respond(ctx, csvStream{rows: []string{"a,b", "c,d"}, failAfter: 1})
+	})
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+	if handled {
+		t.Error("expected error handler to be skipped after the response started")
+	}
+
+	if w.Code != http.StatusAccepted {
+		t.Errorf("expected: [%d]; got: [%d]", http.StatusAccepted, w.Code)
+	}
+
+	if w.Body.String() != "a,b\n" {
+		t.Errorf("expected: [%s]; got: [%s]", "a,b\n", w.Body.String())
+	}
+
+	if len(msgs) != 1 || msgs[0] != "roxi: stream error" {
+		t.Errorf("expected: [%s]; got: [%v]", "roxi: stream error", msgs)
+	}
+}
+
+func Test_RespondFlush(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []func(*Mux)
+		flushed bool
+	}{
+		{"Flush", []func(*Mux){WithRespondFlush()}, true},
+		{"Default", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := New(tt.opts...)
+			mux.GET("/", func(ctx context.Context, r *http.Request) error {
+				return Respond(ctx, textResponse("progress"))
+			})
+
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+			if w.Flushed != tt.flushed {
+				t.Errorf("expected: [%v]; got: [%v]", tt.flushed, w.Flushed)
+			}
+
+			if w.Body.String() != "progress" {
+				t.Errorf("expected: [%s]; got: [%s]", "progress", w.Body.String())
+			}
+		})
+	}
+}
+
+func Test_Flush(t *testing.T) {
+	tests := []struct {
+		name      string
+		w         http.ResponseWriter
+		supported bool
+	}{
+		{"Flusher", httptest.NewRecorder(), true},
+		{"NotFlusher", &discardWriter{header: make(http.Header)}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+
+			mux := New()
+			mux.GET("/events", func(ctx context.Context, r *http.Request) error {
+				_, _ = GetWriter(ctx).Write([]byte("data: 1\n\n"))
+				err = Flush(ctx)
+				return nil
+			})
+
+			mux.ServeHTTP(tt.w, httptest.NewRequest("GET", "/events", nil))
+
+			if supported := !errors.Is(err, http.ErrNotSupported); supported != tt.supported {
+				t.Errorf("expected: [%v]; got: [%v]", tt.supported, supported)
+			}
+
+			if rec, ok := tt.w.(*httptest.ResponseRecorder); ok && !rec.Flushed {
+				t.Errorf("expected response to be flushed")
+			}
+		})
+	}
+
+	t.Run("NoWriter", func(t *testing.T) {
+		if err := Flush(context.Background()); !errors.Is(err, http.ErrNotSupported) {
+			t.Errorf("expected: [%v]; got: [%v]", http.ErrNotSupported, err)
+		}
+	})
+}
+
+func Test_RenderHTML(t *testing.T) {
+	tmpl := template.Must(template.New("page").Parse(
+		`<h1>{{.Title}}</h1>{{define "broken"}}<p>partial</p>{{.Missing.Field}}{{end}}`,
+	))
+
+	tests := []struct {
+		name string
+		tmpl string
+		ct   string
+		body string
+		err  bool
+	}{
+		{"Render", "page", "text/html; charset=utf-8", "<h1>&lt;Home&gt;</h1>", false},
+		{"TemplateError", "broken", "", "", true},
+		{"MissingTemplate", "missing", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			ctx := NewTestContext(w)
+
+			data := struct{ Title, Missing any }{Title: "<Home>"}
+			if err := RenderHTML(ctx, tmpl, tt.tmpl, data); (err != nil) != tt.err {
+				t.Errorf("unexpected error result: %v", err)
+			}
+
+			if ct := w.Header().Get("Content-Type"); ct != tt.ct {
+				t.Errorf("expected: [%s]; got: [%s]", tt.ct, ct)
+			}
+
+			if w.Body.String() != tt.body {
+				t.Errorf("expected: [%s]; got: [%s]", tt.body, w.Body.String())
+			}
+		})
+	}
+}
+
+func Test_NoContent(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    Responder
+		headers http.Header
+	}{
+		{"NoContent", NoContent, http.Header{}},
+		{"WithHeaders", NoContentWith(http.Header{
+			"Location":          {"/items/1"},
+			"X-Ratelimit-Limit": {"100"},
+		}), http.Header{"Location": {"/items/1"}, "X-Ratelimit-Limit": {"100"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			ctx := NewTestContext(w)
+
+			if err := Respond(ctx, tt.data); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if w.Code != http.StatusNoContent {
+				t.Errorf("expected: [%d]; got: [%d]", http.StatusNoContent, w.Code)
+			}
+
+			for k := range tt.headers {
+				if got := w.Header().Get(k); got != tt.headers.Get(k) {
+					t.Errorf("expected %s: [%s]; got: [%s]", k, tt.headers.Get(k), got)
+				}
+			}
+
+			if w.Body.Len() != 0 {
+				t.Errorf("expected empty body; got: [%s]", w.Body.String())
+			}
+		})
+	}
+}
+
+func Test_StreamJSON(t *testing.T) {
+	items := make([]map[string]int, 1000)
+	for i := range items {
+		items[i] = map[string]int{"id": i}
+	}
+
+	tests := []struct {
+		name     string
+		accept   string
+		encoding string
+	}{
+		{"Gzip", "gzip", "gzip"},
+		{"Plain", "", ""},
+		{"DeflateOnly", "deflate", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept-Encoding", tt.accept)
+			}
+
+			w := httptest.NewRecorder()
+			ctx := NewTestContext(w)
+
+			if err := StreamJSON(ctx, r, items); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if ct := w.Header().Get("Content-Type"); ct != "application/json; charset=utf-8" {
+				t.Errorf("expected: [%s]; got: [%s]", "application/json; charset=utf-8", ct)
+			}
+
+			if enc := w.Header().Get("Content-Encoding"); enc != tt.encoding {
+				t.Errorf("expected: [%s]; got: [%s]", tt.encoding, enc)
+			}
+
+			var body io.Reader = w.Body
+			if tt.encoding == "gzip" {
+				zr, err := gzip.NewReader(w.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				body = zr
+			}
+
+			var got []map[string]int
+			if err := json.NewDecoder(body).Decode(&got); err != nil {
+				t.Fatalf("invalid json: %v", err)
+			}
+
+			if len(got) != len(items) || got[999]["id"] != 999 {
+				t.Errorf("expected [%d] items; got: [%d]", len(items), len(got))
+			}
+		})
+	}
+}
+
+// failingWriter is a http.ResponseWriter whose body writes fail,
+// such as after the client went away.
+type failingWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (w failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("broken pipe")
+}
+
+func Test_StreamJSONError(t *testing.T) {
+	var msgs []string
+	logger := func(msg string, args ...any) {
+		msgs = append(msgs, msg)
+	}
+
+	mux := New(WithLogger(logger))
+	mux.GET("/unsupported", func(ctx context.Context, r *http.Request) error {
+		return StreamJSON(ctx, r, make(chan int))
+	})
+	mux.GET("/disconnected", func(ctx context.Context, r *http.Request) error {
+		return StreamJSON(ctx, r, []int{1, 2, 3})
+	})
+
+	tests := []struct {
+		name   string
+		path   string
+		accept string
+		code   int
+		logged int
+	}{
+		{"Unsupported", "/unsupported", "gzip", http.StatusInternalServerError, 0},
+		{"Disconnected", "/disconnected", "", http.StatusOK, 1},
+		{"DisconnectedGzip", "/disconnected", "gzip", http.StatusOK, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msgs = nil
+
+			r := httptest.NewRequest("GET", tt.path, nil)
+			r.Header.Set("Accept-Encoding", tt.accept)
+
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(failingWriter{w}, r)
+
+			if w.Code != tt.code {
+				t.Errorf("expected: [%d]; got: [%d]", tt.code, w.Code)
+			}
+
+			// the error handler must not write a second response.
+			if enc := w.Header().Get("Content-Encoding"); tt.code != http.StatusOK && enc != "" {
+				t.Errorf("expected no Content-Encoding; got: [%s]", enc)
+			}
+
+			if len(msgs) != tt.logged {
+				t.Errorf("expected: [%d] records; got: [%v]", tt.logged, msgs)
+			}
+		})
+	}
+}
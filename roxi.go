@@ -10,11 +10,18 @@ package roxi
 
 import (
 	"context"
-	"errors"
 	"fmt"
+	"io"
+	"maps"
 	"net/http"
+	"net/url"
+	"runtime/debug"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // pool for writerContext.
@@ -28,6 +35,13 @@ func getContext() *writerContext {
 	ctx, _ := ctxPool.Get().(*writerContext)
 	ctx.Context = nil
 	ctx.value = nil
+	ctx.mux = nil
+	ctx.req = nil
+	ctx.depth = 0
+	ctx.sw = statsWriter{}
+	ctx.detached = false
+	clear(ctx.values)
+	ctx.values = ctx.values[:0]
 	return ctx
 }
 
@@ -53,6 +67,10 @@ type HandlerFunc func(ctx context.Context, r *http.Request) error
 //
 // If this behavior is undesired, the error must be handled and set to nil
 // prior to the function's return.
+//
+// Errors wrapping an *AbortError are written as its response instead, and
+// errors returned by a StreamResponder after it started the response are
+// logged.
 func (f HandlerFunc) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// check if we're passed a *writerContext (likely: Middleware)
 	rCtx := r.Context()
@@ -61,13 +79,14 @@ func (f HandlerFunc) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		ctx.value = w
 	} else {
 		// setup context otherwise.
-		ctx = ctxPool.Get().(*writerContext)
+		ctx = getContext()
 		ctx.Context = r.Context()
 		ctx.value = w
-		defer ctxPool.Put(ctx)
+		ctx.req = r
+		defer putContext(ctx)
 	}
 
-	if err := f(ctx, r); err != nil {
+	if err := f(ctx, r); err != nil && !streamFailed(ctx, r, err) && !writeAbort(w, err, defaultContentType(ctx)) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
@@ -75,14 +94,45 @@ func (f HandlerFunc) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 // Mux represents an http.Handler for registering HandlerFuncs to handle
 // HTTP requests.
 type Mux struct {
-	trees map[string]*node
+	// registered routes, swapped by Reset and, with dynamic routes,
+	// replaced by a modified copy on each registration.
+	routes        atomic.Pointer[routeTable]
+	routesMu      sync.Mutex
+	dynamicRoutes bool
 
 	// Routing
 	routeCaseInsensitive bool
+	caseInsensitiveServe bool
+	treeConfig           treeConfig
+	preRouting           []func(*http.Request)
 
 	// Redirects
-	redirectTrailingSlash bool
-	redirectCleanPath     bool
+	trailingSlash     TrailingSlashPolicy
+	redirectCleanPath bool
+	strictSlash       bool
+	serveStrictSlash  bool
+
+	// Request bodies
+	drainBody bool
+
+	// HEAD requests
+	autoHEAD bool
+
+	// Responses
+	flushResponses     bool
+	defaultContentType string
+
+	// Route matching latency, nil if disabled.
+	matchTiming *matchHistogram
+
+	// SPA fallback
+	spa *spaFallback
+
+	// Legacy handler for unmatched requests
+	fallthroughHandler http.Handler
+
+	// Host routing
+	strictHost bool
 
 	// OPTIONS hander
 	optionsHandler http.Handler
@@ -91,9 +141,20 @@ type Mux struct {
 	methodNotAllowed http.Handler
 	notFound         http.Handler
 	errHandler       http.Handler
+	scopedErrHandler []scopedHandler
+	errMapper        func(err error) Responder
 
 	// Panics
-	panicHandler PanicHandler
+	panicHandler      PanicHandler
+	panicStackHandler PanicStackHandler
+
+	// Middleware
+	middleware    []MiddlewareFunc
+	preMiddleware []MiddlewareFunc
+	preHandler    HandlerFunc
+
+	// Logging
+	logger func(msg string, args ...any)
 }
 
 // New returns a new initialized Mux.
@@ -101,19 +162,124 @@ type Mux struct {
 // No options are configured other than the default error handlers and panic handler.
 func New(opts ...func(*Mux)) *Mux {
 	m := &Mux{
-		trees:            make(map[string]*node),
 		methodNotAllowed: HandlerFunc(MethodNotAllowed),
 		notFound:         HandlerFunc(NotFound),
 		errHandler:       HandlerFunc(InternalServerError),
 		panicHandler:     DefaultPanicHandler,
+		treeConfig:       defaultTreeConfig,
 	}
 
+	m.routes.Store(newRouteTable())
+
 	for _, o := range opts {
 		o(m)
 	}
+	m.preHandler = m.buildPreHandler()
 	return m
 }
 
+// routeTable holds the routes registered with a Mux.
+type routeTable struct {
+	trees map[string]*node
+	hosts map[string]*Mux
+
+	// methods holds the routes of every method, to look up
+	// the methods allowed for a path in a single search.
+	methods *node
+}
+
+func newRouteTable() *routeTable {
+	return &routeTable{trees: make(map[string]*node)}
+}
+
+// clone returns a deep copy of the trees in rt. Host muxes are shared.
+func (rt *routeTable) clone() *routeTable {
+	c := &routeTable{trees: make(map[string]*node, len(rt.trees))}
+	for method, root := range rt.trees {
+		c.trees[method] = root.clone()
+	}
+
+	if rt.methods != nil {
+		c.methods = rt.methods.clone()
+	}
+
+	if rt.hosts != nil {
+		c.hosts = maps.Clone(rt.hosts)
+	}
+	return c
+}
+
+// updateRoutes calls fn to modify the routes of m.
+//
+// With dynamic routes, fn modifies a copy of the routes, which replaces
+// them once fn returns, so requests being served never observe a partial
+// update. If fn panics or returns an error, the routes are left unchanged
+// and the error is returned.
+func (m *Mux) updateRoutes(fn func(rt *routeTable) error) error {
+	if !m.dynamicRoutes {
+		return fn(m.routes.Load())
+	}
+
+	m.routesMu.Lock()
+	defer m.routesMu.Unlock()
+
+	rt := m.routes.Load().clone()
+	if err := fn(rt); err != nil {
+		return err
+	}
+	m.routes.Store(rt)
+	return nil
+}
+
+// clone returns a Mux with the configuration of m and no routes.
+func (m *Mux) clone() *Mux {
+	c := &Mux{
+		dynamicRoutes:        m.dynamicRoutes,
+		routeCaseInsensitive: m.routeCaseInsensitive,
+		caseInsensitiveServe: m.caseInsensitiveServe,
+		treeConfig:           m.treeConfig,
+		preRouting:           slices.Clone(m.preRouting),
+		trailingSlash:        m.trailingSlash,
+		redirectCleanPath:    m.redirectCleanPath,
+		strictSlash:          m.strictSlash,
+		serveStrictSlash:     m.serveStrictSlash,
+		drainBody:            m.drainBody,
+		autoHEAD:             m.autoHEAD,
+		flushResponses:       m.flushResponses,
+		defaultContentType:   m.defaultContentType,
+		matchTiming:          m.matchTiming,
+		spa:                  m.spa,
+		fallthroughHandler:   m.fallthroughHandler,
+		strictHost:           m.strictHost,
+		optionsHandler:       m.optionsHandler,
+		methodNotAllowed:     m.methodNotAllowed,
+		notFound:             m.notFound,
+		errHandler:           m.errHandler,
+		scopedErrHandler:     slices.Clone(m.scopedErrHandler),
+		errMapper:            m.errMapper,
+		panicHandler:         m.panicHandler,
+		panicStackHandler:    m.panicStackHandler,
+		middleware:           slices.Clone(m.middleware),
+		preMiddleware:        slices.Clone(m.preMiddleware),
+		logger:               m.logger,
+	}
+	c.preHandler = c.buildPreHandler()
+	c.routes.Store(newRouteTable())
+	return c
+}
+
+// Reset removes all routes registered with the mux, including host routes,
+// so it can be reconfigured. Options, global middleware and error handlers
+// registered with HandleError are kept.
+//
+// Reset is safe to call while the mux is serving requests, requests in
+// flight complete with the routes they were matched against.
+func (m *Mux) Reset() {
+	m.routesMu.Lock()
+	m.routes.Store(newRouteTable())
+	m.routesMu.Unlock()
+}
+
 // NewWithDefaults is a helper method to return a mux with default options enabled.
 //
 // It is equivalent to calling:
@@ -140,10 +306,85 @@ func NewWithDefaults(opts ...func(*Mux)) *Mux {
 // When adding a panic handler, the mux will also log the error message
 // from the panic.
 //
+// While panic recovery is enabled, the writer in the context records the
+// response status, so the handler can tell whether the response has
+// started. It passes flushing, hijacking and io.ReaderFrom through to the
+// http.ResponseWriter, which can be reached with http.ResponseController.
+//
 // To disable the panic handler, provide a nil value to the handler parameter.
 func WithPanicHandler(handler PanicHandler) func(*Mux) {
 	return func(m *Mux) {
 		m.panicHandler = handler
+		m.panicStackHandler = nil
+	}
+}
+
+// WithPanicHandlerStack enables panic recovery in the mux like WithPanicHandler,
+// and registers a PanicStackHandler that receives the stack trace captured
+// when the panic is recovered.
+//
+// It replaces any handler set with WithPanicHandler, and vice versa.
+func WithPanicHandlerStack(handler PanicStackHandler) func(*Mux) {
+	return func(m *Mux) {
+		m.panicStackHandler = handler
+		m.panicHandler = nil
+	}
+}
+
+// WithMiddleware registers middleware that wrap every route registered
+// with the mux.
+//
+// Global middleware are applied outside of any group or route middleware.
+// Multiple calls append to the existing global middleware.
+func WithMiddleware(mw ...MiddlewareFunc) func(*Mux) {
+	return func(m *Mux) {
+		m.middleware = append(m.middleware, mw...)
+	}
+}
+
+// WithPreMiddleware adds middleware that wrap the routing of every
+// request, including requests answered by the not found, method not
+// allowed and OPTIONS handlers, such as for access logs and metrics.
+//
+// Pre-routing middleware run in order before pre-routing functions and
+// before the route is matched, outside of any global, group or route
+// middleware, which only wrap matched handlers. The context carries the
+// writer, which may be replaced with SetWriter. If a middleware returns
+// an *AbortError, it's written as the response, and other errors are
+// handled by the mux's error handler.
+//
+// Panics in pre-routing middleware are not recovered by the mux's panic
+// handler. Multiple calls append to the existing middleware.
+func WithPreMiddleware(mw ...MiddlewareFunc) func(*Mux) {
+	return func(m *Mux) {
+		m.preMiddleware = append(m.preMiddleware, mw...)
+	}
+}
+
+// WithLogger sets the function used by the mux to log messages, such as
+// recovered panics and file server errors.
+//
+// The signature matches slog.Info and friends so structured loggers
+// can be used directly:
+//
+//	roxi.New(roxi.WithLogger(slog.Error))
+//
+// If no logger is set, messages are printed to stdout.
+func WithLogger(fn func(msg string, args ...any)) func(*Mux) {
+	return func(m *Mux) {
+		m.logger = fn
+	}
+}
+
+// WithPreRouting registers a function that runs for every request before
+// the route is looked up, so it can modify the request's method or path.
+//
+// Multiple calls append to the existing functions, which run in order.
+// Unlike middleware, which wraps the matched handler, pre-routing functions
+// affect which route is matched, see MethodOverride.
+func WithPreRouting(fn func(r *http.Request)) func(*Mux) {
+	return func(m *Mux) {
+		m.preRouting = append(m.preRouting, fn)
 	}
 }
 
@@ -154,18 +395,153 @@ func WithOptionsHandler(handler http.Handler) func(*Mux) {
 	}
 }
 
-// WithRedirectCaseInsensitive enables case insensitive routing.
+// WithDynamicRoutes enables registering routes while the mux is serving
+// requests, such as routes loaded by plugins or feature flags.
+//
+// Each registration copies the registered routes, modifies the copy and
+// atomically swaps it in, so matching requests remains lock free and
+// allocation free. The tradeoff is that registering a route costs time and
+// memory proportional to the number of routes, so muxes with many routes
+// should register them before serving where possible.
+func WithDynamicRoutes() func(*Mux) {
+	return func(m *Mux) {
+		m.dynamicRoutes = true
+	}
+}
+
+// WithCaseInsensitiveRouting enables case insensitive routing.
+//
+// Requests whose path only matches a route when ignoring the case of its
+// static parts are redirected to the path with those parts lowercased.
+// The values of path variables and the query string are kept as requested,
+// so "/Users/Bob?tab=Posts" is redirected to "/users/Bob?tab=Posts" for
+// the route "/users/:name".
 func WithCaseInsensitiveRouting() func(*Mux) {
 	return func(m *Mux) {
 		m.routeCaseInsensitive = true
 	}
 }
 
+// WithCaseInsensitiveServe enables case insensitive routing, serving
+// requests that match a route when ignoring case directly instead of
+// redirecting them, see WithCaseInsensitiveRouting.
+//
+// Path variables keep the case of the request path.
+func WithCaseInsensitiveServe() func(*Mux) {
+	return func(m *Mux) {
+		m.routeCaseInsensitive = true
+		m.caseInsensitiveServe = true
+	}
+}
+
+// WithEmptyWildcard captures a wildcard that matches an empty remainder
+// as "" instead of "/".
+//
+// By default, '/files/*file' captures "/" for both '/files' and '/files/',
+// which suits file serving. Proxies that append the captured value to
+// another path may prefer "", so neither request adds a trailing slash.
+// Non-empty remainders, such as '/files/a', are always captured as "/a".
+func WithEmptyWildcard() func(*Mux) {
+	return func(m *Mux) {
+		m.treeConfig.emptyWildcard = true
+	}
+}
+
+// WithParamChars sets the characters that start path variables and
+// wildcards in registered routes, in place of ':' and '*', so static path
+// segments may contain them:
+//
+//	mux := roxi.New(roxi.WithParamChars('$', '*'))
+//	mux.GET("/books/urn:isbn:$isbn", handler)
+//
+// The characters apply to every route of the mux, including the path of
+// FileServer, which must end in the wildcard character followed by 'file'.
+// Params only recognizes the default characters.
+//
+// It panics if the characters are equal, or are '/', '?', '(' or ')'.
+func WithParamChars(param, wildcard byte) func(*Mux) {
+	for _, b := range []byte{param, wildcard} {
+		switch b {
+		case 0, '/', '?', '(', ')':
+			panic("invalid path variable character: '" + string(b) + "'")
+		}
+	}
+
+	if param == wildcard {
+		panic("path variable and wildcard characters must differ: '" + string(param) + "'")
+	}
+
+	return func(m *Mux) {
+		m.treeConfig.param = param
+		m.treeConfig.wildcard = wildcard
+	}
+}
+
+// WithMaxParams sets the maximum number of path variables and wildcards
+// of a route, 32 by default. Registering a route with more panics, and
+// requests are not matched past the limit.
+//
+// It's a defensive limit for routes registered from untrusted sources.
+// A limit of 0 or less removes it.
+func WithMaxParams(n int) func(*Mux) {
+	return func(m *Mux) {
+		m.treeConfig.maxParams = max(n, 0)
+	}
+}
+
 // WithRedirectTrailingSlash enables redirection of unmatched request paths
 // that contain a trailing '/' character.
+//
+// It is equivalent to WithTrailingSlash(TrailingStrip).
 func WithRedirectTrailingSlash() func(*Mux) {
+	return WithTrailingSlash(TrailingStrip)
+}
+
+// TrailingSlashPolicy is the direction in which unmatched request paths
+// are redirected to canonical URLs, see WithTrailingSlash.
+type TrailingSlashPolicy uint8
+
+const (
+	// TrailingIgnore doesn't redirect request paths by their trailing '/'.
+	TrailingIgnore TrailingSlashPolicy = iota
+
+	// TrailingStrip redirects "/foo/" to "/foo" if it's registered.
+	TrailingStrip
+
+	// TrailingAdd redirects "/foo" to "/foo/" if it's registered, for
+	// directory style URLs.
+	TrailingAdd
+)
+
+// WithTrailingSlash sets the policy for redirecting unmatched request
+// paths by adding or removing their trailing '/', TrailingIgnore by
+// default.
+//
+// The path is only redirected in the direction of the policy, and if the
+// redirected path matches a route. WithStrictSlash redirects in both
+// directions, and takes precedence over the policy.
+func WithTrailingSlash(policy TrailingSlashPolicy) func(*Mux) {
 	return func(m *Mux) {
-		m.redirectTrailingSlash = true
+		m.trailingSlash = policy
+	}
+}
+
+// WithStrictSlash enables matching request paths with or without a
+// trailing '/' to the route registered with the other form, so "/foo/"
+// matches "/foo" and "/foo" matches "/foo/".
+//
+// If serve is set, the request is handled by the matched route directly,
+// keeping its method and body, which redirects can't guarantee for clients
+// of mutating requests. Otherwise it's redirected to the route's path, as
+// with WithRedirectTrailingSlash.
+//
+// Serving directly leaves both forms of a path reachable with the same
+// content, which search engines may index as duplicates. Redirecting keeps
+// a single canonical URL at the cost of a round trip.
+func WithStrictSlash(serve bool) func(*Mux) {
+	return func(m *Mux) {
+		m.strictSlash = true
+		m.serveStrictSlash = serve
 	}
 }
 
@@ -177,6 +553,103 @@ func WithRedirectCleanPath() func(*Mux) {
 	}
 }
 
+// WithDrainBody enables draining and closing of request bodies after the
+// handler returns, so the connection can be reused for keep-alive.
+//
+// At most 256KB of unread body is discarded. Bodies of CONNECT and
+// upgrade requests are never drained, as the handler may have hijacked
+// the connection.
+func WithDrainBody() func(*Mux) {
+	return func(m *Mux) {
+		m.drainBody = true
+	}
+}
+
+// WithAutoHEAD enables serving HEAD requests with the GET handler of
+// the matched route when no HEAD handler is registered for it.
+//
+// The response headers and status are written as usual, but the
+// body is discarded. Explicitly registered HEAD routes take precedence.
+func WithAutoHEAD() func(*Mux) {
+	return func(m *Mux) {
+		m.autoHEAD = true
+	}
+}
+
+// WithSPAFallback enables serving the index file from fsys to unmatched
+// GET and HEAD requests, in place of the not found handler, so a
+// single-page app can handle its own routes.
+//
+// Requests whose final path segment contains a '.' are treated as asset
+// requests and still handled by the not found handler.
+func WithSPAFallback(fsys http.FileSystem, index string) func(*Mux) {
+	return func(m *Mux) {
+		m.spa = &spaFallback{fsys: fsys, index: index}
+	}
+}
+
+// WithFallthroughHandler sets a handler, such as another router, that
+// handles requests matching no route of the mux, so an application can be
+// migrated to roxi a route at a time.
+//
+// Requests are passed to the handler if no route matches and no redirect
+// applies, in place of the mux's OPTIONS, 405, SPA and not found handling.
+// The handler receives a copy of the request taken before routing, without
+// any path values or pattern set while matching.
+//
+// Enabling it copies every request before it's routed.
+func WithFallthroughHandler(handler http.Handler) func(*Mux) {
+	return func(m *Mux) {
+		m.fallthroughHandler = handler
+	}
+}
+
+// WithStrictHost enables responding with 421 Misdirected Request to
+// requests whose host matches no host registered with Host, instead of
+// handling them with the mux's own routes.
+//
+// This allows clients that coalesced connections across hosts to retry
+// the request on a new connection. It has no effect if no hosts are registered.
+func WithStrictHost() func(*Mux) {
+	return func(m *Mux) {
+		m.strictHost = true
+	}
+}
+
+// WithRespondFlush enables flushing the response after each call to Respond,
+// so clients receive output as it's produced by long-running handlers.
+//
+// Writers that don't support flushing are left as is.
+func WithRespondFlush() func(*Mux) {
+	return func(m *Mux) {
+		m.flushResponses = true
+	}
+}
+
+// WithDefaultContentType sets the Content-Type of responses written with
+// Respond, RespondStatus, Abort or the error mapper whose Responder
+// returns an empty content type, such as "application/json" for an API.
+//
+// Without a default, the header is left unset and net/http detects the
+// content type from the first bytes of the body. The default is not set
+// on empty bodies, or if the handler has already set the header.
+func WithDefaultContentType(ct string) func(*Mux) {
+	return func(m *Mux) {
+		m.defaultContentType = ct
+	}
+}
+
+// WithMatchTiming enables recording the time spent matching each request
+// against the registered routes, separately from the handler, see
+// Mux.MatchLatency.
+//
+// Hosts registered with Host record to the same histogram.
+func WithMatchTiming() func(*Mux) {
+	return func(m *Mux) {
+		m.matchTiming = new(matchHistogram)
+	}
+}
+
 // WithMethodNotAllowedHandler replaces the default 405 response handler.
 func WithMethodNotAllowedHandler(handler http.Handler) func(*Mux) {
 	return func(m *Mux) {
@@ -193,25 +666,125 @@ func WithNotFoundHandler(handler http.Handler) func(*Mux) {
 
 // WithErrorHandler replaces the default 500 response handler.
 //
-// A nil handler will be ignored.
+// The error returned by the handler is stored in the request's context,
+// see GetError. A nil handler will be ignored.
 func WithErrorHandler(handler http.Handler) func(*Mux) {
 	return func(m *Mux) {
 		m.errHandler = handler
 	}
 }
 
+// WithErrorMapper sets a function mapping errors returned by handlers to
+// responses, so errors can be rendered centrally:
+//
+//	roxi.WithErrorMapper(func(err error) roxi.Responder {
+//		switch {
+//		case errors.Is(err, ErrNotFound):
+//			return roxi.Text(http.StatusNotFound, "not found")
+//		case errors.Is(err, ErrForbidden):
+//			return roxi.Text(http.StatusForbidden, "forbidden")
+//		}
+//		return nil
+//	})
+//
+// The Responder is written with its StatusSetter status code, or 500
+// Internal Server Error if it doesn't implement StatusSetter. If fn
+// returns nil, or the Responder fails to encode, the error is handled by
+// the error handler as usual. An *AbortError is always written as is.
+func WithErrorMapper(fn func(err error) Responder) func(*Mux) {
+	return func(m *Mux) {
+		m.errMapper = fn
+	}
+}
+
 // ----------------------------------------------------------------------
 // Methods
 
 // ServeHTTP implements the http.Handler interface.
 func (m *Mux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if m.preHandler == nil {
+		m.serve(w, r)
+		return
+	}
+
+	ctx := getContext()
+	ctx.Context = r.Context()
+	ctx.value = w
+	ctx.mux = m
+	ctx.req = r
+	defer putContext(ctx)
+
+	if err := m.preHandler(ctx, r); err != nil && !streamFailed(ctx, r, err) && !writeAbort(w, err, m.defaultContentType) && !m.writeMapped(w, err) {
+		m.serveError(w, r, err)
+	}
+}
+
+// buildPreHandler returns the pre-routing middleware of m wrapping the
+// routing of requests, or nil if there are none.
+func (m *Mux) buildPreHandler() HandlerFunc {
+	if len(m.preMiddleware) == 0 {
+		return nil
+	}
+
+	return MiddlewareStack(func(ctx context.Context, r *http.Request) error {
+		// pass on values added to the context by the middleware.
+		if v, ok := ctx.(*writerContext); !ok {
+			r = r.WithContext(ctx)
+		} else if v.Context != r.Context() {
+			r = r.WithContext(v.Context)
+		}
+
+		m.serve(GetWriter(ctx), r)
+		return nil
+	}, m.preMiddleware...)
+}
+
+// serve routes the request to its handler.
+func (m *Mux) serve(w http.ResponseWriter, r *http.Request) {
+	for _, fn := range m.preRouting {
+		fn(r)
+	}
+
+	rt := m.routes.Load()
+
+	if len(rt.hosts) != 0 {
+		if hm := rt.host(normalizeHost(r.Host)); hm != nil {
+			hm.ServeHTTP(w, r)
+			return
+		}
+
+		if m.strictHost {
+			_ = respond(w, http.StatusMisdirectedRequest, &errorResponse{
+				http.StatusMisdirectedRequest,
+				http.StatusText(http.StatusMisdirectedRequest),
+			})
+			return
+		}
+	}
+
 	// Setup context.
 	ctx := getContext()
 	ctx.Context = r.Context()
 	ctx.value = w
+	ctx.mux = m
+	ctx.req = r
 	defer putContext(ctx)
 
-	if m.panicHandler != nil {
+	// record the status so panic handlers can tell whether the
+	// response has been started.
+	if m.panicStackHandler != nil || m.panicHandler != nil {
+		ctx.sw.ResponseWriter = w
+		w = &ctx.sw
+		ctx.value = w
+	}
+
+	if m.panicStackHandler != nil {
+		defer func() {
+			if rec := recover(); rec != nil {
+				m.panicStackHandler(ctx, r, rec, debug.Stack())
+			}
+		}()
+	} else if m.panicHandler != nil {
 		defer func() {
 			if rec := recover(); rec != nil {
 				m.panicHandler(ctx, r, rec)
@@ -219,18 +792,75 @@ func (m *Mux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}()
 	}
 
+	// keep the request untouched by matching for the fallthrough handler.
+	var unrouted *http.Request
+	if m.fallthroughHandler != nil {
+		unrouted = r.Clone(r.Context())
+	}
+
 	path := toBytes(r.URL.Path)
 
-	if root := m.trees[r.Method]; root != nil {
-		// search for handler
-		if handler, found := root.search(path, r); found {
-			if err := handler(ctx, r); err != nil {
-				m.errHandler.ServeHTTP(w, r)
+	// search for handler
+	root := rt.trees[r.Method]
+
+	var start time.Time
+	if m.matchTiming != nil {
+		start = time.Now()
+	}
+
+	var handler HandlerFunc
+	var found bool
+	if root != nil {
+		handler, found = root.search(path, r)
+
+		if !found && m.caseInsensitiveServe {
+			_, handler, found = m.matchCaseInsensitive(root, path, r)
+		}
+
+		if !found && m.serveStrictSlash {
+			if alt := toggleSlash(path); alt != nil {
+				handler, found = root.search(alt, r)
 			}
-			return
 		}
+	}
 
-		// don't redirect if proxy connection or root path are requested.
+	// fall back to the GET route for HEAD requests.
+	if !found && m.autoHEAD && r.Method == http.MethodHead {
+		if get := rt.trees[http.MethodGet]; get != nil {
+			if handler, found = get.search(path, r); found {
+				hw := &headWriter{ResponseWriter: w}
+				defer hw.finish()
+				ctx.value = hw
+			}
+		}
+	}
+
+	if m.matchTiming != nil {
+		m.matchTiming.record(time.Since(start))
+	}
+
+	if found {
+		defer func() {
+			// a handler that timed out may still be reading the request.
+			if ctx.detached {
+				return
+			}
+
+			if m.drainBody {
+				drainBody(r)
+			}
+			removeMultipart(r)
+		}()
+
+		if err := handler(ctx, r); err != nil && !streamFailed(ctx, r, err) && !writeAbort(ctx.value, err, m.defaultContentType) && !m.writeMapped(ctx.value, err) {
+			m.serveError(w, r, err)
+		}
+		return
+	}
+
+	if root != nil {
+		// don't redirect if proxy connection or root path are requested,
+		// a redirect is not a valid response to CONNECT.
 		if r.Method != http.MethodConnect && (len(path) != 1 || path[0] != '/') {
 			// following the same redirect behavior as httprouter
 			code := http.StatusMovedPermanently
@@ -239,26 +869,36 @@ func (m *Mux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			}
 
 			// check if any redirect behavior is enabled.
-			redirect := (m.redirectCleanPath || m.redirectTrailingSlash || m.routeCaseInsensitive)
+			redirect := (m.redirectCleanPath || m.trailingSlash != TrailingIgnore || m.routeCaseInsensitive || m.strictSlash)
 
 			// step through each enabled path scrubbing option
 			if m.redirectCleanPath {
 				path = CleanPath(r.URL.Path)
 			}
 
-			if m.redirectTrailingSlash {
-				if len(path) > 1 && path[len(path)-1] == '/' {
+			if m.strictSlash && !m.serveStrictSlash {
+				if alt := toggleSlash(path); alt != nil {
+					path = alt
+				}
+			} else if len(path) > 1 {
+				switch trailing := path[len(path)-1] == '/'; {
+				case m.trailingSlash == TrailingStrip && trailing:
 					path = path[:len(path)-1]
+				case m.trailingSlash == TrailingAdd && !trailing:
+					path = toggleSlash(path)
 				}
 			}
 
-			if m.routeCaseInsensitive {
-				path = toBytes(strings.ToLower(toString(path)))
-			}
-
 			if redirect {
+				var found bool
+				if m.routeCaseInsensitive {
+					path, _, found = m.matchCaseInsensitive(root, path, r)
+				} else {
+					_, found = root.search(path, r)
+				}
+
 				// found a match, redirect to correct path.
-				if _, found := root.search(path, r); found {
+				if found {
 					r.URL.Path = toString(path)
 					http.Redirect(w, r, r.URL.String(), code)
 					return
@@ -267,21 +907,31 @@ func (m *Mux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// handle OPTIONS requests.
+	// hand unmatched requests to the legacy handler.
+	if m.fallthroughHandler != nil {
+		m.fallthroughHandler.ServeHTTP(w, unrouted)
+		return
+	}
+
+	// handle OPTIONS requests, or respond that the method is not allowed.
+	handler405 := m.methodNotAllowed
 	if r.Method == http.MethodOptions && m.optionsHandler != nil {
-		if allow := m.allowed(r.Method, path); allow != "" {
-			w.Header().Set("Allow", allow)
-			m.optionsHandler.ServeHTTP(w, r)
-			return
-		}
-	} else if m.methodNotAllowed != nil {
-		if allow := m.allowed(r.Method, path); allow != "" {
+		handler405 = m.optionsHandler
+	}
+
+	if handler405 != nil {
+		if allow := m.allowed(rt, r.Method, toBytes(r.URL.Path)); allow != "" {
 			w.Header().Set("Allow", allow)
-			m.methodNotAllowed.ServeHTTP(w, r)
+			handler405.ServeHTTP(w, r)
 			return
 		}
 	}
 
+	// serve the single-page app index.
+	if m.spa != nil && m.spa.serve(w, r) {
+		return
+	}
+
 	// not found case.
 	if m.notFound != nil {
 		m.notFound.ServeHTTP(w, r)
@@ -290,31 +940,208 @@ func (m *Mux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func (m *Mux) allowed(rMethod string, path []byte) string {
-	var allowed methodFlag
+// headWriter is a http.ResponseWriter that counts and discards
+// the body written in response to a HEAD request.
+type headWriter struct {
+	http.ResponseWriter
+	n           int
+	wroteHeader bool
+}
 
-	for method, tree := range m.trees {
-		if method == rMethod {
+// WriteHeader implements the http.ResponseWriter interface.
+func (w *headWriter) WriteHeader(code int) {
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write implements the http.ResponseWriter interface.
+func (w *headWriter) Write(b []byte) (int, error) {
+	w.n += len(b)
+	return len(b), nil
+}
+
+// finish sets the Content-Length the GET response would have had
+// if the handler did not write the header itself.
+func (w *headWriter) finish() {
+	if w.wroteHeader {
+		return
+	}
+
+	if h := w.Header(); h.Get("Content-Length") == "" {
+		h.Set("Content-Length", strconv.Itoa(w.n))
+	}
+}
+
+// Unwrap returns the underlying http.ResponseWriter for use
+// with http.ResponseController.
+func (w *headWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// maxDrainBytes is the maximum number of unread body bytes discarded
+// by drainBody.
+const maxDrainBytes = 256 << 10
+
+// drainBody discards the unread request body, up to maxDrainBytes,
+// and closes it.
+func drainBody(r *http.Request) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return
+	}
+
+	// the connection may have been hijacked.
+	if r.Method == http.MethodConnect || r.Header.Get("Upgrade") != "" {
+		return
+	}
+
+	_, _ = io.CopyN(io.Discard, r.Body, maxDrainBytes)
+	_ = r.Body.Close()
+}
+
+// scopedHandler is an error handler registered for a path prefix.
+type scopedHandler struct {
+	prefix  string
+	handler http.Handler
+}
+
+// HandleError registers an error handler for routes registered under the
+// given path prefix, which replaces the mux's error handler when one of
+// their handlers returns an error.
+//
+// The prefix is matched against the registered route pattern on path segment
+// boundaries, so "/api" applies to "/api" and "/api/users" but not "/apis".
+// If several prefixes match, the longest wins. With case insensitive
+// routing, the prefix is matched case insensitively as well.
+func (m *Mux) HandleError(prefix string, handler http.Handler) {
+	if len(prefix) == 0 || prefix[0] != '/' {
+		panic("error handler prefix '" + prefix + "' does not begin with '/'")
+	}
+
+	if handler == nil {
+		panic("error handler cannot be nil")
+	}
+
+	m.scopedErrHandler = append(m.scopedErrHandler, scopedHandler{
+		prefix:  strings.TrimRight(prefix, "/"),
+		handler: handler,
+	})
+}
+
+// writeMapped writes the response mapped from err by the error mapper.
+//
+// It reports false if there's no mapper, it maps err to nil or the
+// response fails to render, before anything is written.
+func (m *Mux) writeMapped(w http.ResponseWriter, err error) bool {
+	if m.errMapper == nil {
+		return false
+	}
+
+	body := m.errMapper(err)
+	if body == nil {
+		return false
+	}
+
+	v, ct, err := body.Response()
+	if err != nil {
+		return false
+	}
+
+	code := http.StatusInternalServerError
+	if s, ok := body.(StatusSetter); ok {
+		code = s.StatusCode()
+	}
+
+	_ = writeResponse(w, code, body, v, ct, m.defaultContentType)
+	return true
+}
+
+// serveError calls the error handler for r with err stored in the
+// request's context, see GetError.
+func (m *Mux) serveError(w http.ResponseWriter, r *http.Request, err error) {
+	m.errorHandler(r).ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), errorKey, err)))
+}
+
+// errorHandler returns the error handler for the route matched by r.
+func (m *Mux) errorHandler(r *http.Request) http.Handler {
+	handler, longest := m.errHandler, -1
+
+	for _, s := range m.scopedErrHandler {
+		if len(s.prefix) <= longest || len(r.Pattern) < len(s.prefix) {
+			continue
+		}
+
+		// patterns keep their registered casing.
+		if head := r.Pattern[:len(s.prefix)]; head != s.prefix &&
+			(!m.routeCaseInsensitive || !strings.EqualFold(head, s.prefix)) {
 			continue
 		}
-		if n := tree.getNode(path); n != nil {
-			// early return since this is handled at registration.
-			allowed |= n.allowed
-			break
+
+		// only match on path segment boundaries.
+		if rest := r.Pattern[len(s.prefix):]; rest != "" && rest[0] != '/' {
+			continue
 		}
+
+		handler, longest = s.handler, len(s.prefix)
 	}
 
-	// include OPTIONS if it's not the requested method.
-	if allowed != 0 && rMethod != http.MethodOptions {
-		allowed |= OPTIONS
-		return allowed.String()
+	return handler
+}
+
+// log logs a message with key value pairs using the configured logger.
+func (m *Mux) log(msg string, args ...any) {
+	if m.logger != nil {
+		m.logger(msg, args...)
+		return
 	}
+	printRecord(msg, args...)
+}
 
-	if allowed != 0 {
-		return allowed.String()
+// allowed returns the methods, other than rMethod, with a route matching
+// path, formatted for the Allow header. OPTIONS is included unless it's
+// the requested method.
+//
+// Constraints of the routes are not checked, so a path is reported as
+// allowed if it matches a route's pattern.
+func (m *Mux) allowed(rt *routeTable, rMethod string, path []byte) string {
+	if rt.methods == nil {
+		return ""
 	}
 
-	return ""
+	allowed, exact := rt.methods.allowedMethods(path)
+	allowed &^= httpMethods[rMethod]
+
+	// a route of the methods tree may be shadowed by a static route in
+	// the tree of its method, search those trees as the request would be.
+	if !exact && allowed != 0 {
+		candidates := allowed
+		allowed = 0
+		for method := GET; method <= TRACE; method <<= 1 {
+			if candidates&method == 0 {
+				continue
+			}
+
+			if root := rt.trees[method.String()]; root != nil {
+				if _, found := root.search(path, nil); found {
+					allowed |= method
+				}
+			}
+		}
+	}
+
+	// GET routes also serve HEAD requests.
+	if m.autoHEAD && allowed&GET != 0 {
+		allowed |= HEAD
+	}
+
+	if allowed == 0 {
+		return ""
+	}
+
+	// include OPTIONS if it's not the requested method.
+	if rMethod != http.MethodOptions {
+		allowed |= OPTIONS
+	}
+	return allowed.header()
 }
 
 // Handler registers an http.Handler to handle requests at the given
@@ -335,137 +1162,520 @@ func (m *Mux) HandlerFunc(method, path string, handler http.HandlerFunc) {
 	})
 }
 
+// Static registers a handler that responds to requests at the given method
+// and path with a constant body.
+//
+// The response headers are computed once at registration so serving the
+// route does not allocate. A status of 0 is treated as 200 OK.
+//
+// The body must not be modified after registration.
+func (m *Mux) Static(method, path string, body []byte, contentType string, status int) {
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	ct := []string{contentType}
+	cl := []string{strconv.Itoa(len(body))}
+
+	m.Handle(method, path, func(ctx context.Context, r *http.Request) error {
+		w := GetWriter(ctx)
+
+		h := w.Header()
+		if contentType != "" {
+			h["Content-Type"] = ct
+		}
+		h["Content-Length"] = cl
+
+		w.WriteHeader(status)
+		if r.Method == http.MethodHead {
+			return nil
+		}
+
+		_, err := w.Write(body)
+		return err
+	})
+}
+
+// HandleAB registers a route that dispatches to one of several variant
+// handlers, chosen by the bucket returned for the request.
+//
+// The handler registered with the empty key is the default, and handles
+// requests whose bucket has no variant. If no default is registered,
+// those requests are handled by the mux's not found handler.
+func (m *Mux) HandleAB(method, path string, bucket func(r *http.Request) string, handlers map[string]HandlerFunc, mw ...MiddlewareFunc) {
+	if bucket == nil {
+		panic("bucket func cannot be nil")
+	}
+
+	if len(handlers) == 0 {
+		panic("no variant handlers provided for path '" + path + "'")
+	}
+
+	// copy to prevent modification after registration.
+	variants := make(map[string]HandlerFunc, len(handlers))
+	for k, h := range handlers {
+		if h == nil {
+			panic("variant handler '" + k + "' cannot be nil")
+		}
+		variants[k] = h
+	}
+
+	m.Handle(method, path, func(ctx context.Context, r *http.Request) error {
+		if h, ok := variants[bucket(r)]; ok {
+			return h(ctx, r)
+		}
+
+		if h, ok := variants[""]; ok {
+			return h(ctx, r)
+		}
+
+		if m.notFound != nil {
+			m.notFound.ServeHTTP(GetWriter(ctx), r)
+		} else {
+			http.NotFound(GetWriter(ctx), r)
+		}
+		return nil
+	}, mw...)
+}
+
 // Handle registers a HandlerFunc to handle requests at the given
 // method and path.
 //
+// The middleware provided wrap the handler inside of the mux's
+// global middleware.
+//
 // Handle only allows standard HTTP methods provided by net/http.
-func (m *Mux) Handle(method, path string, handlerFunc HandlerFunc) {
-	if method == "" {
-		panic("method cannot be empty")
+//
+// CONNECT and TRACE routes are matched and dispatched like any other method,
+// except CONNECT requests are never redirected. CONNECT requests are matched
+// against r.URL.Path, so routes only match requests with a path, such as
+// extended CONNECT requests, authority-form requests are handled by the
+// not found handler.
+//
+// Handle panics with a *RouteError if the route can't be registered,
+// see TryHandle.
+func (m *Mux) Handle(method, path string, handlerFunc HandlerFunc, mw ...MiddlewareFunc) {
+	if err := m.TryHandle(method, path, handlerFunc, mw...); err != nil {
+		panic(err)
 	}
+}
 
-	if _, ok := httpMethods[method]; !ok {
-		panic("method '" + method + "' is not a valid http method")
+// TryHandle registers a HandlerFunc like Handle, but returns an error
+// instead of panicking if the route can't be registered, such as when
+// routes are loaded from configuration.
+//
+// The error is a *RouteError wrapping ErrInvalidRoute, ErrRouteExists
+// or ErrRouteConflict, and the routes of m are left unchanged:
+//
+//	err := mux.TryHandle("GET", path, handler)
+//	if errors.Is(err, roxi.ErrRouteExists) {
+//		// ...
+//	}
+func (m *Mux) TryHandle(method, path string, handlerFunc HandlerFunc, mw ...MiddlewareFunc) error {
+	return m.tryHandle(method, path, handlerFunc, 0, mw)
+}
+
+// tryHandle registers a HandlerFunc like TryHandle, with the priority
+// of the route, see WithPriority.
+func (m *Mux) tryHandle(method, path string, handlerFunc HandlerFunc, priority int, mw []MiddlewareFunc) error {
+	if err := checkRoute(method, path); err != nil {
+		return err
 	}
 
-	if len(path) == 0 {
-		panic("cannot register empty path")
+	if handlerFunc == nil {
+		return &RouteError{Method: method, Path: path, Err: ErrInvalidRoute, Detail: "handlerfunc cannot be nil"}
 	}
 
-	if len(path) > 0 && path[0] != '/' {
-		panic("path '" + path + "' does not begin with '/'")
+	if len(m.middleware) != 0 || len(mw) != 0 {
+		stack := make([]MiddlewareFunc, 0, len(m.middleware)+len(mw))
+		stack = append(stack, m.middleware...)
+		stack = append(stack, mw...)
+		handlerFunc = withDepth(MiddlewareStack(handlerFunc, stack...), stack)
 	}
 
-	if handlerFunc == nil {
-		panic("handlerfunc cannot be nil")
+	return m.insert(method, path, handlerFunc, priority)
+}
+
+// CanRegister reports whether a route could be registered at the method
+// and path, without modifying the routes of m, such as to validate the
+// routes of a plugin before registering any of them.
+//
+// It returns the *RouteError TryHandle would return for the route, such
+// as a path variable conflicting with a registered one in the same
+// segment, or nil if the route can be registered.
+func (m *Mux) CanRegister(method, path string) error {
+	if err := checkRoute(method, path); err != nil {
+		return err
 	}
 
-	root := m.trees[method]
+	// insert into a copy of the tree, the routes of m are never modified.
+	root := m.routes.Load().trees[method]
 	if root == nil {
-		root = &node{}
+		root = &node{config: m.treeConfig}
+	} else {
+		root = root.clone()
+	}
 
-		m.trees[method] = root
+	err := root.insertRoute(m.routeKey(path), toBytes(path), noopHandler, httpMethods[method], 0)
+	if rerr, ok := err.(*RouteError); ok {
+		rerr.Method = method
 	}
+	return err
+}
 
-	bPath := toBytes(path)
-	if m.routeCaseInsensitive {
-		bPath = toBytes(strings.ToLower(path))
+// noopHandler is registered in the trees checked by CanRegister.
+func noopHandler(ctx context.Context, r *http.Request) error {
+	return nil
+}
+
+// checkRoute returns a *RouteError if the method or path of a route
+// is not valid.
+func checkRoute(method, path string) error {
+	invalid := func(detail string) error {
+		return &RouteError{Method: method, Path: path, Err: ErrInvalidRoute, Detail: detail}
 	}
 
-	// cache allowed methods
-	var allowed methodFlag
-	for method, tree := range m.trees {
-		if n := tree.getNode(bPath); n != nil {
-			n.allowed |= httpMethods[method]
-			allowed |= n.allowed
-		}
+	if detail := methodError(method); detail != "" {
+		return invalid(detail)
 	}
 
-	root.insert(bPath, handlerFunc, httpMethods[method])
+	if len(path) == 0 {
+		return invalid("cannot register empty path")
+	}
+
+	if path[0] != '/' {
+		return invalid("path '" + path + "' does not begin with '/'")
+	}
+	return nil
 }
 
-// ----------------------------------------------------------------------
-// File Server methods
+// methodError describes why method can't be registered, or returns an
+// empty string if it's valid.
+func methodError(method string) string {
+	if method == "" {
+		return "method cannot be empty"
+	}
 
-// FileServer wraps http.FileServer to serve files from the provided http.FileSystem.
-//
-// The path must end in a wildcard with the name '*file'.
-func (m *Mux) FileServer(path string, fs http.FileSystem) {
-	// check path
-	if err := checkFSPath(path); err != nil {
-		panic(err)
+	if _, ok := httpMethods[method]; !ok {
+		// methods are case sensitive, suggest the registered one.
+		upper := strings.ToUpper(method)
+		if _, ok := httpMethods[upper]; ok {
+			return "method '" + method + "' is not valid; did you mean '" + upper + "'?"
+		}
+		return "method '" + method + "' is not a valid http method"
 	}
+	return ""
+}
+
+// insert adds the handler to the tree for the method.
+func (m *Mux) insert(method, path string, handlerFunc HandlerFunc, priority int) error {
+	route := toBytes(path)
+	bPath := m.routeKey(path)
+
+	err := m.updateRoutes(func(rt *routeTable) error {
+		root := rt.trees[method]
+		if root == nil {
+			root = &node{config: m.treeConfig}
+		}
 
-	fsrv := http.FileServer(fs)
-	m.GET(path, func(ctx context.Context, r *http.Request) error {
-		r.URL.Path = r.PathValue("file")
-		fsrv.ServeHTTP(GetWriter(ctx), r)
+		if err := root.insertRoute(bPath, route, handlerFunc, httpMethods[method], priority); err != nil {
+			return err
+		}
+		rt.trees[method] = root
+
+		if rt.methods == nil {
+			rt.methods = &node{config: m.treeConfig}
+		}
+		rt.methods.allow(bPath, httpMethods[method])
 		return nil
 	})
+
+	if rerr, ok := err.(*RouteError); ok {
+		rerr.Method = method
+	}
+	return err
 }
 
-func checkFSPath(path string) error {
-	if len(path) == 0 {
-		return errors.New("cannot register empty path")
+// routeKey returns the key of the path in the route trees.
+//
+// With case insensitive routing, the static parts of the path are
+// lowercased, path variable names and constraints are kept as is.
+func (m *Mux) routeKey(path string) []byte {
+	if !m.routeCaseInsensitive {
+		return toBytes(path)
 	}
 
-	if len(path) > 0 && path[0] != '/' {
-		return errors.New("path '" + path + "' does not begin with '/'")
+	b := []byte(path)
+	for i := 0; i < len(b); i++ {
+		switch b[i] {
+		case m.treeConfig.wildcard:
+			// wildcards consume the rest of the path.
+			return b
+		case m.treeConfig.param:
+			for i < len(b) && b[i] != '/' && b[i] != '(' {
+				i++
+			}
+
+			if i < len(b) && b[i] == '(' {
+				if i = constraintEnd(b, i); i < 0 {
+					return b
+				}
+			}
+		default:
+			if 'A' <= b[i] && b[i] <= 'Z' {
+				b[i] += 'a' - 'A'
+			}
+		}
 	}
+	return b
+}
 
-	if len(path) < 6 || path[len(path)-6:] != "/*file" {
-		return errors.New("file server path must end in '/*file'")
+// toggleSlash returns path with its trailing '/' removed, or added if it
+// has none. It returns nil for the root path.
+func toggleSlash(path []byte) []byte {
+	switch {
+	case len(path) <= 1:
+		return nil
+	case path[len(path)-1] == '/':
+		return path[:len(path)-1]
+	default:
+		// path may share memory with the request's URL.
+		return append(path[:len(path):len(path)], '/')
 	}
+}
 
-	return nil
+// matchCaseInsensitive searches root for path with its static parts
+// lowercased, as they are in the route keys. It returns the path as
+// matched, in which the values of path variables keep their case.
+func (m *Mux) matchCaseInsensitive(root *node, path []byte, r *http.Request) ([]byte, HandlerFunc, bool) {
+	lower := make([]byte, len(path))
+	for i, c := range path {
+		if 'A' <= c && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		lower[i] = c
+	}
+
+	if _, found := root.search(lower, r); !found {
+		return nil, nil, false
+	}
+
+	// search again with the values restored, so the path values of r
+	// and constraints see the requested case.
+	m.treeConfig.restoreValues(lower, path, r.Pattern)
+	handler, found := root.search(lower, r)
+	if !found {
+		// such as a constraint rejecting the requested case, drop the
+		// match of the first search before r is handled as unmatched.
+		m.treeConfig.clearParams(r)
+		return nil, nil, false
+	}
+	return lower, handler, true
+}
+
+// Unregister removes the route registered at the given method and path,
+// and reports whether it was registered.
+//
+// The path must be the one passed to Handle, including any constraints
+// and optional markers. Routes can only be removed while serving
+// requests if the mux was created with WithDynamicRoutes.
+func (m *Mux) Unregister(method, path string) bool {
+	bPath := m.routeKey(path)
+
+	var removed bool
+	m.updateRoutes(func(rt *routeTable) error {
+		root := rt.trees[method]
+		if root == nil || !root.remove(bPath, toBytes(path)) {
+			return nil
+		}
+		removed = true
+
+		if len(root.edges) == 0 {
+			delete(rt.trees, method)
+		}
+
+		// routes sharing the path keep the method allowed.
+		if root.registered(bPath) {
+			return nil
+		}
+
+		rt.methods.disallow(bPath, httpMethods[method])
+		return nil
+	})
+	return removed
 }
 
 // ----------------------------------------------------------------------
 // Helper methods
 
-// GET is a helper method for m.Handle("GET", path, handlerFunc).
-func (m *Mux) GET(path string, handlerFunc HandlerFunc) {
-	m.Handle(http.MethodGet, path, handlerFunc)
+// GET is a helper method for m.Handle("GET", path, handlerFunc, mw...).
+func (m *Mux) GET(path string, handlerFunc HandlerFunc, mw ...MiddlewareFunc) {
+	m.Handle(http.MethodGet, path, handlerFunc, mw...)
 }
 
-// HEAD is a helper method for m.Handle("HEAD", path, handlerFunc).
-func (m *Mux) HEAD(path string, handlerFunc HandlerFunc) {
-	m.Handle(http.MethodHead, path, handlerFunc)
+// HEAD is a helper method for m.Handle("HEAD", path, handlerFunc, mw...).
+func (m *Mux) HEAD(path string, handlerFunc HandlerFunc, mw ...MiddlewareFunc) {
+	m.Handle(http.MethodHead, path, handlerFunc, mw...)
 }
 
-// POST is a helper method for m.Handle("POST", path, handlerFunc).
-func (m *Mux) POST(path string, handlerFunc HandlerFunc) {
-	m.Handle(http.MethodPost, path, handlerFunc)
+// POST is a helper method for m.Handle("POST", path, handlerFunc, mw...).
+func (m *Mux) POST(path string, handlerFunc HandlerFunc, mw ...MiddlewareFunc) {
+	m.Handle(http.MethodPost, path, handlerFunc, mw...)
 }
 
-// PUT is a helper method for m.Handle("PUT", path, handlerFunc).
-func (m *Mux) PUT(path string, handlerFunc HandlerFunc) {
-	m.Handle(http.MethodPut, path, handlerFunc)
+// PUT is a helper method for m.Handle("PUT", path, handlerFunc, mw...).
+func (m *Mux) PUT(path string, handlerFunc HandlerFunc, mw ...MiddlewareFunc) {
+	m.Handle(http.MethodPut, path, handlerFunc, mw...)
 }
 
-// PATCH is a helper method for m.Handle("PATCH", path, handlerFunc).
-func (m *Mux) PATCH(path string, handlerFunc HandlerFunc) {
-	m.Handle(http.MethodPatch, path, handlerFunc)
+// PATCH is a helper method for m.Handle("PATCH", path, handlerFunc, mw...).
+func (m *Mux) PATCH(path string, handlerFunc HandlerFunc, mw ...MiddlewareFunc) {
+	m.Handle(http.MethodPatch, path, handlerFunc, mw...)
 }
 
-// DELETE is a helper method for m.Handle("DELETE", path, handlerFunc).
-func (m *Mux) DELETE(path string, handlerFunc HandlerFunc) {
-	m.Handle(http.MethodDelete, path, handlerFunc)
+// DELETE is a helper method for m.Handle("DELETE", path, handlerFunc, mw...).
+func (m *Mux) DELETE(path string, handlerFunc HandlerFunc, mw ...MiddlewareFunc) {
+	m.Handle(http.MethodDelete, path, handlerFunc, mw...)
 }
 
-// OPTIONS is a helper method for m.Handle("OPTIONS", path, handlerFunc).
-func (m *Mux) OPTIONS(path string, handlerFunc HandlerFunc) {
-	m.Handle(http.MethodOptions, path, handlerFunc)
+// OPTIONS is a helper method for m.Handle("OPTIONS", path, handlerFunc, mw...).
+func (m *Mux) OPTIONS(path string, handlerFunc HandlerFunc, mw ...MiddlewareFunc) {
+	m.Handle(http.MethodOptions, path, handlerFunc, mw...)
+}
+
+// Methods returns a MethodGroup that registers routes for each of the
+// given methods:
+//
+//	mux.Methods("GET", "POST").Handle("/search", search)
+//
+// Methods panics if no methods are given or any of them is not valid.
+func (m *Mux) Methods(methods ...string) *MethodGroup {
+	if len(methods) == 0 {
+		panic("methods cannot be empty")
+	}
+
+	for _, method := range methods {
+		if detail := methodError(method); detail != "" {
+			panic(detail)
+		}
+	}
+
+	return &MethodGroup{mux: m, methods: slices.Clone(methods)}
+}
+
+// MethodGroup registers a handler for several methods at the same path.
+// Each method is registered as its own route, as if by Mux.Handle.
+type MethodGroup struct {
+	mux     *Mux
+	methods []string
+}
+
+// Handle registers the HandlerFunc at path for each method of the group.
+//
+// Handle panics with a *RouteError if the route can't be registered for
+// any of the methods, see TryHandle.
+func (g *MethodGroup) Handle(path string, handlerFunc HandlerFunc, mw ...MiddlewareFunc) {
+	if err := g.TryHandle(path, handlerFunc, mw...); err != nil {
+		panic(err)
+	}
+}
+
+// TryHandle registers the HandlerFunc at path for each method of the
+// group, like Mux.TryHandle. If the route can't be registered for one
+// of the methods, the routes registered for the others are removed and
+// the error is returned.
+func (g *MethodGroup) TryHandle(path string, handlerFunc HandlerFunc, mw ...MiddlewareFunc) error {
+	for i, method := range g.methods {
+		if err := g.mux.TryHandle(method, path, handlerFunc, mw...); err != nil {
+			for _, registered := range g.methods[:i] {
+				g.mux.Unregister(registered, path)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// With returns a group that registers routes with the route options,
+// including those that affect how a route is matched, such as
+// WithPriority:
+//
+//	mux.With(roxi.WithPriority(1)).Handle("GET", `/posts/:id(\d+)`, byID)
+func (m *Mux) With(opts ...RouteOption) *OptionGroup {
+	return &OptionGroup{mux: m, opts: slices.Clone(opts)}
+}
+
+// OptionGroup registers routes with route options, see Mux.With.
+type OptionGroup struct {
+	mux  *Mux
+	opts []RouteOption
+}
+
+// Handle registers the HandlerFunc at the method and path with the
+// route options of the group, like Mux.Handle.
+//
+// Handle panics with a *RouteError if the route can't be registered,
+// see TryHandle.
+func (g *OptionGroup) Handle(method, path string, handlerFunc HandlerFunc, mw ...MiddlewareFunc) {
+	if err := g.TryHandle(method, path, handlerFunc, mw...); err != nil {
+		panic(err)
+	}
+}
+
+// TryHandle registers the HandlerFunc at the method and path with the
+// route options of the group, like Mux.TryHandle.
+func (g *OptionGroup) TryHandle(method, path string, handlerFunc HandlerFunc, mw ...MiddlewareFunc) error {
+	cfg := newRouteConfig(g.opts)
+	return g.mux.tryHandle(method, path, Route(handlerFunc, g.opts...), cfg.priority, mw)
 }
 
 // ----------------------------------------------------------------------
 // Debugging methods
 
+// Lookup returns the handler registered for the method and path, along with
+// the values of its path variables, without serving a request.
+//
+// Lookup uses the mux's own routes, not those of hosts registered with Host.
+// HEAD requests fall back to GET routes if WithAutoHEAD is set. With case
+// insensitive routing, the path matches routes regardless of the case of
+// their static parts, as it would when served.
+func (m *Mux) Lookup(method, path string) (HandlerFunc, map[string]string, bool) {
+	rt := m.routes.Load()
+	r := &http.Request{Method: method, URL: &url.URL{Path: path}}
+
+	h, found := m.lookup(rt.trees[method], path, r)
+	if !found && m.autoHEAD && method == http.MethodHead {
+		h, found = m.lookup(rt.trees[http.MethodGet], path, r)
+	}
+
+	if !found {
+		return nil, nil, false
+	}
+	return h, m.treeConfig.params(r), true
+}
+
+// lookup searches root for path like a request being served, see Lookup.
+func (m *Mux) lookup(root *node, path string, r *http.Request) (HandlerFunc, bool) {
+	if root == nil {
+		return nil, false
+	}
+
+	if h, found := root.search(toBytes(path), r); found {
+		return h, true
+	}
+
+	if m.routeCaseInsensitive {
+		_, h, found := m.matchCaseInsensitive(root, toBytes(path), r)
+		return h, found
+	}
+	return nil, false
+}
+
 // Routes returns all of the routes registered in the Mux as a map.
 // The map keys are HTTP methods, and the values are slices of paths for that method.
 func (m *Mux) Routes() map[string][]string {
 	routes := make(map[string][]string)
-	for method, tree := range m.trees {
+	for method, tree := range m.routes.Load().trees {
 		var methodRoutes []string
 		tree.collectRoutes(&methodRoutes)
 		if len(methodRoutes) > 0 {
@@ -475,6 +1685,50 @@ func (m *Mux) Routes() map[string][]string {
 	return routes
 }
 
+// TreeStats describes the routing trees of a Mux, see Mux.Stats.
+type TreeStats struct {
+	// Methods holds the stats of the tree of each registered method.
+	Methods map[string]MethodStats
+
+	// Routes is the total number of routes of every method.
+	Routes int
+}
+
+// MethodStats describes the routing tree of a single method.
+type MethodStats struct {
+	// Nodes is the number of nodes in the tree, including the root.
+	Nodes int
+
+	// Leaves is the number of nodes a request can be matched to. It
+	// includes the leaves added for routes with an optional final path
+	// segment, so it may be greater than Routes.
+	Leaves int
+
+	// MaxDepth is the number of edges from the root to the deepest node.
+	MaxDepth int
+
+	// Routes is the number of routes registered for the method.
+	Routes int
+}
+
+// Stats walks the routing tree of each method and returns its node, leaf
+// and route counts and its depth, such as to log or expose on a debug
+// endpoint when tuning large route tables.
+//
+// Routes of sub-muxes registered with Host are not included.
+func (m *Mux) Stats() TreeStats {
+	stats := TreeStats{Methods: make(map[string]MethodStats)}
+	for method, tree := range m.routes.Load().trees {
+		var s MethodStats
+		tree.collectStats(&s, 0)
+		if s.Routes > 0 {
+			stats.Methods[method] = s
+			stats.Routes += s.Routes
+		}
+	}
+	return stats
+}
+
 // PrintTree prints the contents of the routing tree.
 //
 // The root node is always skipped when performing lookups,
@@ -485,7 +1739,7 @@ func (m *Mux) Routes() map[string][]string {
 //
 // is expected behavior when printing the Tree.
 func (m *Mux) PrintTree() {
-	for k, v := range m.trees {
+	for k, v := range m.routes.Load().trees {
 		fmt.Printf("[%s]\n", k)
 		v.print(1)
 	}
@@ -4,13 +4,19 @@
 package roxi
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"fmt"
-	"io/fs"
+	"io"
+	"maps"
 	"net/http"
 	"net/http/httptest"
-	"path"
+	"os"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -70,6 +76,67 @@ func Test_InvalidRoutes(t *testing.T) {
 	}
 }
 
+func Test_DuplicateParams(t *testing.T) {
+	h := func(ctx context.Context, r *http.Request) error { return nil }
+	tests := []struct {
+		name string
+		path string
+		dup  bool
+	}{
+		{"Adjacent", "/:id/:id", true},
+		{"Separated", "/:id/x/:id", true},
+		{"Wildcard", "/:path/*path", true},
+		{"Constraint", "/:id([0-9]+)/:id", true},
+		{"Optional", "/:id/:id?", true},
+		{"Distinct", "/:user/x/:id", false},
+		{"DistinctWildcard", "/:id/*path", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := New().TryHandle("GET", tt.path, h)
+			if errors.Is(err, ErrInvalidRoute) != tt.dup {
+				t.Errorf("unexpected error result: %v", err)
+			}
+
+			if tt.dup && !strings.Contains(fmt.Sprint(err), "duplicate variable") {
+				t.Errorf("expected error to name the duplicate; got: [%v]", err)
+			}
+		})
+	}
+
+	defer func() {
+		if rec := recover(); !strings.Contains(fmt.Sprint(rec), "duplicate variable 'id'") {
+			t.Errorf("expected: [duplicate variable 'id']; got: [%v]", rec)
+		}
+	}()
+	New().GET("/:id/:id", h)
+}
+
+func Test_InvalidMethodMessage(t *testing.T) {
+	h := func(ctx context.Context, r *http.Request) error { return nil }
+	tests := []struct {
+		name   string
+		method string
+		msg    string
+	}{
+		{"Lowercase", "get", "method 'get' is not valid; did you mean 'GET'?"},
+		{"MixedCase", "Delete", "method 'Delete' is not valid; did you mean 'DELETE'?"},
+		{"Unknown", "panda", "method 'panda' is not a valid http method"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if rec := recover(); fmt.Sprint(rec) != tt.msg {
+					t.Errorf("expected: [%v]; got: [%v]", tt.msg, rec)
+				}
+			}()
+			New().Handle(tt.method, "/", h)
+		})
+	}
+}
+
 func Test_HTTPHandlerFunc(t *testing.T) {
 	mux := New()
 
@@ -140,6 +207,97 @@ func Test_MuxMethods(t *testing.T) {
 	mux2.OPTIONS("/", func(ctx context.Context, r *http.Request) error { return nil })
 }
 
+func Test_Static(t *testing.T) {
+	mux := New()
+	mux.Static("GET", "/config.json", []byte(`{"debug":false}`), "application/json", 0)
+	mux.Static("HEAD", "/config.json", []byte(`{"debug":false}`), "application/json", 0)
+	mux.Static("POST", "/created", nil, "", 201)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		code   int
+		ct     string
+		body   string
+	}{
+		{"Body", "GET", "/config.json", 200, "application/json", `{"debug":false}`},
+		{"Head", "HEAD", "/config.json", 200, "application/json", ""},
+		{"NoBody", "POST", "/created", 201, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := http.NewRequest(tt.method, tt.path, nil)
+			w := httptest.NewRecorder()
+
+			mux.ServeHTTP(w, r)
+			if w.Code != tt.code {
+				t.Errorf("expected: [%d]; got: [%d]", tt.code, w.Code)
+			}
+
+			if ct := w.Header().Get("Content-Type"); ct != tt.ct {
+				t.Errorf("expected: [%s]; got: [%s]", tt.ct, ct)
+			}
+
+			if w.Body.String() != tt.body {
+				t.Errorf("expected: [%s]; got: [%s]", tt.body, w.Body.String())
+			}
+		})
+	}
+}
+
+// closeReader is a request body that records whether it was closed.
+type closeReader struct {
+	*strings.Reader
+	closed bool
+}
+
+func (r *closeReader) Close() error {
+	r.closed = true
+	return nil
+}
+
+func Test_DrainBody(t *testing.T) {
+	mux := New(WithDrainBody())
+	mux.POST("/ignore", func(ctx context.Context, r *http.Request) error {
+		GetWriter(ctx).WriteHeader(400)
+		return nil
+	})
+
+	tests := []struct {
+		name    string
+		size    int
+		upgrade bool
+		remain  int
+		closed  bool
+	}{
+		{"Drain", 1024, false, 0, true},
+		{"DrainLimit", maxDrainBytes + 10, false, 10, true},
+		{"Upgrade", 1024, true, 1024, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := &closeReader{Reader: strings.NewReader(strings.Repeat("a", tt.size))}
+			r, _ := http.NewRequest("POST", "/ignore", body)
+			if tt.upgrade {
+				r.Header.Set("Upgrade", "websocket")
+			}
+
+			mux.ServeHTTP(httptest.NewRecorder(), r)
+
+			if body.Len() != tt.remain {
+				t.Errorf("expected [%d] unread bytes; got [%d]", tt.remain, body.Len())
+			}
+
+			if body.closed != tt.closed {
+				t.Errorf("expected closed: [%v]; got: [%v]", tt.closed, body.closed)
+			}
+		})
+	}
+}
+
 func Test_NotFound(t *testing.T) {
 	mux := New()
 
@@ -156,6 +314,45 @@ func Test_NotFound(t *testing.T) {
 	}
 }
 
+func Test_NotFoundPage(t *testing.T) {
+	tests := []struct {
+		name   string
+		accept string
+		ct     string
+		body   string
+	}{
+		{"Browser", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", "text/html; charset=utf-8", notFoundPage},
+		{"NoAccept", "", "text/plain", "Not Found"},
+		{"Wildcard", "*/*", "text/plain", "Not Found"},
+		{"JSON", "application/json", "text/plain", "Not Found"},
+		{"Refused", "text/html;q=0, text/plain", "text/plain", "Not Found"},
+		{"RefusedDecimal", "text/html; q=0.0, text/plain", "text/plain", "Not Found"},
+		{"LowQuality", "text/plain, text/html;q=0.1", "text/html; charset=utf-8", notFoundPage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/missing", nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+
+			w := httptest.NewRecorder()
+			New().ServeHTTP(w, r)
+
+			if w.Code != http.StatusNotFound {
+				t.Errorf("expected: [%d]; got: [%d]", http.StatusNotFound, w.Code)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != tt.ct {
+				t.Errorf("expected: [%s]; got: [%s]", tt.ct, ct)
+			}
+			if w.Body.String() != tt.body {
+				t.Errorf("expected: [%s]; got: [%s]", tt.body, w.Body.String())
+			}
+		})
+	}
+}
+
 func Test_PanicHandler(t *testing.T) {
 	mux := New(WithPanicHandler(DefaultPanicHandler))
 
@@ -173,230 +370,1800 @@ func Test_PanicHandler(t *testing.T) {
 	}
 }
 
-func Test_RedirectTrailingSlash(t *testing.T) {
-	mux := New(WithRedirectTrailingSlash())
+func Test_PanicHandlerStarted(t *testing.T) {
+	var msgs []string
+	logger := func(msg string, args ...any) {
+		msgs = append(msgs, msg)
+	}
 
-	mux.GET("/redirect", func(ctx context.Context, r *http.Request) error {
-		GetWriter(ctx).WriteHeader(204)
-		return nil
-	})
+	mux := New(WithPanicHandler(DefaultPanicHandler), WithLogger(logger))
 
-	r, _ := http.NewRequest("GET", "/redirect/", nil)
-	w := httptest.NewRecorder()
+	mux.GET("/status", func(ctx context.Context, r *http.Request) error {
+		GetWriter(ctx).WriteHeader(http.StatusAccepted)
+		panic("at the disco")
+	})
+	mux.GET("/body", func(ctx context.Context, r *http.Request) error {
+		_, _ = GetWriter(ctx).Write([]byte("partial"))
+		panic("at the disco")
+	})
 
-	mux.ServeHTTP(w, r)
-	if w.Result().StatusCode != 301 {
-		t.Errorf("failed redirect; got status code [%d]", w.Result().StatusCode)
+	tests := []struct {
+		name    string
+		path    string
+		code    int
+		headers int
+	}{
+		{"Status", "/status", http.StatusAccepted, 1},
+		{"Body", "/body", http.StatusOK, 0},
 	}
-}
 
-func Test_CaseInsensitveRouting(t *testing.T) {
-	mux := New(WithCaseInsensitiveRouting())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msgs = nil
 
-	mux.GET("/foo", func(ctx context.Context, r *http.Request) error {
-		GetWriter(ctx).WriteHeader(204)
-		return nil
-	})
+			w := &countingWriter{ResponseRecorder: httptest.NewRecorder()}
+			mux.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
 
-	r, _ := http.NewRequest("GET", "/FOO", nil)
-	w := httptest.NewRecorder()
+			if w.Code != tt.code {
+				t.Errorf("expected: [%d]; got: [%d]", tt.code, w.Code)
+			}
 
-	mux.ServeHTTP(w, r)
-	if w.Result().StatusCode != 301 {
-		t.Error("failed to redirect with case insensitivity")
+			if w.headers != tt.headers {
+				t.Errorf("expected: [%d]; got: [%d]", tt.headers, w.headers)
+			}
+
+			if !slices.Equal(msgs, []string{"roxi: recovered panic"}) {
+				t.Errorf("expected: [%v]; got: [%v]", []string{"roxi: recovered panic"}, msgs)
+			}
+		})
 	}
 }
 
-func Test_OptionsHandler(t *testing.T) {
-	mux := New(WithOptionsHandler(optHandler))
-
-	mux.GET("/unused", func(ctx context.Context, r *http.Request) error {
-		return InternalServerError(ctx, r)
-	})
-
-	r, _ := http.NewRequest("OPTIONS", "/unused", nil)
-	w := httptest.NewRecorder()
+// readerFromWriter is a ResponseRecorder that counts calls to ReadFrom,
+// like the sendfile path of net/http's writer.
+type readerFromWriter struct {
+	*httptest.ResponseRecorder
+	readFrom int
+}
 
-	mux.ServeHTTP(w, r)
-	if w.Result().StatusCode != 204 {
-		t.Error("failed to fallback to options handler")
-	}
+func (w *readerFromWriter) ReadFrom(src io.Reader) (int64, error) {
+	w.readFrom++
+	return io.Copy(w.ResponseRecorder, src)
 }
 
-func Test_MethodNotAllowed(t *testing.T) {
+func Test_PanicHandlerReadFrom(t *testing.T) {
 	mux := New()
-
-	mux.GET("/unused", func(ctx context.Context, r *http.Request) error {
-		return InternalServerError(ctx, r)
+	mux.GET("/copy", func(ctx context.Context, r *http.Request) error {
+		// hide strings.Reader.WriteTo so io.Copy uses ReadFrom.
+		if _, err := io.Copy(GetWriter(ctx), struct{ io.Reader }{strings.NewReader("body")}); err != nil {
+			return err
+		}
+		panic("at the disco")
 	})
 
-	r, _ := http.NewRequest("POST", "/unused", nil)
-	w := httptest.NewRecorder()
-
-	t.Log(mux.Routes())
+	w := &readerFromWriter{ResponseRecorder: httptest.NewRecorder()}
+	_ = captureStdout(t, func() {
+		mux.ServeHTTP(w, httptest.NewRequest("GET", "/copy", nil))
+	})
 
-	mux.ServeHTTP(w, r)
-	if w.Result().StatusCode != 405 {
-		t.Errorf("failed to execute method not allowed handler: resp[%d]", w.Result().StatusCode)
+	if w.readFrom != 1 {
+		t.Errorf("expected: [%d]; got: [%d]", 1, w.readFrom)
 	}
-	allow := w.Result().Header.Get("Allow")
-	if allow != "GET, OPTIONS" {
-		t.Errorf("expected: [%v]; got: [%v]", "GET, OPTIONS", allow)
+
+	// the response was started by ReadFrom, so no 500 is written.
+	if w.Code != http.StatusOK || w.Body.String() != "body" {
+		t.Errorf("expected: [200 body]; got: [%d %s]", w.Code, w.Body.String())
 	}
 }
 
-func Test_SetAllowHeaderWithOptions(t *testing.T) {
-	mux := New(
-		WithOptionsHandler(optHandler),
+func Test_PanicHandlerStack(t *testing.T) {
+	var (
+		recovered any
+		stack     string
 	)
 
-	mux.GET("/unused", func(ctx context.Context, r *http.Request) error {
-		return InternalServerError(ctx, r)
-	})
-
-	r, _ := http.NewRequest("POST", "/unused", nil)
-	w := httptest.NewRecorder()
+	mux := New(WithPanicHandlerStack(func(ctx context.Context, r *http.Request, err any, s []byte) {
+		recovered, stack = err, string(s)
+		GetWriter(ctx).WriteHeader(http.StatusInternalServerError)
+	}))
 
-	mux.ServeHTTP(w, r)
+	mux.GET("/panic", panickingHandler)
 
-	res := w.Result().Header.Get("Allow")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/panic", nil))
 
-	// ordering isn't guaranteed.
-	if res != "OPTIONS, GET" && res != "GET, OPTIONS" {
-		t.Errorf("expected [%s]; got [%s]", "GET, OPTIONS", res)
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected: [%d]; got: [%d]", http.StatusInternalServerError, w.Code)
 	}
-}
-
-func Test_SetRequestPattern(t *testing.T) {
-	mux := New()
 
-	mux.GET("/foo", func(ctx context.Context, r *http.Request) error {
-		GetWriter(ctx).WriteHeader(204)
-		return nil
-	})
+	if recovered != "at the disco" {
+		t.Errorf("expected: [%v]; got: [%v]", "at the disco", recovered)
+	}
 
-	r, _ := http.NewRequest("GET", "/foo", nil)
-	w := httptest.NewRecorder()
+	// the stack must include the frame that panicked.
+	if !strings.Contains(stack, "panickingHandler") {
+		t.Errorf("expected stack to contain [panickingHandler]; got: [%s]", stack)
+	}
 
-	mux.ServeHTTP(w, r)
-	if r.Pattern != "/foo" {
-		t.Errorf("expected: [%v]; got[%v]", "/foo", r.Pattern)
+	// the last option replaces the other.
+	mux = New(WithPanicHandlerStack(nil), WithPanicHandler(DefaultPanicHandler))
+	if mux.panicStackHandler != nil || mux.panicHandler == nil {
+		t.Error("expected WithPanicHandler to replace the stack handler")
 	}
 }
 
-func Test_RedirectCleanPath(t *testing.T) {
-	mux := New(WithRedirectCleanPath())
-
-	mux.GET("/redirect", func(ctx context.Context, r *http.Request) error {
-		GetWriter(ctx).WriteHeader(204)
-		return nil
-	})
+func panickingHandler(ctx context.Context, r *http.Request) error {
+	panic("at the disco")
+}
 
-	r, _ := http.NewRequest("GET", "/../../redirect", nil)
-	w := httptest.NewRecorder()
+// captureStdout returns everything written to os.Stdout while fn executes.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
 
-	mux.ServeHTTP(w, r)
-	if w.Result().StatusCode != 301 {
-		t.Errorf("failed redirect; got status code [%d]", w.Result().StatusCode)
+	rd, wr, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
 	}
 
-	loc := w.Result().Header.Get("Location")
-	if loc != "/redirect" {
-		t.Errorf("failed redirect; got location [%s]", loc)
-	}
+	stdout := os.Stdout
+	os.Stdout = wr
+	defer func() { os.Stdout = stdout }()
+
+	out := make(chan string)
+	go func() {
+		b, _ := io.ReadAll(rd)
+		out <- string(b)
+	}()
+
+	fn()
+	wr.Close()
+	return <-out
 }
 
-func Test_HandlerFuncServeHTTPHandleError(t *testing.T) {
-	handler := HandlerFunc(func(ctx context.Context, r *http.Request) error {
-		return fmt.Errorf("woah there partner, you're routing too fast")
+func Test_PanicHandlerRecord(t *testing.T) {
+	mux := New()
+
+	mux.POST("/panic/:id", func(ctx context.Context, r *http.Request) error {
+		panic("at the disco")
 	})
 
-	r, _ := http.NewRequest("GET", "/", nil)
+	r, _ := http.NewRequest("POST", "/panic/42?q=1", nil)
+	r.Header.Set("X-Request-ID", "abc123")
 	w := httptest.NewRecorder()
 
-	handler.ServeHTTP(w, r)
-	if w.Result().StatusCode != 500 {
-		t.Errorf("failed to handle error; got status code [%d]", w.Result().StatusCode)
+	out := captureStdout(t, func() { mux.ServeHTTP(w, r) })
+
+	for _, want := range []string{
+		"roxi: recovered panic",
+		`error="at the disco"`,
+		`method="POST"`,
+		`url="/panic/42?q=1"`,
+		`pattern="/panic/:id"`,
+		`request_id="abc123"`,
+		`stack="goroutine`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected log to contain [%s]; got: [%s]", want, out)
+		}
 	}
 }
 
-type mockFS struct {
-	opened bool
-}
-
-func (f *mockFS) Open(name string) (http.File, error) {
-	switch name {
-	case "/test.html", "/index.js", "/asset.png":
-		f.opened = true
-		return nil, nil
-	case "/error.jpeg":
-		return nil, fmt.Errorf("diff error")
-	default:
-		return nil, fs.ErrNotExist
+func Test_WithLogger(t *testing.T) {
+	var msgs []string
+	logger := func(msg string, args ...any) {
+		msgs = append(msgs, fmt.Sprint(append([]any{msg}, args...)...))
 	}
-}
-
-func Test_FileServer(t *testing.T) {
-	mux := NewWithDefaults()
 
-	fs := &mockFS{}
-	mux.FileServer("/files/*file", fs)
+	mux := New(WithLogger(logger))
+	mux.FileServer("/files/*file", &mockFS{})
+	mux.GET("/panic", func(ctx context.Context, r *http.Request) error {
+		panic("at the disco")
+	})
 
 	tests := []struct {
-		name       string
-		path       string
-		shouldOpen bool
+		name string
+		path string
+		msg  string
 	}{
-		{"Match", "/files/test.html", true},
-		{"NoMatch", "/files/file.txt", false},
-		{"ReadError", "/files/error.jpeg", false},
-		{"CleanPath", "/files/../asset.png", true},
+		{"Panic", "/panic", "roxi: recovered panic"},
+		{"FileServerError", "/files/error.jpeg", "roxi: file server error"},
 	}
 
 	for _, tt := range tests {
-		fs.opened = false
 		t.Run(tt.name, func(t *testing.T) {
-			fs.opened = false
+			msgs = msgs[:0]
+
 			r, _ := http.NewRequest("GET", tt.path, nil)
-			w := httptest.NewRecorder()
+			out := captureStdout(t, func() { mux.ServeHTTP(httptest.NewRecorder(), r) })
 
-			mux.ServeHTTP(w, r)
-			if fs.opened != tt.shouldOpen {
-				t.Errorf("expected: [%v]; got: [%v]", tt.shouldOpen, fs.opened)
-				t.Errorf("file value (cleaned): [%v]", path.Clean(r.PathValue("file")))
+			if out != "" {
+				t.Errorf("expected no output on stdout; got: [%s]", out)
+			}
+
+			if len(msgs) != 1 || !strings.HasPrefix(msgs[0], tt.msg) {
+				t.Errorf("expected: [%s]; got: %v", tt.msg, msgs)
 			}
 		})
 	}
 }
 
-// ----------------------------------------------------------------------
-// Edge cases
+func Test_RedirectTrailingSlash(t *testing.T) {
+	mux := New(WithRedirectTrailingSlash())
 
-func Test_WildcardHandler(t *testing.T) {
-	mux := NewWithDefaults()
-	mux.GET("/*path", func(ctx context.Context, r *http.Request) error {
+	mux.GET("/redirect", func(ctx context.Context, r *http.Request) error {
 		GetWriter(ctx).WriteHeader(204)
 		return nil
 	})
 
-	tests := []struct {
-		name string
-		path string
-		ok   bool
-	}{
-		{"Empty", "/", true},
+	r, _ := http.NewRequest("GET", "/redirect/", nil)
+	w := httptest.NewRecorder()
+
+	mux.ServeHTTP(w, r)
+	if w.Result().StatusCode != 301 {
+		t.Errorf("failed redirect; got status code [%d]", w.Result().StatusCode)
+	}
+}
+
+func Test_TrailingSlashPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   TrailingSlashPolicy
+		path     string
+		code     int
+		location string
+	}{
+		{"AddRedirects", TrailingAdd, "/docs", 301, "/docs/"},
+		{"AddKeepsQuery", TrailingAdd, "/docs?page=2", 301, "/docs/?page=2"},
+		{"AddDoesNotStrip", TrailingAdd, "/users/", 404, ""},
+		{"AddExact", TrailingAdd, "/docs/", 200, ""},
+		{"StripRedirects", TrailingStrip, "/users/", 301, "/users"},
+		{"StripDoesNotAdd", TrailingStrip, "/docs", 404, ""},
+		{"IgnoreAdd", TrailingIgnore, "/docs", 404, ""},
+		{"IgnoreStrip", TrailingIgnore, "/users/", 404, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := New(WithTrailingSlash(tt.policy))
+			mux.GET("/docs/", emptyHandler)
+			mux.GET("/users", emptyHandler)
+
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+
+			if w.Code != tt.code {
+				t.Errorf("expected: [%d]; got: [%d]", tt.code, w.Code)
+			}
+			if loc := w.Header().Get("Location"); loc != tt.location {
+				t.Errorf("expected: [%s]; got: [%s]", tt.location, loc)
+			}
+		})
+	}
+}
+
+func Test_StrictSlash(t *testing.T) {
+	tests := []struct {
+		name     string
+		serve    bool
+		method   string
+		path     string
+		code     int
+		location string
+		body     string
+	}{
+		{"ServeStripped", true, "POST", "/foo/", 200, "", "payload"},
+		{"ServeAdded", true, "PUT", "/bar", 200, "", "payload"},
+		{"ServeExact", true, "POST", "/foo", 200, "", "payload"},
+		{"ServeNotFound", true, "POST", "/baz/", 404, "", ""},
+		{"RedirectStripped", false, "GET", "/foo/", 301, "/foo", ""},
+		{"RedirectAdded", false, "GET", "/bar?x=1", 301, "/bar/?x=1", ""},
+		{"RedirectPreservesMethod", false, "POST", "/foo/", 308, "/foo", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			echo := func(ctx context.Context, r *http.Request) error {
+				_, err := io.Copy(GetWriter(ctx), r.Body)
+				return err
+			}
+
+			mux := New(WithStrictSlash(tt.serve))
+			mux.Handle(tt.method, "/foo", echo)
+			mux.Handle(tt.method, "/bar/", echo)
+
+			r := httptest.NewRequest(tt.method, tt.path, strings.NewReader("payload"))
+			w := httptest.NewRecorder()
+
+			mux.ServeHTTP(w, r)
+
+			if w.Code != tt.code {
+				t.Errorf("expected: [%d]; got: [%d]", tt.code, w.Code)
+			}
+
+			if loc := w.Header().Get("Location"); loc != tt.location {
+				t.Errorf("expected: [%s]; got: [%s]", tt.location, loc)
+			}
+
+			if tt.body != "" && w.Body.String() != tt.body {
+				t.Errorf("expected: [%s]; got: [%s]", tt.body, w.Body.String())
+			}
+		})
+	}
+}
+
+func Test_CaseInsensitveRouting(t *testing.T) {
+	mux := New(WithCaseInsensitiveRouting())
+
+	mux.GET("/foo", func(ctx context.Context, r *http.Request) error {
+		GetWriter(ctx).WriteHeader(204)
+		return nil
+	})
+
+	r, _ := http.NewRequest("GET", "/FOO", nil)
+	w := httptest.NewRecorder()
+
+	mux.ServeHTTP(w, r)
+	if w.Result().StatusCode != 301 {
+		t.Error("failed to redirect with case insensitivity")
+	}
+}
+
+func Test_CaseInsensitiveRedirect(t *testing.T) {
+	mux := New(WithCaseInsensitiveRouting(), WithRedirectTrailingSlash())
+	mux.GET("/foo", emptyHandler)
+	mux.GET("/Users/:name", emptyHandler)
+	mux.GET("/files/*path", emptyHandler)
+	mux.GET(`/tags/:tag([a-z]+)`, emptyHandler)
+	mux.GET("/articles/:page?", emptyHandler)
+
+	tests := []struct {
+		name     string
+		path     string
+		code     int
+		location string
+	}{
+		{"Query", "/FOO?x=1&Y=Z", 301, "/foo?x=1&Y=Z"},
+		{"TrailingSlash", "/FOO/", 301, "/foo"},
+		{"ParamCase", "/USERS/Bob", 301, "/users/Bob"},
+		{"WildcardCase", "/Files/A/B.txt", 301, "/files/A/B.txt"},
+		{"OptionalParent", "/Articles", 301, "/articles"},
+		{"Optional", "/Articles/Two", 301, "/articles/Two"},
+		{"ConstraintCase", "/TAGS/Go", 404, ""},
+		{"Exact", "/users/Bob", 200, ""},
+		{"NotFound", "/BAR", 404, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tt.path, nil)
+			w := httptest.NewRecorder()
+
+			mux.ServeHTTP(w, r)
+
+			if w.Code != tt.code {
+				t.Errorf("expected: [%d]; got: [%d]", tt.code, w.Code)
+			}
+
+			if loc := w.Header().Get("Location"); loc != tt.location {
+				t.Errorf("expected: [%s]; got: [%s]", tt.location, loc)
+			}
+		})
+	}
+}
+
+func Test_CaseInsensitiveServe(t *testing.T) {
+	var got map[string]string
+
+	mux := New(WithCaseInsensitiveServe())
+	mux.GET("/users/:name/*path", func(ctx context.Context, r *http.Request) error {
+		got = Params(ctx, r)
+		return nil
+	})
+
+	tests := []struct {
+		name string
+		path string
+		code int
+		want map[string]string
+	}{
+		{"Exact", "/users/bob/a", 200, map[string]string{"name": "bob", "path": "/a"}},
+		{"Served", "/USERS/Bob/Posts/1", 200, map[string]string{"name": "Bob", "path": "/Posts/1"}},
+		{"NotFound", "/ACCOUNTS/Bob/x", 404, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = nil
+
+			r := httptest.NewRequest("GET", tt.path, nil)
+			w := httptest.NewRecorder()
+
+			mux.ServeHTTP(w, r)
+
+			if w.Code != tt.code {
+				t.Errorf("expected: [%d]; got: [%d]", tt.code, w.Code)
+			}
+
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("expected: [%v]; got: [%v]", tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_CaseInsensitiveServeUnmatched(t *testing.T) {
+	var pattern, tag string
+	notFound := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pattern, tag = r.Pattern, r.PathValue("tag")
+		http.NotFound(w, r)
+	})
+
+	mux := New(WithCaseInsensitiveServe(), WithNotFoundHandler(notFound))
+	mux.GET("/tags/:tag([a-z]+)", respondWith("tag"))
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/TAGS/Go", nil))
+
+	if w.Code != 404 {
+		t.Errorf("expected: [%d]; got: [%d]", 404, w.Code)
+	}
+
+	// the lowercased match must not leak to the not found handler.
+	if pattern != "" || tag != "" {
+		t.Errorf("expected no match; got: [%s] [%s]", pattern, tag)
+	}
+}
+
+func Test_OptionsHandler(t *testing.T) {
+	mux := New(WithOptionsHandler(optHandler))
+
+	mux.GET("/unused", func(ctx context.Context, r *http.Request) error {
+		return InternalServerError(ctx, r)
+	})
+
+	r, _ := http.NewRequest("OPTIONS", "/unused", nil)
+	w := httptest.NewRecorder()
+
+	mux.ServeHTTP(w, r)
+	if w.Result().StatusCode != 204 {
+		t.Error("failed to fallback to options handler")
+	}
+}
+
+func Test_Stats(t *testing.T) {
+	h := func(ctx context.Context, r *http.Request) error { return nil }
+
+	mux := New()
+	if got := mux.Stats(); got.Routes != 0 || len(got.Methods) != 0 {
+		t.Errorf("expected empty stats; got: [%+v]", got)
+	}
+
+	mux.GET("/users", h)
+	mux.GET("/users/:id", h)
+	mux.GET("/users/:id/posts", h)
+	mux.GET("/about/:page?", h)
+	mux.POST("/users", h)
+
+	want := TreeStats{
+		Methods: map[string]MethodStats{
+			"GET":  {Nodes: 7, Leaves: 5, MaxDepth: 4, Routes: 4},
+			"POST": {Nodes: 2, Leaves: 1, MaxDepth: 1, Routes: 1},
+		},
+		Routes: 5,
+	}
+
+	got := mux.Stats()
+	if got.Routes != want.Routes || !maps.Equal(got.Methods, want.Methods) {
+		t.Errorf("expected: [%+v]; got: [%+v]", want, got)
+	}
+}
+
+func Test_MethodNotAllowed(t *testing.T) {
+	mux := New()
+
+	mux.GET("/unused", func(ctx context.Context, r *http.Request) error {
+		return InternalServerError(ctx, r)
+	})
+
+	r, _ := http.NewRequest("POST", "/unused", nil)
+	w := httptest.NewRecorder()
+
+	t.Log(mux.Routes())
+
+	mux.ServeHTTP(w, r)
+	if w.Result().StatusCode != 405 {
+		t.Errorf("failed to execute method not allowed handler: resp[%d]", w.Result().StatusCode)
+	}
+	allow := w.Result().Header.Get("Allow")
+	if allow != "GET, OPTIONS" {
+		t.Errorf("expected: [%v]; got: [%v]", "GET, OPTIONS", allow)
+	}
+}
+
+func Test_AllowedMethods(t *testing.T) {
+	mux := New(WithOptionsHandler(optHandler))
+	mux.OPTIONS("/options", respondWith("options"))
+	mux.HEAD("/head", respondWith(""))
+	mux.GET("/users/:id", respondWith("user"))
+	mux.PUT("/users/:name", respondWith("update"))
+	mux.DELETE("/users/:id/posts", respondWith("delete"))
+	mux.GET("/users/new/edit", respondWith("edit"))
+	mux.GET("/files/*path", respondWith("file"))
+	mux.POST("/files/new", respondWith("new"))
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		code   int
+		allow  string
+	}{
+		{"OnlyOptions", "GET", "/options", http.StatusMethodNotAllowed, "OPTIONS"},
+		{"OnlyHead", "GET", "/head", http.StatusMethodNotAllowed, "HEAD, OPTIONS"},
+		{"Params", "POST", "/users/42", http.StatusMethodNotAllowed, "GET, PUT, OPTIONS"},
+		{"NestedParams", "GET", "/users/42/posts", http.StatusMethodNotAllowed, "DELETE, OPTIONS"},
+		{"Wildcard", "POST", "/files/a/b", http.StatusMethodNotAllowed, "GET, OPTIONS"},
+		{"EmptyWildcard", "DELETE", "/files/", http.StatusMethodNotAllowed, "GET, OPTIONS"},
+		{"StaticShadowsParam", "POST", "/users/new", http.StatusMethodNotAllowed, "PUT, OPTIONS"},
+		{"OptionsRequest", "OPTIONS", "/users/42", http.StatusNoContent, "GET, PUT"},
+		{"Unknown", "GET", "/missing", http.StatusNotFound, ""},
+		{"UnknownNested", "GET", "/users/42/comments", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the result must not depend on the order trees are visited.
+			for range 10 {
+				w := httptest.NewRecorder()
+				mux.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+
+				if w.Code != tt.code {
+					t.Fatalf("expected: [%d]; got: [%d]", tt.code, w.Code)
+				}
+
+				if allow := w.Header().Get("Allow"); allow != tt.allow {
+					t.Fatalf("expected: [%s]; got: [%s]", tt.allow, allow)
+				}
+			}
+		})
+	}
+}
+
+func Test_SetAllowHeaderWithOptions(t *testing.T) {
+	mux := New(
+		WithOptionsHandler(optHandler),
+	)
+
+	mux.GET("/unused", func(ctx context.Context, r *http.Request) error {
+		return InternalServerError(ctx, r)
+	})
+
+	r, _ := http.NewRequest("POST", "/unused", nil)
+	w := httptest.NewRecorder()
+
+	mux.ServeHTTP(w, r)
+
+	res := w.Result().Header.Get("Allow")
+
+	// ordering isn't guaranteed.
+	if res != "OPTIONS, GET" && res != "GET, OPTIONS" {
+		t.Errorf("expected [%s]; got [%s]", "GET, OPTIONS", res)
+	}
+}
+
+func Test_SetRequestPattern(t *testing.T) {
+	mux := New()
+
+	mux.GET("/foo", func(ctx context.Context, r *http.Request) error {
+		GetWriter(ctx).WriteHeader(204)
+		return nil
+	})
+
+	r, _ := http.NewRequest("GET", "/foo", nil)
+	w := httptest.NewRecorder()
+
+	mux.ServeHTTP(w, r)
+	if r.Pattern != "/foo" {
+		t.Errorf("expected: [%v]; got[%v]", "/foo", r.Pattern)
+	}
+}
+
+func Test_RedirectCleanPath(t *testing.T) {
+	mux := New(WithRedirectCleanPath())
+
+	mux.GET("/redirect", func(ctx context.Context, r *http.Request) error {
+		GetWriter(ctx).WriteHeader(204)
+		return nil
+	})
+
+	r, _ := http.NewRequest("GET", "/../../redirect", nil)
+	w := httptest.NewRecorder()
+
+	mux.ServeHTTP(w, r)
+	if w.Result().StatusCode != 301 {
+		t.Errorf("failed redirect; got status code [%d]", w.Result().StatusCode)
+	}
+
+	loc := w.Result().Header.Get("Location")
+	if loc != "/redirect" {
+		t.Errorf("failed redirect; got location [%s]", loc)
+	}
+}
+
+func Test_HandlerFuncServeHTTPHandleError(t *testing.T) {
+	handler := HandlerFunc(func(ctx context.Context, r *http.Request) error {
+		return fmt.Errorf("woah there partner, you're routing too fast")
+	})
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, r)
+	if w.Result().StatusCode != 500 {
+		t.Errorf("failed to handle error; got status code [%d]", w.Result().StatusCode)
+	}
+}
+
+// ----------------------------------------------------------------------
+// Edge cases
+
+func Test_WildcardHandler(t *testing.T) {
+	mux := NewWithDefaults()
+	mux.GET("/*path", func(ctx context.Context, r *http.Request) error {
+		GetWriter(ctx).WriteHeader(204)
+		return nil
+	})
+
+	tests := []struct {
+		name string
+		path string
+		ok   bool
+	}{
+		{"Empty", "/", true},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest("GET", tt.path, nil)
+		t.Run(tt.name, func(t *testing.T) {
+			mux.ServeHTTP(w, r)
+
+			if w.Result().StatusCode != 204 && tt.ok {
+				t.Error("failed to route request")
+			}
+			t.Log(r.PathValue("path"))
+		})
+	}
+}
+
+func Test_WildcardValue(t *testing.T) {
+	mux := New()
+	for _, route := range []string{"/files/*file", "/users/:id/*rest", "/static*asset", "/*all"} {
+		mux.GET(route, func(ctx context.Context, r *http.Request) error {
+			GetWriter(ctx).Write([]byte(r.Pattern + " " + r.PathValue(route[strings.LastIndexByte(route, '*')+1:])))
+			return nil
+		})
+	}
+
+	tests := []struct {
+		name string
+		path string
+		body string
+	}{
+		{"Nested", "/files/css/site.css", "/files/*file /css/site.css"},
+		{"AfterParam", "/users/42/posts/1", "/users/:id/*rest /posts/1"},
+		{"MidSegment", "/staticlogo.png", "/static*asset /logo.png"},
+		{"Root", "/about", "/*all /about"},
+		{"Empty", "/files/", "/files/*file /"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+
+			if w.Body.String() != tt.body {
+				t.Errorf("expected: [%s]; got: [%s]", tt.body, w.Body.String())
+			}
+		})
+	}
+}
+
+func Test_HandleError(t *testing.T) {
+	scoped := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, name, http.StatusInternalServerError)
+		})
+	}
+
+	fail := func(ctx context.Context, r *http.Request) error {
+		return errors.New("failed")
+	}
+
+	mux := New(WithErrorHandler(scoped("global")))
+	mux.HandleError("/api", scoped("api"))
+	mux.GET("/api/users/:id", fail)
+	mux.GET("/apis", fail)
+	mux.GET("/home", fail)
+
+	admin := mux.Group("/api/admin")
+	admin.HandleError(scoped("admin"))
+	admin.GET("/", fail)
+
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{"Scoped", "/api/users/1", "api"},
+		{"Longest", "/api/admin/", "admin"},
+		{"SegmentBoundary", "/apis", "global"},
+		{"Global", "/home", "global"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+
+			if got := strings.TrimSpace(w.Body.String()); got != tt.want {
+				t.Errorf("expected: [%s]; got: [%s]", tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_HandleErrorCaseInsensitive(t *testing.T) {
+	fail := func(ctx context.Context, r *http.Request) error {
+		return errors.New("failed")
+	}
+
+	mux := New(WithCaseInsensitiveRouting())
+	mux.HandleError("/API", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "api", http.StatusTeapot)
+	}))
+	mux.HandleError("/admin", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "admin", http.StatusTeapot)
+	}))
+	mux.GET("/API/users", fail)
+	mux.GET("/Admin/Users", fail)
+	mux.GET("/Apis", fail)
+
+	tests := []struct {
+		name string
+		path string
+		code int
+		body string
+	}{
+		{"SameCase", "/api/users", 418, "api"},
+		{"RouteCase", "/admin/users", 418, "admin"},
+		{"SegmentBoundary", "/apis", 500, "Internal Server Error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+
+			if w.Code != tt.code {
+				t.Errorf("expected: [%d]; got: [%d]", tt.code, w.Code)
+			}
+
+			if got := strings.TrimSpace(w.Body.String()); got != tt.body {
+				t.Errorf("expected: [%s]; got: [%s]", tt.body, got)
+			}
+		})
+	}
+}
+
+func Test_AutoHEAD(t *testing.T) {
+	mux := New(WithAutoHEAD())
+	mux.GET("/foo", func(ctx context.Context, r *http.Request) error {
+		w := GetWriter(ctx)
+		w.Header().Set("X-Route", "get")
+		_, err := w.Write([]byte("hello"))
+		return err
+	})
+	mux.GET("/bar", func(ctx context.Context, r *http.Request) error {
+		return nil
+	})
+	mux.HEAD("/bar", func(ctx context.Context, r *http.Request) error {
+		GetWriter(ctx).Header().Set("X-Route", "head")
+		return nil
+	})
+	mux.POST("/baz", func(ctx context.Context, r *http.Request) error {
+		return nil
+	})
+
+	tests := []struct {
+		name   string
+		path   string
+		code   int
+		route  string
+		length string
+	}{
+		{"Auto", "/foo", 200, "get", "5"},
+		{"Explicit", "/bar", 200, "head", ""},
+		{"NotAllowed", "/baz", 405, "", ""},
+		{"NotFound", "/qux", 404, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest("HEAD", tt.path, nil))
+
+			if w.Code != tt.code {
+				t.Errorf("expected status: [%d]; got: [%d]", tt.code, w.Code)
+			}
+
+			if got := w.Header().Get("X-Route"); got != tt.route {
+				t.Errorf("expected route: [%s]; got: [%s]", tt.route, got)
+			}
+
+			if got := w.Header().Get("Content-Length"); got != tt.length {
+				t.Errorf("expected length: [%s]; got: [%s]", tt.length, got)
+			}
+
+			if tt.code == 200 && w.Body.Len() != 0 {
+				t.Errorf("expected empty body; got: [%s]", w.Body.String())
+			}
+		})
+	}
+
+	// GET routes advertise HEAD as allowed.
+	mux = New(WithAutoHEAD())
+	mux.GET("/foo", func(ctx context.Context, r *http.Request) error {
+		return nil
+	})
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("DELETE", "/foo", nil))
+	if allow := w.Header().Get("Allow"); !strings.Contains(allow, "HEAD") {
+		t.Errorf("expected HEAD in Allow header; got: [%s]", allow)
+	}
+}
+
+func Test_HandleAB(t *testing.T) {
+	variant := func(name string) HandlerFunc {
+		return func(ctx context.Context, r *http.Request) error {
+			_, err := GetWriter(ctx).Write([]byte(name))
+			return err
+		}
+	}
+
+	bucket := func(r *http.Request) string {
+		return r.Header.Get("X-Bucket")
+	}
+
+	mux := New()
+	mux.HandleAB("GET", "/home", bucket, map[string]HandlerFunc{
+		"":  variant("control"),
+		"b": variant("b"),
+	})
+	mux.HandleAB("GET", "/beta", bucket, map[string]HandlerFunc{
+		"b": variant("b"),
+	})
+
+	tests := []struct {
+		name   string
+		path   string
+		bucket string
+		code   int
+		body   string
+	}{
+		{"Variant", "/home", "b", 200, "b"},
+		{"Default", "/home", "", 200, "control"},
+		{"UnknownBucket", "/home", "z", 200, "control"},
+		{"NoDefault", "/beta", "z", 404, "Not Found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tt.path, nil)
+			r.Header.Set("X-Bucket", tt.bucket)
+			w := httptest.NewRecorder()
+
+			mux.ServeHTTP(w, r)
+
+			if w.Code != tt.code {
+				t.Errorf("expected status: [%d]; got: [%d]", tt.code, w.Code)
+			}
+
+			if w.Body.String() != tt.body {
+				t.Errorf("expected: [%s]; got: [%s]", tt.body, w.Body.String())
+			}
+		})
+	}
+}
+
+func Test_ConnectTrace(t *testing.T) {
+	h := func(ctx context.Context, r *http.Request) error {
+		GetWriter(ctx).WriteHeader(204)
+		return nil
+	}
+
+	mux := NewWithDefaults()
+	mux.Handle("CONNECT", "/tunnel", h)
+	mux.Handle("TRACE", "/trace", h)
+
+	// authority-form CONNECT request.
+	authority, _ := http.ReadRequest(bufio.NewReader(strings.NewReader(
+		"CONNECT example.com:443 HTTP/1.1\r\nHost: example.com:443\r\n\r\n",
+	)))
+
+	tests := []struct {
+		name string
+		r    *http.Request
+		code int
+	}{
+		{"Connect", httptest.NewRequest("CONNECT", "/tunnel", nil), 204},
+		{"ConnectNoRedirect", httptest.NewRequest("CONNECT", "/tunnel/", nil), 404},
+		{"ConnectAuthority", authority, 404},
+		{"Trace", httptest.NewRequest("TRACE", "/trace", nil), 204},
+		{"TraceRedirect", httptest.NewRequest("TRACE", "/trace/", nil), 308},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, tt.r)
+
+			if w.Code != tt.code {
+				t.Errorf("expected: [%d]; got: [%d]", tt.code, w.Code)
+			}
+		})
+	}
+}
+
+func Test_Reset(t *testing.T) {
+	mux := New(WithRedirectTrailingSlash())
+	mux.GET("/old", respondWith("old"))
+	mux.Host("api.example.com").GET("/", respondWith("api"))
+
+	// serve concurrently with the reset.
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/old", nil))
+			}
+		}()
+	}
+
+	mux.Reset()
+	wg.Wait()
+
+	mux.GET("/new", respondWith("new"))
+
+	tests := []struct {
+		name string
+		host string
+		path string
+		code int
+	}{
+		{"Removed", "", "/old", 404},
+		{"RemovedHost", "api.example.com", "/", 404},
+		{"Registered", "", "/new", 200},
+		{"OptionsKept", "", "/new/", 301},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tt.path, nil)
+			if tt.host != "" {
+				r.Host = tt.host
+			}
+			w := httptest.NewRecorder()
+
+			mux.ServeHTTP(w, r)
+
+			if w.Code != tt.code {
+				t.Errorf("expected: [%d]; got: [%d]", tt.code, w.Code)
+			}
+		})
+	}
+}
+
+func Test_Lookup(t *testing.T) {
+	mux := New(WithAutoHEAD())
+	mux.GET("/users/:id", respondWith("user"))
+	mux.GET("/files/*path", respondWith("file"))
+	mux.POST("/users", respondWith("create"))
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		found  bool
+		params map[string]string
+		body   string
+	}{
+		{"Params", "GET", "/users/42", true, map[string]string{"id": "42"}, "user"},
+		{"Wildcard", "GET", "/files/a/b", true, map[string]string{"path": "/a/b"}, "file"},
+		{"Static", "POST", "/users", true, nil, "create"},
+		{"AutoHEAD", "HEAD", "/users/1", true, map[string]string{"id": "1"}, "user"},
+		{"NotFound", "GET", "/missing", false, nil, ""},
+		{"NoTree", "DELETE", "/users/42", false, nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, params, found := mux.Lookup(tt.method, tt.path)
+			if found != tt.found {
+				t.Fatalf("expected found: [%v]; got: [%v]", tt.found, found)
+			}
+
+			if !maps.Equal(params, tt.params) {
+				t.Errorf("expected: [%v]; got: [%v]", tt.params, params)
+			}
+
+			if !found {
+				return
+			}
+
+			w := httptest.NewRecorder()
+			_ = h(&writerContext{Context: context.Background(), value: w}, httptest.NewRequest(tt.method, tt.path, nil))
+			if w.Body.String() != tt.body {
+				t.Errorf("expected: [%s]; got: [%s]", tt.body, w.Body.String())
+			}
+		})
+	}
+}
+
+func Test_LookupCaseInsensitive(t *testing.T) {
+	mux := New(WithCaseInsensitiveRouting())
+	mux.GET("/Users/:name", respondWith("user"))
+
+	_, params, found := mux.Lookup("GET", "/USERS/Bob")
+	if !found {
+		t.Fatalf("expected route to be found")
+	}
+
+	if want := map[string]string{"name": "Bob"}; !maps.Equal(params, want) {
+		t.Errorf("expected: [%v]; got: [%v]", want, params)
+	}
+}
+
+func Test_EmptyWildcard(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  []func(*Mux)
+		path  string
+		value string
+	}{
+		{"DefaultNoSlash", nil, "/files", "/"},
+		{"DefaultSlash", nil, "/files/", "/"},
+		{"DefaultFile", nil, "/files/a", "/a"},
+		{"EmptyNoSlash", []func(*Mux){WithEmptyWildcard()}, "/files", ""},
+		{"EmptySlash", []func(*Mux){WithEmptyWildcard()}, "/files/", ""},
+		{"EmptyFile", []func(*Mux){WithEmptyWildcard()}, "/files/a", "/a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := New(tt.opts...)
+
+			value := "unset"
+			mux.GET("/files/*file", func(ctx context.Context, r *http.Request) error {
+				value = r.PathValue("file")
+				return nil
+			})
+
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected: [%d]; got: [%d]", http.StatusOK, w.Code)
+			}
+
+			if value != tt.value {
+				t.Errorf("expected: [%q]; got: [%q]", tt.value, value)
+			}
+		})
+	}
+}
+
+func Test_DynamicRoutes(t *testing.T) {
+	mux := New(WithDynamicRoutes())
+	mux.GET("/static", respondWith("static"))
+
+	const n = 50
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		for i := range n {
+			mux.GET("/plugin/"+strconv.Itoa(i), respondWith(strconv.Itoa(i)))
+		}
+	}()
+
+	go func() {
+		defer wg.Done()
+		for range n {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest("GET", "/static", nil))
+			if w.Body.String() != "static" {
+				t.Errorf("expected: [static]; got: [%s]", w.Body.String())
+			}
+		}
+	}()
+
+	wg.Wait()
+
+	for i := range n {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", "/plugin/"+strconv.Itoa(i), nil))
+		if w.Body.String() != strconv.Itoa(i) {
+			t.Errorf("expected: [%d]; got: [%s]", i, w.Body.String())
+		}
+	}
+}
+
+func Test_DynamicRoutesSnapshot(t *testing.T) {
+	mux := New(WithDynamicRoutes())
+	mux.GET("/a", respondWith("a"))
+
+	// routes being served are never modified by a registration.
+	before := mux.routes.Load()
+	mux.GET("/b", respondWith("b"))
+
+	if _, found := before.trees["GET"].search([]byte("/b"), nil); found {
+		t.Error("expected registration to leave the previous routes unchanged")
+	}
+
+	// a failed registration leaves the routes as is.
+	func() {
+		defer func() { _ = recover() }()
+		mux.GET("/a", respondWith("dup"))
+	}()
+
+	for _, path := range []string{"/a", "/b"} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Body.String() != path[1:] {
+			t.Errorf("expected: [%s]; got: [%s]", path[1:], w.Body.String())
+		}
+	}
+
+	// the lock is released after a failed registration.
+	mux.GET("/c", respondWith("c"))
+}
+
+func Test_Unregister(t *testing.T) {
+	mux := New(WithDynamicRoutes())
+	mux.GET("/users", respondWith("users"))
+	mux.GET("/users/:id", respondWith("user"))
+	mux.DELETE("/users", respondWith("deleted"))
+
+	if mux.Unregister("GET", "/user") {
+		t.Error("expected unregistered route to return false")
+	}
+
+	if !mux.Unregister("GET", "/users") {
+		t.Fatal("expected registered route to return true")
+	}
+
+	if mux.Unregister("GET", "/users") {
+		t.Error("expected removed route to return false")
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		code   int
+		body   string
+		allow  string
+	}{
+		{"Removed", "GET", "/users", http.StatusMethodNotAllowed, "Method Not Allowed", "DELETE, OPTIONS"},
+		{"SharedPrefix", "GET", "/users/1", http.StatusOK, "user", ""},
+		{"OtherMethod", "DELETE", "/users", http.StatusOK, "deleted", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+
+			if w.Code != tt.code {
+				t.Errorf("expected: [%d]; got: [%d]", tt.code, w.Code)
+			}
+
+			if w.Body.String() != tt.body {
+				t.Errorf("expected: [%s]; got: [%s]", tt.body, w.Body.String())
+			}
+
+			if allow := w.Header().Get("Allow"); allow != tt.allow {
+				t.Errorf("expected: [%s]; got: [%s]", tt.allow, allow)
+			}
+		})
+	}
+
+	// removing the last route of a method removes its tree.
+	if !mux.Unregister("DELETE", "/users") {
+		t.Fatal("expected registered route to return true")
+	}
+
+	if _, ok := mux.routes.Load().trees["DELETE"]; ok {
+		t.Error("expected empty tree to be removed")
+	}
+}
+
+func Test_FallthroughHandler(t *testing.T) {
+	legacy := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Legacy-ID", r.PathValue("id"))
+		w.Header().Set("X-Legacy-Pattern", r.Pattern)
+		_, _ = w.Write([]byte("legacy"))
+	})
+
+	mux := New(WithFallthroughHandler(legacy), WithRedirectTrailingSlash())
+	mux.GET("/users/:id/posts", respondWith("posts"))
+	mux.GET("/about", respondWith("about"))
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		code   int
+		body   string
+	}{
+		{"RoxiRoute", "GET", "/users/42/posts", http.StatusOK, "posts"},
+		{"Unmatched", "GET", "/users/42/comments", http.StatusOK, "legacy"},
+		{"PartialMatch", "GET", "/users/42/posts/1", http.StatusOK, "legacy"},
+		{"UnmatchedMethod", "POST", "/about", http.StatusOK, "legacy"},
+		{"NoTree", "DELETE", "/users/42/posts", http.StatusOK, "legacy"},
+		{"RedirectFirst", "GET", "/about/", http.StatusMovedPermanently, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+
+			if w.Code != tt.code {
+				t.Errorf("expected: [%d]; got: [%d]", tt.code, w.Code)
+			}
+
+			if tt.body != "" && w.Body.String() != tt.body {
+				t.Errorf("expected: [%s]; got: [%s]", tt.body, w.Body.String())
+			}
+
+			// values captured while probing roxi routes must not leak.
+			if id := w.Header().Get("X-Legacy-ID"); id != "" {
+				t.Errorf("expected: []; got: [%s]", id)
+			}
+
+			if p := w.Header().Get("X-Legacy-Pattern"); p != "" {
+				t.Errorf("expected: []; got: [%s]", p)
+			}
+		})
+	}
+}
+
+func Test_ParamChars(t *testing.T) {
+	mux := New(WithParamChars('$', '~'))
+	mux.GET("/books/urn:isbn:$isbn", func(ctx context.Context, r *http.Request) error {
+		_, err := GetWriter(ctx).Write([]byte("isbn=" + r.PathValue("isbn")))
+		return err
+	})
+	mux.GET("/time/12:30", respondWith("static"))
+	mux.GET("/files/~path", func(ctx context.Context, r *http.Request) error {
+		_, err := GetWriter(ctx).Write([]byte("path=" + r.PathValue("path") + " prefix=" + MatchedPrefix(ctx)))
+		return err
+	})
+
+	tests := []struct {
+		name string
+		path string
+		code int
+		body string
+	}{
+		{"Param", "/books/urn:isbn:978", http.StatusOK, "isbn=978"},
+		{"StaticColon", "/time/12:30", http.StatusOK, "static"},
+		{"StaticColonMismatch", "/time/12:31", http.StatusNotFound, "Not Found"},
+		{"Wildcard", "/files/a/b*c", http.StatusOK, "path=/a/b*c prefix=/files"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+
+			if w.Code != tt.code {
+				t.Errorf("expected: [%d]; got: [%d]", tt.code, w.Code)
+			}
+
+			if w.Body.String() != tt.body {
+				t.Errorf("expected: [%s]; got: [%s]", tt.body, w.Body.String())
+			}
+		})
+	}
+}
+
+func Test_ParamCharsInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func()
+	}{
+		{"Slash", func() { WithParamChars('/', '*') }},
+		{"Optional", func() { WithParamChars(':', '?') }},
+		{"Equal", func() { WithParamChars('$', '$') }},
+		{"BadRoute", func() { New(WithParamChars('$', '~')).GET("/a/$b$c", respondWith("")) }},
+		{"Combine", func() { Combine(New(), New(WithParamChars('$', '~'))) }},
+		{"Mount", func() { New().Mount("/v1", New(WithParamChars('$', '~'))) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if rec := recover(); rec == nil {
+					t.Error("expected panic")
+				}
+			}()
+			tt.fn()
+		})
+	}
+}
+
+func Test_MaxParams(t *testing.T) {
+	manyParams := func(n int) string {
+		var b strings.Builder
+		for i := range n {
+			b.WriteString("/:p" + strconv.Itoa(i))
+		}
+		return b.String()
+	}
+
+	tests := []struct {
+		name   string
+		opts   []func(*Mux)
+		path   string
+		panics bool
+	}{
+		{"WithinLimit", []func(*Mux){WithMaxParams(2)}, "/:a/:b", false},
+		{"OverLimit", []func(*Mux){WithMaxParams(2)}, "/:a/:b/:c", true},
+		{"WildcardCounts", []func(*Mux){WithMaxParams(2)}, "/:a/:b/*rest", true},
+		{"OptionalCounts", []func(*Mux){WithMaxParams(2)}, "/:a/:b/:c?", true},
+		{"DefaultLimit", nil, manyParams(defaultMaxParams), false},
+		{"OverDefaultLimit", nil, manyParams(defaultMaxParams + 1), true},
+		{"NoLimit", []func(*Mux){WithMaxParams(0)}, manyParams(128), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if panicked := recover() != nil; panicked != tt.panics {
+					t.Errorf("expected panic: [%v]; got: [%v]", tt.panics, panicked)
+				}
+			}()
+
+			New(tt.opts...).GET(tt.path, emptyHandler)
+		})
+	}
+}
+
+func Test_TryHandle(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		h      HandlerFunc
+		err    error
+	}{
+		{"Registered", "GET", "/users/:id/posts", emptyHandler, nil},
+		{"InvalidMethod", "get", "/users", emptyHandler, ErrInvalidRoute},
+		{"EmptyPath", "GET", "", emptyHandler, ErrInvalidRoute},
+		{"NoSlash", "GET", "users", emptyHandler, ErrInvalidRoute},
+		{"NilHandler", "GET", "/users", nil, ErrInvalidRoute},
+		{"InvalidParam", "GET", "/users/:", emptyHandler, ErrInvalidRoute},
+		{"InvalidOptional", "GET", "/users/new?", emptyHandler, ErrInvalidRoute},
+		{"Exists", "GET", "/users/:id", emptyHandler, ErrRouteExists},
+		{"Conflict", "GET", "/users/:name/posts", emptyHandler, ErrRouteConflict},
+		{"OptionalConflict", "GET", "/users/:name?", emptyHandler, ErrRouteConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := New()
+			mux.GET("/users/:id", emptyHandler)
+
+			err := mux.TryHandle(tt.method, tt.path, tt.h)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected: [%v]; got: [%v]", tt.err, err)
+			}
+			if err == nil {
+				return
+			}
+
+			var rerr *RouteError
+			if !errors.As(err, &rerr) || rerr.Method != tt.method || rerr.Path != tt.path {
+				t.Errorf("expected: [%s %s]; got: [%v]", tt.method, tt.path, rerr)
+			}
+
+			// the routes are left unchanged.
+			want := map[string][]string{"GET": {"/users/:id"}}
+			if got := mux.Routes(); !maps.EqualFunc(got, want, slices.Equal) {
+				t.Errorf("expected: [%v]; got: [%v]", want, got)
+			}
+		})
+	}
+}
+
+func Test_TryHandleDynamicRoutes(t *testing.T) {
+	mux := New(WithDynamicRoutes())
+	mux.GET("/users/:id", emptyHandler)
+	before := mux.routes.Load()
+
+	if err := mux.TryHandle("GET", "/users/:id", emptyHandler); !errors.Is(err, ErrRouteExists) {
+		t.Fatalf("expected: [%v]; got: [%v]", ErrRouteExists, err)
+	}
+
+	if after := mux.routes.Load(); after != before {
+		t.Errorf("expected routes to be left unchanged")
+	}
+}
+
+func Test_CanRegister(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		err    error
+	}{
+		{"Registrable", "GET", "/users/:id/posts", nil},
+		{"NewMethod", "POST", "/users/:name", nil},
+		{"InvalidMethod", "get", "/users", ErrInvalidRoute},
+		{"NoSlash", "GET", "users", ErrInvalidRoute},
+		{"InvalidParam", "GET", "/users/:", ErrInvalidRoute},
+		{"Exists", "GET", "/users/:id", ErrRouteExists},
+		{"Conflict", "GET", "/users/:name/posts", ErrRouteConflict},
+		{"OptionalConflict", "GET", "/users/:name?", ErrRouteConflict},
+	}
+
+	for _, dynamic := range []bool{false, true} {
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				mux := New()
+				if dynamic {
+					mux = New(WithDynamicRoutes())
+				}
+				mux.GET("/users/:id", emptyHandler)
+
+				err := mux.CanRegister(tt.method, tt.path)
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected: [%v]; got: [%v]", tt.err, err)
+				}
+
+				var rerr *RouteError
+				if err != nil && (!errors.As(err, &rerr) || rerr.Method != tt.method || rerr.Path != tt.path) {
+					t.Errorf("expected: [%s %s]; got: [%v]", tt.method, tt.path, rerr)
+				}
+
+				// the routes are never modified.
+				want := map[string][]string{"GET": {"/users/:id"}}
+				if got := mux.Routes(); !maps.EqualFunc(got, want, slices.Equal) {
+					t.Errorf("expected: [%v]; got: [%v]", want, got)
+				}
+
+				// the result matches registering the route.
+				if terr := mux.TryHandle(tt.method, tt.path, emptyHandler); !errors.Is(terr, tt.err) {
+					t.Errorf("expected: [%v]; got: [%v]", tt.err, terr)
+				}
+			})
+		}
+	}
+}
+
+func Test_TryHandleOptional(t *testing.T) {
+	mux := New()
+	mux.GET("/teams", emptyHandler)
+
+	// the path with the variable is added before the path without it
+	// fails, and is removed again.
+	if err := mux.TryHandle("GET", "/teams/:id?", emptyHandler); !errors.Is(err, ErrRouteExists) {
+		t.Fatalf("expected: [%v]; got: [%v]", ErrRouteExists, err)
+	}
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/teams/5", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected: [%d]; got: [%d]", http.StatusNotFound, w.Code)
+	}
+
+	if err := mux.TryHandle("GET", "/teams/:id", emptyHandler); err != nil {
+		t.Errorf("expected: [<nil>]; got: [%v]", err)
+	}
+}
+
+func Test_Methods(t *testing.T) {
+	mux := New()
+	mux.Methods("GET", "POST").Handle("/search", func(ctx context.Context, r *http.Request) error {
+		GetWriter(ctx).Write([]byte(r.Method))
+		return nil
+	})
+
+	tests := []struct {
+		method string
+		code   int
+		body   string
+		allow  string
+	}{
+		{"GET", http.StatusOK, "GET", ""},
+		{"POST", http.StatusOK, "POST", ""},
+		{"PUT", http.StatusMethodNotAllowed, "", "GET, POST, OPTIONS"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(tt.method, "/search", nil))
+
+			if w.Code != tt.code {
+				t.Errorf("expected: [%d]; got: [%d]", tt.code, w.Code)
+			}
+			if tt.body != "" && w.Body.String() != tt.body {
+				t.Errorf("expected: [%s]; got: [%s]", tt.body, w.Body.String())
+			}
+			if allow := w.Header().Get("Allow"); allow != tt.allow {
+				t.Errorf("expected: [%s]; got: [%s]", tt.allow, allow)
+			}
+		})
+	}
+}
+
+func Test_MethodsInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		methods []string
+		msg     string
+	}{
+		{"Empty", nil, "methods cannot be empty"},
+		{"Lowercase", []string{"GET", "post"}, "method 'post' is not valid; did you mean 'POST'?"},
+		{"Unknown", []string{"GET", "panda"}, "method 'panda' is not a valid http method"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if rec := recover(); fmt.Sprint(rec) != tt.msg {
+					t.Errorf("expected: [%v]; got: [%v]", tt.msg, rec)
+				}
+			}()
+			New().Methods(tt.methods...)
+		})
+	}
+}
+
+func Test_MethodsRollback(t *testing.T) {
+	mux := New()
+	mux.PUT("/items", emptyHandler)
+
+	err := mux.Methods("GET", "POST", "PUT").TryHandle("/items", emptyHandler)
+	if !errors.Is(err, ErrRouteExists) {
+		t.Fatalf("expected: [%v]; got: [%v]", ErrRouteExists, err)
+	}
+
+	want := map[string][]string{"PUT": {"/items"}}
+	if got := mux.Routes(); !maps.EqualFunc(got, want, slices.Equal) {
+		t.Errorf("expected: [%v]; got: [%v]", want, got)
+	}
+}
+
+func Test_AutoOptions(t *testing.T) {
+	mux := New(WithOptionsHandler(AutoOptions()), WithAutoHEAD())
+	mux.GET("/users/:id", emptyHandler)
+	mux.DELETE("/users/:id", emptyHandler)
+	mux.Handle("TRACE", "/debug", emptyHandler)
+	mux.OPTIONS("/custom", func(ctx context.Context, r *http.Request) error {
+		GetWriter(ctx).WriteHeader(http.StatusOK)
+		return nil
+	})
+
+	tests := []struct {
+		name  string
+		path  string
+		code  int
+		allow string
+	}{
+		{"Registered", "/users/5", http.StatusNoContent, "GET, HEAD, DELETE, OPTIONS"},
+		{"Trace", "/debug", http.StatusNoContent, "OPTIONS, TRACE"},
+		{"OptionsRoute", "/custom", http.StatusOK, ""},
+		{"NotFound", "/missing", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest("OPTIONS", tt.path, nil))
+
+			if w.Code != tt.code {
+				t.Errorf("expected: [%d]; got: [%d]", tt.code, w.Code)
+			}
+			if allow := w.Header().Get("Allow"); allow != tt.allow {
+				t.Errorf("expected: [%s]; got: [%s]", tt.allow, allow)
+			}
+		})
+	}
+}
+
+func Test_PreMiddleware(t *testing.T) {
+	type key struct{}
+
+	var order []string
+	var codes []int
+	record := func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, r *http.Request) error {
+			order = append(order, "pre")
+			ctx, stats := WrapWriter(ctx)
+			err := next(ctx, r)
+			codes = append(codes, stats.status(err))
+			return err
+		}
+	}
+	withValue := func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, r *http.Request) error {
+			return next(context.WithValue(ctx, key{}, "pre"), r)
+		}
+	}
+	deny := func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, r *http.Request) error {
+			if r.Header.Get("X-Deny") != "" {
+				return Abort(http.StatusForbidden, nil)
+			}
+			return next(ctx, r)
+		}
+	}
+	route := func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, r *http.Request) error {
+			order = append(order, "route")
+			return next(ctx, r)
+		}
+	}
+
+	mux := New(
+		WithPreMiddleware(record, withValue, deny),
+		WithMiddleware(route),
+		WithOptionsHandler(AutoOptions()),
+	)
+	mux.GET("/users", func(ctx context.Context, r *http.Request) error {
+		v, _ := ctx.Value(key{}).(string)
+		GetWriter(ctx).Write([]byte(v))
+		return nil
+	})
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		deny   bool
+		code   int
+		body   string
+		order  []string
+	}{
+		{"Matched", "GET", "/users", false, 200, "pre", []string{"pre", "route"}},
+		{"NotFound", "GET", "/missing", false, 404, "Not Found", []string{"pre"}},
+		{"MethodNotAllowed", "POST", "/users", false, 405, "Method Not Allowed", []string{"pre"}},
+		{"Options", "OPTIONS", "/users", false, 204, "", []string{"pre"}},
+		{"Abort", "GET", "/users", true, 403, "Forbidden", []string{"pre"}},
 	}
 
 	for _, tt := range tests {
-		w := httptest.NewRecorder()
-		r, _ := http.NewRequest("GET", tt.path, nil)
 		t.Run(tt.name, func(t *testing.T) {
+			order, codes = nil, nil
+
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.deny {
+				r.Header.Set("X-Deny", "1")
+			}
+			w := httptest.NewRecorder()
 			mux.ServeHTTP(w, r)
 
-			if w.Result().StatusCode != 204 && tt.ok {
-				t.Error("failed to route request")
+			if w.Code != tt.code {
+				t.Errorf("expected: [%d]; got: [%d]", tt.code, w.Code)
+			}
+			if w.Body.String() != tt.body {
+				t.Errorf("expected: [%s]; got: [%s]", tt.body, w.Body.String())
+			}
+			if !slices.Equal(order, tt.order) {
+				t.Errorf("expected: %v; got: %v", tt.order, order)
+			}
+			if len(codes) != 1 || codes[0] != tt.code {
+				t.Errorf("expected recorded: [%d]; got: %v", tt.code, codes)
+			}
+		})
+	}
+}
+
+func Test_ErrorMapper(t *testing.T) {
+	errNotFound := errors.New("not found")
+	errForbidden := errors.New("forbidden")
+	errBroken := errors.New("broken")
+	errStream := errors.New("stream")
+
+	mux := New(
+		WithErrorMapper(func(err error) Responder {
+			switch {
+			case errors.Is(err, errNotFound):
+				return JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+			case errors.Is(err, errForbidden):
+				return textResponse("forbidden")
+			case errors.Is(err, errBroken):
+				return JSON(http.StatusTeapot, func() {})
+			case errors.Is(err, errStream):
+				return csvStream{rows: []string{"a,b", "c,d"}, failAfter: 1}
+			}
+			return nil
+		}),
+		WithErrorHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "error handler", http.StatusInternalServerError)
+		})),
+	)
+
+	tests := []struct {
+		name string
+		err  error
+		code int
+		body string
+	}{
+		{"Mapped", fmt.Errorf("user 42: %w", errNotFound), 404, `{"error":"user 42: not found"}`},
+		{"NoStatus", errForbidden, 500, "forbidden"},
+		{"Unmapped", errors.New("unknown"), 500, "error handler\n"},
+		{"EncodeError", errBroken, 500, "error handler\n"},
+		{"WriteError", errStream, 202, "a,b\n"},
+		{"Abort", Abort(http.StatusConflict, nil), 409, "Conflict"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux.Reset()
+			mux.GET("/", func(ctx context.Context, r *http.Request) error { return tt.err })
+
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+			if w.Code != tt.code {
+				t.Errorf("expected: [%d]; got: [%d]", tt.code, w.Code)
+			}
+			if w.Body.String() != tt.body {
+				t.Errorf("expected: [%s]; got: [%s]", tt.body, w.Body.String())
 			}
-			t.Log(r.PathValue("path"))
 		})
 	}
 }
+
+func Test_GetError(t *testing.T) {
+	errFailed := errors.New("failed")
+
+	var got []error
+	mux := New(WithErrorHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, GetError(r.Context()))
+		w.WriteHeader(http.StatusInternalServerError)
+	})))
+	mux.HandleError("/api", HandlerFunc(func(ctx context.Context, r *http.Request) error {
+		got = append(got, GetError(ctx))
+		return InternalServerError(ctx, r)
+	}))
+
+	fail := func(ctx context.Context, r *http.Request) error {
+		return fmt.Errorf("%s: %w", r.URL.Path, errFailed)
+	}
+	mux.GET("/home", fail)
+	mux.GET("/api/users", fail)
+
+	for _, path := range []string{"/home", "/api/users"} {
+		got = nil
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+
+		if len(got) != 1 || !errors.Is(got[0], errFailed) || got[0].Error() != path+": failed" {
+			t.Errorf("expected: [%s: failed]; got: %v", path, got)
+		}
+	}
+
+	if err := GetError(context.Background()); err != nil {
+		t.Errorf("expected: [<nil>]; got: [%v]", err)
+	}
+}
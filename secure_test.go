@@ -0,0 +1,77 @@
+// Copyright 2025 Brandon Epperson
+// SPDX-License-Identifier: Apache-2.0
+
+package roxi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_SecureHeaders(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []SecureOption
+		want map[string]string
+	}{
+		{
+			"Default", nil,
+			map[string]string{
+				"X-Content-Type-Options":    "nosniff",
+				"X-Frame-Options":           "DENY",
+				"Referrer-Policy":           "strict-origin-when-cross-origin",
+				"Strict-Transport-Security": "",
+				"Content-Security-Policy":   "",
+			},
+		},
+		{
+			"Configured",
+			[]SecureOption{
+				WithFrameOptions("SAMEORIGIN"),
+				WithReferrerPolicy("no-referrer"),
+				WithHSTS(365*24*time.Hour, true),
+				WithCSP("default-src 'self'"),
+			},
+			map[string]string{
+				"X-Content-Type-Options":    "nosniff",
+				"X-Frame-Options":           "SAMEORIGIN",
+				"Referrer-Policy":           "no-referrer",
+				"Strict-Transport-Security": "max-age=31536000; includeSubDomains",
+				"Content-Security-Policy":   "default-src 'self'",
+			},
+		},
+		{
+			"Omitted",
+			[]SecureOption{WithFrameOptions(""), WithReferrerPolicy(""), WithHSTS(time.Hour, false)},
+			map[string]string{
+				"X-Content-Type-Options":    "nosniff",
+				"X-Frame-Options":           "",
+				"Referrer-Policy":           "",
+				"Strict-Transport-Security": "max-age=3600",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := New(WithMiddleware(SecureHeaders(tt.opts...)))
+			mux.GET("/", func(ctx context.Context, r *http.Request) error {
+				// headers are set before the handler writes the response.
+				GetWriter(ctx).WriteHeader(http.StatusCreated)
+				return nil
+			})
+
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+			for k, v := range tt.want {
+				if h := w.Header().Get(k); h != v {
+					t.Errorf("expected %s: [%s]; got: [%s]", k, v, h)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2025 Brandon Epperson
+// SPDX-License-Identifier: Apache-2.0
+
+package roxi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// DefaultReadHeaderTimeout is the ReadHeaderTimeout of servers created by
+// NewServer and Serve unless set with WithReadHeaderTimeout.
+const DefaultReadHeaderTimeout = 10 * time.Second
+
+// DefaultShutdownTimeout is the grace period Serve gives open connections
+// to finish when shutting down, unless set with WithShutdownTimeout.
+const DefaultShutdownTimeout = 10 * time.Second
+
+// ServeOption configures the http.Server created by NewServer and Serve.
+type ServeOption func(*serveConfig)
+
+// serveConfig holds the server configured by ServeOptions, and how
+// Serve shuts it down.
+type serveConfig struct {
+	server          *http.Server
+	shutdownTimeout time.Duration
+}
+
+// WithReadHeaderTimeout sets the amount of time allowed to read request headers.
+//
+// A timeout of zero disables it, which leaves the server open to clients
+// that send headers slowly to hold connections open.
+func WithReadHeaderTimeout(d time.Duration) ServeOption {
+	return func(c *serveConfig) {
+		c.server.ReadHeaderTimeout = d
+	}
+}
+
+// WithShutdownTimeout sets the grace period Serve gives open connections
+// to finish once its context is done, after which they are closed.
+//
+// A timeout of zero waits for every connection to finish, so a single
+// hung connection blocks the shutdown. It has no effect on NewServer.
+func WithShutdownTimeout(d time.Duration) ServeOption {
+	return func(c *serveConfig) {
+		c.shutdownTimeout = d
+	}
+}
+
+// NewServer returns an http.Server listening on addr and serving requests with h.
+//
+// The server's ReadHeaderTimeout is set to DefaultReadHeaderTimeout.
+func NewServer(addr string, h http.Handler, opts ...ServeOption) *http.Server {
+	return newServeConfig(addr, h, opts).server
+}
+
+// newServeConfig returns the configuration of a server listening on addr
+// and serving requests with h.
+func newServeConfig(addr string, h http.Handler, opts []ServeOption) *serveConfig {
+	c := &serveConfig{
+		server: &http.Server{
+			Addr:              addr,
+			Handler:           h,
+			ReadHeaderTimeout: DefaultReadHeaderTimeout,
+		},
+		shutdownTimeout: DefaultShutdownTimeout,
+	}
+
+	for _, o := range opts {
+		o(c)
+	}
+	return c
+}
+
+// Serve listens on addr and serves requests with h until ctx is done,
+// after which the server is shut down gracefully.
+//
+// Open connections are given DefaultShutdownTimeout to finish, or the
+// grace period set with WithShutdownTimeout. Connections still open after
+// it are closed, and the context.DeadlineExceeded error is returned.
+//
+// The server is created with NewServer.
+func Serve(ctx context.Context, addr string, h http.Handler, opts ...ServeOption) error {
+	c := newServeConfig(addr, h, opts)
+	return c.serve(ctx, c.server.ListenAndServe)
+}
+
+// serve runs the server with listen until ctx is done, and then shuts
+// it down.
+func (c *serveConfig) serve(ctx context.Context, listen func() error) error {
+	s := c.server
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- listen()
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	sctx := context.WithoutCancel(ctx)
+	if c.shutdownTimeout > 0 {
+		var cancel context.CancelFunc
+		sctx, cancel = context.WithTimeout(sctx, c.shutdownTimeout)
+		defer cancel()
+	}
+
+	if err := s.Shutdown(sctx); err != nil {
+		// close the connections outliving the grace period.
+		_ = s.Close()
+		return err
+	}
+
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+// Copyright 2025 Brandon Epperson
+// SPDX-License-Identifier: Apache-2.0
+
+package roxi
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func Test_NewServer(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []ServeOption
+		want time.Duration
+	}{
+		{"Default", nil, DefaultReadHeaderTimeout},
+		{"ReadHeaderTimeout", []ServeOption{WithReadHeaderTimeout(time.Second)}, time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer(":8080", New(), tt.opts...)
+
+			if s.ReadHeaderTimeout != tt.want {
+				t.Errorf("expected: [%v]; got: [%v]", tt.want, s.ReadHeaderTimeout)
+			}
+		})
+	}
+}
+
+func Test_Serve(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- Serve(ctx, "127.0.0.1:0", New())
+	}()
+
+	cancel()
+
+	select {
+	case err := <-errc:
+		if err != nil {
+			t.Errorf("expected: [%v]; got: [%v]", nil, err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not shut down")
+	}
+}
+
+func Test_ServeShutdownTimeout(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hung := make(chan struct{})
+	defer close(hung)
+
+	started := make(chan struct{})
+	mux := New()
+	mux.GET("/hang", func(ctx context.Context, r *http.Request) error {
+		close(started)
+		<-hung
+		return nil
+	})
+
+	c := newServeConfig("", mux, []ServeOption{WithShutdownTimeout(50 * time.Millisecond)})
+	ctx, cancel := context.WithCancel(context.Background())
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- c.serve(ctx, func() error { return c.server.Serve(l) })
+	}()
+
+	go func() {
+		resp, err := http.Get("http://" + l.Addr().String() + "/hang")
+		if err == nil {
+			resp.Body.Close()
+		}
+	}()
+
+	<-started
+	cancel()
+
+	select {
+	case err := <-errc:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected: [%v]; got: [%v]", context.DeadlineExceeded, err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not shut down")
+	}
+}
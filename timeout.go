@@ -0,0 +1,251 @@
+// Copyright 2025 Brandon Epperson
+// SPDX-License-Identifier: Apache-2.0
+
+package roxi
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Timeout returns a middleware that cancels the handler's context after d.
+//
+// If the handler has not returned by then, 503 Service Unavailable is
+// written, unless the handler already wrote the response header, and later
+// writes by the handler are dropped, returning http.ErrHandlerTimeout.
+//
+// The handler runs in its own goroutine with a context that is not reused
+// by the mux, so it may safely finish after the request completes. The
+// mux then leaves the request body and multipart form to the handler, and
+// the form's temporary files are removed once it returns. Requests whose
+// context is already done are not handled.
+//
+// As with http.TimeoutHandler, the handler's writer can't be flushed or
+// hijacked, so Timeout should not wrap streaming or websocket routes.
+func Timeout(d time.Duration) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, r *http.Request) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			w := GetWriter(ctx)
+			tw := &timeoutWriter{w: w, h: make(http.Header)}
+
+			// derive from a context that outlives the pooled one.
+			parent := ctx
+			tctx := &writerContext{value: tw}
+			if v, ok := ctx.(*writerContext); ok {
+				parent = v.Context
+				tctx.mux = v.mux
+				tctx.req = v.req
+				tctx.depth = v.depth
+				tctx.values = append([]ctxValue(nil), v.values...)
+			}
+
+			cctx, cancel := context.WithTimeout(parent, d)
+			defer cancel()
+			tctx.Context = cctx
+
+			done := make(chan error, 1)
+			panicked := make(chan any, 1)
+			hr := r.WithContext(cctx)
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						removeMultipart(hr)
+						panicked <- p
+					}
+				}()
+
+				err := next(tctx, hr)
+				removeMultipart(hr)
+				done <- err
+			}()
+
+			select {
+			case err := <-done:
+				return err
+			case p := <-panicked:
+				panic(p)
+			case <-cctx.Done():
+				tw.timeout()
+				if v, ok := ctx.(*writerContext); ok {
+					v.detached = true
+				}
+				return nil
+			}
+		}
+	}
+}
+
+// RouteOption configures a single route, see Route and Mux.With.
+//
+// Unlike a MiddlewareFunc, a route option applies to the handler alone:
+// it doesn't wrap the route's middleware and isn't counted by
+// MiddlewareDepth.
+type RouteOption func(*routeConfig)
+
+// routeConfig holds the options of a route applied with Route.
+type routeConfig struct {
+	timeout  time.Duration
+	priority int
+}
+
+// newRouteConfig returns the configuration of a route with the options.
+func newRouteConfig(opts []RouteOption) routeConfig {
+	var cfg routeConfig
+	for _, o := range opts {
+		o(&cfg)
+	}
+	return cfg
+}
+
+// Route returns the handler configured with the route options, to be
+// registered with any of the mux's methods alongside its middleware:
+//
+//	mux.POST("/upload", roxi.Route(upload, roxi.WithRouteTimeout(30*time.Second)), auth)
+//
+// Options that affect how the route is matched, such as WithPriority,
+// only apply to routes registered with Mux.With.
+func Route(handler HandlerFunc, opts ...RouteOption) HandlerFunc {
+	if handler == nil {
+		return nil
+	}
+
+	cfg := newRouteConfig(opts)
+	if cfg.timeout > 0 {
+		handler = routeTimeout(handler, cfg.timeout)
+	}
+	return handler
+}
+
+// WithRouteTimeout returns a route option that sets a deadline of d on
+// the context of a route's handler, for routes that need more or less
+// time than the rest of the mux.
+//
+// Unlike Timeout, the handler runs as usual and nothing is written when
+// the deadline passes, it's up to the handler to return once the context
+// is done. The route's middleware don't receive the deadline.
+func WithRouteTimeout(d time.Duration) RouteOption {
+	return func(cfg *routeConfig) {
+		cfg.timeout = d
+	}
+}
+
+// WithPriority returns a route option that sets the priority of a route
+// registered with Mux.With, to choose between routes that both match
+// a request.
+//
+// Routes may be registered at the same path when they have different
+// priorities, as long as their path variables are the same and only
+// their constraints differ. A request is served by the route with the
+// highest priority whose constraints match:
+//
+//	mux.With(roxi.WithPriority(1)).Handle("GET", `/posts/:id(\d+)`, byID)
+//	mux.Handle("GET", `/posts/:id([a-z0-9-]+)`, bySlug)
+//
+// Routes have a priority of 0 by default. Routes ending in an optional
+// path variable can't share their path.
+func WithPriority(n int) RouteOption {
+	return func(cfg *routeConfig) {
+		cfg.priority = n
+	}
+}
+
+// routeTimeout wraps a handler to set a deadline of d on its context.
+func routeTimeout(next HandlerFunc, d time.Duration) HandlerFunc {
+	return func(ctx context.Context, r *http.Request) error {
+		v, ok := ctx.(*writerContext)
+		if !ok {
+			cctx, cancel := context.WithTimeout(ctx, d)
+			defer cancel()
+			return next(cctx, r)
+		}
+
+		// swap the deadline into the mux's context in place, so values
+		// and the writer remain available to the handler.
+		parent := v.Context
+		cctx, cancel := context.WithTimeout(parent, d)
+		defer cancel()
+
+		v.Context = cctx
+		defer func() { v.Context = parent }()
+
+		return next(v, r)
+	}
+}
+
+// timeoutWriter is a http.ResponseWriter that drops writes after
+// the handler has timed out.
+type timeoutWriter struct {
+	mu sync.Mutex
+	w  http.ResponseWriter
+
+	// h is the handler's header, copied to w when it's written,
+	// so the handler never touches w's header after a timeout.
+	h           http.Header
+	wroteHeader bool
+	timedOut    bool
+}
+
+// Header implements the http.ResponseWriter interface.
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.h
+}
+
+// WriteHeader implements the http.ResponseWriter interface.
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut || tw.wroteHeader {
+		return
+	}
+	tw.writeHeader(code)
+}
+
+func (tw *timeoutWriter) writeHeader(code int) {
+	tw.wroteHeader = true
+
+	dst := tw.w.Header()
+	for k, v := range tw.h {
+		dst[k] = v
+	}
+	tw.w.WriteHeader(code)
+}
+
+// Write implements the http.ResponseWriter interface.
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+
+	if !tw.wroteHeader {
+		tw.writeHeader(http.StatusOK)
+	}
+	return tw.w.Write(b)
+}
+
+// timeout marks the writer timed out, and writes 503 Service Unavailable
+// if the header has not been written.
+func (tw *timeoutWriter) timeout() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	tw.timedOut = true
+	if tw.wroteHeader {
+		return
+	}
+
+	tw.wroteHeader = true
+	_ = respond(tw.w, http.StatusServiceUnavailable, &errorResponse{
+		http.StatusServiceUnavailable,
+		http.StatusText(http.StatusServiceUnavailable),
+	})
+}
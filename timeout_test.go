@@ -0,0 +1,260 @@
+// Copyright 2025 Brandon Epperson
+// SPDX-License-Identifier: Apache-2.0
+
+package roxi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Timeout(t *testing.T) {
+	lateErr := make(chan error, 1)
+
+	mux := New(WithPanicHandler(func(ctx context.Context, r *http.Request, err any) {
+		GetWriter(ctx).WriteHeader(http.StatusInternalServerError)
+	}))
+	mux.GET("/fast", func(ctx context.Context, r *http.Request) error {
+		GetWriter(ctx).Header().Set("X-Handler", "fast")
+		_, err := GetWriter(ctx).Write([]byte("done"))
+		return err
+	}, Timeout(time.Second))
+	mux.GET("/slow", func(ctx context.Context, r *http.Request) error {
+		<-ctx.Done()
+		// wait for the timeout response to be written.
+		time.Sleep(10 * time.Millisecond)
+		_, err := GetWriter(ctx).Write([]byte("late"))
+		lateErr <- err
+		return err
+	}, Timeout(10*time.Millisecond))
+	mux.GET("/panic", func(ctx context.Context, r *http.Request) error {
+		panic("at the disco")
+	}, Timeout(time.Second))
+
+	tests := []struct {
+		name string
+		path string
+		code int
+		body string
+	}{
+		{"Fast", "/fast", 200, "done"},
+		{"Slow", "/slow", 503, "Service Unavailable"},
+		{"Panic", "/panic", 500, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+
+			if w.Code != tt.code {
+				t.Errorf("expected status: [%d]; got: [%d]", tt.code, w.Code)
+			}
+
+			if w.Body.String() != tt.body {
+				t.Errorf("expected: [%s]; got: [%s]", tt.body, w.Body.String())
+			}
+		})
+	}
+
+	select {
+	case err := <-lateErr:
+		if !errors.Is(err, http.ErrHandlerTimeout) {
+			t.Errorf("expected: [%v]; got: [%v]", http.ErrHandlerTimeout, err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("slow handler did not return")
+	}
+}
+
+func Test_TimeoutCanceledParent(t *testing.T) {
+	var called bool
+
+	mux := New()
+	mux.GET("/", func(ctx context.Context, r *http.Request) error {
+		called = true
+		return nil
+	}, Timeout(time.Second))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil).WithContext(ctx))
+
+	if called {
+		t.Error("handler called with canceled context")
+	}
+}
+
+func Test_TimeoutBody(t *testing.T) {
+	served := make(chan struct{})
+	read := make(chan string, 1)
+
+	mux := New(WithDrainBody())
+	mux.POST("/upload", func(ctx context.Context, r *http.Request) error {
+		<-ctx.Done()
+		<-served
+
+		b, _ := io.ReadAll(r.Body)
+		read <- string(b)
+		return nil
+	}, Timeout(10*time.Millisecond))
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("POST", "/upload", strings.NewReader("payload")))
+	close(served)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected: [%d]; got: [%d]", http.StatusServiceUnavailable, w.Code)
+	}
+
+	// the mux must not drain the body of a handler still running.
+	if got := <-read; got != "payload" {
+		t.Errorf("expected: [%s]; got: [%s]", "payload", got)
+	}
+}
+
+func Test_WithRouteTimeout(t *testing.T) {
+	type result struct {
+		deadline bool
+		remain   time.Duration
+	}
+
+	var got result
+	var depth int
+	h := func(ctx context.Context, r *http.Request) error {
+		dl, ok := ctx.Deadline()
+		got = result{ok, time.Until(dl)}
+		depth = MiddlewareDepth(ctx)
+		_, err := GetWriter(ctx).Write([]byte("ok"))
+		return err
+	}
+
+	mux := New()
+	mux.POST("/upload", Route(h, WithRouteTimeout(30*time.Second)))
+	mux.GET("/fast", Route(h))
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		deadline bool
+	}{
+		{"RouteTimeout", "POST", "/upload", true},
+		{"NoTimeout", "GET", "/fast", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = result{}
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+
+			if w.Body.String() != "ok" {
+				t.Errorf("expected: [ok]; got: [%s]", w.Body.String())
+			}
+
+			if got.deadline != tt.deadline {
+				t.Fatalf("expected deadline: [%v]; got: [%v]", tt.deadline, got.deadline)
+			}
+
+			if tt.deadline && (got.remain <= 29*time.Second || got.remain > 30*time.Second) {
+				t.Errorf("expected a deadline in 30s; got: [%v]", got.remain)
+			}
+
+			// route options are not middleware.
+			if depth != 0 {
+				t.Errorf("expected: [0]; got: [%d]", depth)
+			}
+		})
+	}
+}
+
+func Test_WithRouteTimeoutRestore(t *testing.T) {
+	parent := context.Background()
+	ctx := &writerContext{Context: parent, value: httptest.NewRecorder()}
+
+	h := Route(func(ctx context.Context, r *http.Request) error {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("expected handler context to have a deadline")
+		}
+		return nil
+	}, WithRouteTimeout(time.Second))
+
+	_ = h(ctx, httptest.NewRequest("GET", "/", nil))
+
+	if ctx.Context != parent {
+		t.Error("expected context to be restored after the handler returns")
+	}
+}
+
+func Test_WithPriority(t *testing.T) {
+	var pattern string
+	h := func(body string) HandlerFunc {
+		return func(ctx context.Context, r *http.Request) error {
+			pattern = r.Pattern
+			_, err := GetWriter(ctx).Write([]byte(body))
+			return err
+		}
+	}
+
+	mux := New(WithDynamicRoutes())
+	mux.With(WithPriority(1)).Handle("GET", `/posts/:id(\d+)`, h("id"))
+	mux.With(WithPriority(2)).Handle("GET", `/posts/:id([0-9a-f]+)`, h("hex"))
+	mux.Handle("GET", `/posts/:id([a-z0-9-]+)`, h("slug"))
+
+	// routes sharing a path need their own priority and constraints.
+	if err := mux.With(WithPriority(2)).TryHandle("GET", `/posts/:id([a-z]+)`, h("")); !errors.Is(err, ErrRouteExists) {
+		t.Errorf("expected: [%v]; got: [%v]", ErrRouteExists, err)
+	}
+
+	if err := mux.With(WithPriority(3)).TryHandle("GET", `/posts/:id(\d+)`, h("")); !errors.Is(err, ErrRouteExists) {
+		t.Errorf("expected: [%v]; got: [%v]", ErrRouteExists, err)
+	}
+
+	tests := []struct {
+		name       string
+		unregister string
+		path       string
+		code       int
+		body       string
+		pattern    string
+	}{
+		{"Highest", "", "/posts/123", 200, "hex", `/posts/:id([0-9a-f]+)`},
+		{"Lowest", "", "/posts/hello-world", 200, "slug", `/posts/:id([a-z0-9-]+)`},
+		{"NoMatch", "", "/posts/HELLO", 404, "Not Found", ""},
+		{"UnregisterHighest", `/posts/:id([0-9a-f]+)`, "/posts/123", 200, "id", `/posts/:id(\d+)`},
+		{"UnregisterLowest", `/posts/:id([a-z0-9-]+)`, "/posts/hello-world", 404, "Not Found", ""},
+		{"UnregisterLast", `/posts/:id(\d+)`, "/posts/123", 404, "Not Found", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.unregister != "" && !mux.Unregister("GET", tt.unregister) {
+				t.Fatalf("expected route [%s] to be unregistered", tt.unregister)
+			}
+
+			pattern = ""
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+
+			if w.Code != tt.code {
+				t.Errorf("expected: [%d]; got: [%d]", tt.code, w.Code)
+			}
+
+			if w.Body.String() != tt.body {
+				t.Errorf("expected: [%s]; got: [%s]", tt.body, w.Body.String())
+			}
+
+			if pattern != tt.pattern {
+				t.Errorf("expected: [%s]; got: [%s]", tt.pattern, pattern)
+			}
+		})
+	}
+}
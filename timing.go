@@ -0,0 +1,224 @@
+// Copyright 2025 Brandon Epperson
+// SPDX-License-Identifier: Apache-2.0
+
+package roxi
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Timings collects durations of the phases of handling a request, which
+// are written to the Server-Timing response header by ServerTimingMiddleware.
+type Timings struct {
+	mu      sync.Mutex
+	metrics []timing
+}
+
+type timing struct {
+	name string
+	dur  time.Duration
+}
+
+// Record adds a phase with the given name and duration.
+//
+// Record is a no-op on a nil *Timings, so handlers don't need to check
+// whether ServerTimingMiddleware is in use.
+func (t *Timings) Record(name string, d time.Duration) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	t.metrics = append(t.metrics, timing{name, d})
+	t.mu.Unlock()
+}
+
+// header returns the recorded phases in the Server-Timing header format,
+// with durations in milliseconds:
+//
+//	db;dur=53.2, render;dur=12
+func (t *Timings) header() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var b strings.Builder
+	for i, m := range t.metrics {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(m.name)
+		b.WriteString(";dur=")
+		b.WriteString(strconv.FormatFloat(float64(m.dur)/float64(time.Millisecond), 'f', -1, 64))
+	}
+	return b.String()
+}
+
+// ServerTiming returns the Timings of the request, or nil if the handler
+// is not wrapped with ServerTimingMiddleware.
+func ServerTiming(ctx context.Context) *Timings {
+	w := GetWriter(ctx)
+	for w != nil {
+		if tw, ok := w.(*timingWriter); ok {
+			return &tw.timings
+		}
+
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			break
+		}
+		w = u.Unwrap()
+	}
+	return nil
+}
+
+// ServerTimingMiddleware collects the Timings recorded by the handler and
+// writes them to the Server-Timing header before the response header is sent.
+//
+// If the handler returns an error without writing the header, the timings
+// are set on the header of the error response. Timings recorded after the
+// handler writes the header are dropped. Handlers called without a writer
+// in their context are called as is.
+func ServerTimingMiddleware(next HandlerFunc) HandlerFunc {
+	return func(ctx context.Context, r *http.Request) error {
+		w := GetWriter(ctx)
+		if w == nil {
+			return next(ctx, r)
+		}
+
+		tw := &timingWriter{ResponseWriter: w}
+		ctx = SetWriter(ctx, tw)
+		defer SetWriter(ctx, w)
+
+		// the header is shared with w, so the timings are sent with
+		// any response written after the handler returns an error.
+		err := next(ctx, r)
+		tw.writeTimings()
+		return err
+	}
+}
+
+// timingWriter is a http.ResponseWriter that writes the Server-Timing
+// header before the response header.
+type timingWriter struct {
+	http.ResponseWriter
+	timings     Timings
+	wroteHeader bool
+	hijacked    bool
+}
+
+// writeTimings sets the Server-Timing header if the response
+// header has not been written.
+func (w *timingWriter) writeTimings() {
+	if w.wroteHeader || w.hijacked {
+		return
+	}
+	w.wroteHeader = true
+
+	if v := w.timings.header(); v != "" {
+		w.Header().Set("Server-Timing", v)
+	}
+}
+
+// WriteHeader implements the http.ResponseWriter interface.
+func (w *timingWriter) WriteHeader(code int) {
+	if w.hijacked {
+		return
+	}
+
+	w.writeTimings()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write implements the http.ResponseWriter interface.
+func (w *timingWriter) Write(b []byte) (int, error) {
+	if w.hijacked {
+		return 0, http.ErrHijacked
+	}
+
+	w.writeTimings()
+	return w.ResponseWriter.Write(b)
+}
+
+// Hijack implements the http.Hijacker interface.
+func (w *timingWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil {
+		w.hijacked = true
+	}
+	return conn, rw, err
+}
+
+// Unwrap returns the underlying http.ResponseWriter for use
+// with http.ResponseController.
+func (w *timingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// ----------------------------------------------------------------------
+// match timing
+
+// matchBounds are the upper bounds of the route matching latency buckets.
+var matchBounds = [...]time.Duration{
+	250 * time.Nanosecond,
+	500 * time.Nanosecond,
+	time.Microsecond,
+	2 * time.Microsecond,
+	5 * time.Microsecond,
+	10 * time.Microsecond,
+	50 * time.Microsecond,
+	100 * time.Microsecond,
+}
+
+// LatencyBucket is a bucket of the route matching latency histogram,
+// see Mux.MatchLatency.
+type LatencyBucket struct {
+	// Upper is the inclusive upper bound of the bucket. It is zero
+	// for the last bucket, which has no upper bound.
+	Upper time.Duration
+
+	// Count is the number of requests matched within the bucket.
+	Count uint64
+}
+
+// matchHistogram counts route matching latencies.
+type matchHistogram struct {
+	counts [len(matchBounds) + 1]atomic.Uint64
+}
+
+// record adds a sample to the histogram.
+func (h *matchHistogram) record(d time.Duration) {
+	i := 0
+	for i < len(matchBounds) && d > matchBounds[i] {
+		i++
+	}
+	h.counts[i].Add(1)
+}
+
+// MatchLatency returns a snapshot of the histogram of time spent matching
+// requests against the registered routes, excluding the handler, if the
+// mux was created with WithMatchTiming. Otherwise it returns nil.
+//
+// Buckets are ordered by their upper bound, and counts accumulate from
+// the creation of the mux.
+func (m *Mux) MatchLatency() []LatencyBucket {
+	if m.matchTiming == nil {
+		return nil
+	}
+
+	buckets := make([]LatencyBucket, len(m.matchTiming.counts))
+	for i := range buckets {
+		if i < len(matchBounds) {
+			buckets[i].Upper = matchBounds[i]
+		}
+		buckets[i].Count = m.matchTiming.counts[i].Load()
+	}
+	return buckets
+}
@@ -0,0 +1,144 @@
+// Copyright 2025 Brandon Epperson
+// SPDX-License-Identifier: Apache-2.0
+
+package roxi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_ServerTiming(t *testing.T) {
+	tests := []struct {
+		name    string
+		timings []timing
+		write   bool
+		err     error
+		want    string
+	}{
+		{"None", nil, false, nil, ""},
+		{"Single", []timing{{"db", 53200 * time.Microsecond}}, false, nil, "db;dur=53.2"},
+		{"Multiple", []timing{{"db", 53200 * time.Microsecond}, {"render", 12 * time.Millisecond}}, true, nil, "db;dur=53.2, render;dur=12"},
+		{"Error", []timing{{"db", 5 * time.Millisecond}}, false, errors.New("failed"), "db;dur=5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := New()
+			mux.GET("/", func(ctx context.Context, r *http.Request) error {
+				st := ServerTiming(ctx)
+				for _, m := range tt.timings {
+					st.Record(m.name, m.dur)
+				}
+
+				if tt.write {
+					_, err := GetWriter(ctx).Write([]byte("body"))
+					// recorded after the header is written.
+					st.Record("late", time.Second)
+					return err
+				}
+				return tt.err
+			}, ServerTimingMiddleware)
+
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+			if got := w.Header().Get("Server-Timing"); got != tt.want {
+				t.Errorf("expected: [%s]; got: [%s]", tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_ServerTimingWithoutWriter(t *testing.T) {
+	var called bool
+	h := ServerTimingMiddleware(func(ctx context.Context, r *http.Request) error {
+		called = true
+		ServerTiming(ctx).Record("db", time.Second)
+		return nil
+	})
+
+	// must not panic.
+	if err := h(context.Background(), httptest.NewRequest("GET", "/", nil)); err != nil {
+		t.Errorf("expected: [%v]; got: [%v]", nil, err)
+	}
+
+	if !called {
+		t.Error("expected handler to be called")
+	}
+}
+
+func Test_ServerTimingWithoutMiddleware(t *testing.T) {
+	ctx := &writerContext{Context: context.Background(), value: httptest.NewRecorder()}
+
+	st := ServerTiming(ctx)
+	if st != nil {
+		t.Errorf("expected: [%v]; got: [%v]", nil, st)
+	}
+
+	// must not panic.
+	st.Record("db", time.Second)
+}
+
+func Test_MatchLatency(t *testing.T) {
+	if New().MatchLatency() != nil {
+		t.Error("expected nil histogram without WithMatchTiming")
+	}
+
+	mux := New(WithMatchTiming())
+	mux.GET("/users/:id", func(ctx context.Context, r *http.Request) error { return nil })
+
+	paths := []string{"/users/1", "/users/2", "/missing"}
+	for _, path := range paths {
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	buckets := mux.MatchLatency()
+	if len(buckets) != len(matchBounds)+1 {
+		t.Fatalf("expected: [%d] buckets; got: [%d]", len(matchBounds)+1, len(buckets))
+	}
+
+	var total uint64
+	for i, b := range buckets {
+		total += b.Count
+		if i > 0 && i < len(matchBounds) && b.Upper <= buckets[i-1].Upper {
+			t.Errorf("expected bucket [%d] bounds to increase; got: [%v]", i, b.Upper)
+		}
+	}
+
+	if last := buckets[len(buckets)-1].Upper; last != 0 {
+		t.Errorf("expected: [0]; got: [%v]", last)
+	}
+
+	if total != uint64(len(paths)) {
+		t.Errorf("expected: [%d] samples; got: [%d]", len(paths), total)
+	}
+}
+
+func Test_MatchHistogramRecord(t *testing.T) {
+	tests := []struct {
+		name   string
+		d      time.Duration
+		bucket int
+	}{
+		{"Zero", 0, 0},
+		{"Bound", 250 * time.Nanosecond, 0},
+		{"AboveBound", 251 * time.Nanosecond, 1},
+		{"Overflow", time.Second, len(matchBounds)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var h matchHistogram
+			h.record(tt.d)
+
+			if got := h.counts[tt.bucket].Load(); got != 1 {
+				t.Errorf("expected: [1]; got: [%d]", got)
+			}
+		})
+	}
+}
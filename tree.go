@@ -10,7 +10,9 @@ import (
 	"fmt"
 	"net/http"
 	"path/filepath"
+	"regexp"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 )
@@ -50,6 +52,19 @@ func (e edges) add(n edge) edges {
 	return e
 }
 
+// remove deletes the edge with the label from the slice.
+func (e edges) remove(label byte) edges {
+	l := len(e)
+	idx := e.binarySearch(l, label)
+	if idx == l || e[idx].label != label {
+		return e
+	}
+
+	copy(e[idx:], e[idx+1:])
+	e[l-1] = edge{}
+	return e[:l-1]
+}
+
 // binarySearch is copied from sort.Search so the function
 // call can be inlined.
 func (e edges) binarySearch(n int, label byte) int {
@@ -82,19 +97,171 @@ type node struct {
 	value   HandlerFunc
 	edges   edges
 	allowed methodFlag
+
+	// implicit marks a leaf inserted for a route whose optional
+	// final path segment is absent.
+	implicit bool
+
+	// constraints on the path variables captured by a leaf.
+	constraints []constraint
+
+	// priority orders the routes sharing a leaf, see share.
+	priority int
+
+	// shared holds the routes sharing the key of a leaf with a lower
+	// priority than its own, highest first.
+	shared []*node
+
+	// config is set on the root node.
+	config treeConfig
 }
 
-// insert inserts a new key value pair into the tree.
+// treeConfig configures how a tree parses routes and request paths.
+type treeConfig struct {
+	// param and wildcard are the characters that start
+	// path variables and wildcards.
+	param    byte
+	wildcard byte
+
+	// emptyWildcard captures an empty wildcard remainder
+	// as "" instead of "/".
+	emptyWildcard bool
+
+	// maxParams is the maximum number of path variables and
+	// wildcards of a route, or 0 for no limit.
+	maxParams int
+}
+
+// defaultMaxParams is the number of path variables and wildcards
+// a route may have unless set by the mux.
+const defaultMaxParams = 32
+
+// defaultTreeConfig is the configuration of a tree unless set by the mux.
+var defaultTreeConfig = treeConfig{param: ':', wildcard: '*', maxParams: defaultMaxParams}
+
+// constraint is a regular expression a path variable must match.
+type constraint struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// insert inserts a new route into the tree.
+//
+// A route ending in an optional path variable, '/:name?', is inserted
+// both with and without its final path segment.
+//
+// Path variables may be constrained by a regular expression,
+// '/:name(expr)', which the captured value must match entirely.
+//
+// It panics if the route is not valid or conflicts with a registered one.
 func (n *node) insert(key []byte, value HandlerFunc, flags methodFlag) {
+	if err := n.insertRoute(key, key, value, flags, 0); err != nil {
+		panic(err)
+	}
+}
+
+// insertRoute inserts key into the tree like insert, recording route
+// as the pattern matched by it, such as the registered path of a key
+// lowercased for case insensitive routing.
+//
+// A route whose key is registered already shares its leaf if they have
+// different priorities, see share.
+//
+// If the route can't be inserted, a *RouteError is returned and the
+// tree is left unchanged.
+func (n *node) insertRoute(key, route []byte, value HandlerFunc, flags methodFlag, priority int) error {
+	if n.config.param == 0 {
+		n.config = defaultTreeConfig
+	}
+	c := &n.config
+
+	key, constraints, err := c.parseConstraints(key)
+	if err != nil {
+		return routeError(ErrInvalidRoute, route, err.Error())
+	}
+
+	l := len(key)
+	if l == 0 || key[l-1] != '?' {
+		leaf, err := n.add(key, route, value, flags)
+		if err != nil {
+			shared := &node{
+				route:       route,
+				value:       value,
+				leaf:        true,
+				allowed:     flags,
+				constraints: constraints,
+				priority:    priority,
+			}
+			if errors.Is(err, ErrRouteExists) && n.share(key, shared) {
+				return nil
+			}
+			return err
+		}
+		leaf.constraints = constraints
+		leaf.priority = priority
+		return nil
+	}
+
+	key = key[:l-1]
+
+	idx := bytes.LastIndexByte(key, '/')
+	if idx < 0 || idx+1 == len(key) || key[idx+1] != c.param {
+		return routeError(ErrInvalidRoute, route,
+			"optional marker '?' must follow a path variable in the final path segment:\n"+
+				"path: '"+string(route)+"' is not valid.")
+	}
+
+	parent := key[:idx]
+	if len(parent) == 0 {
+		parent = key[:1]
+	}
+
+	present, err := n.add(key, route, value, flags)
+	if err != nil {
+		return err
+	}
+	present.constraints = constraints
+	present.priority = priority
+
+	absent, err := n.add(parent, route, value, flags)
+	if err != nil {
+		n.removeKey(key, route)
+		return err
+	}
+	absent.implicit = true
+
+	// the optional variable is never captured when absent.
+	optional := string(key[idx+2:])
+	for _, c := range constraints {
+		if c.name != optional {
+			absent.constraints = append(absent.constraints, c)
+		}
+	}
+	return nil
+}
+
+// add inserts a new key value pair into the tree and returns its leaf.
+//
+// The route is the path registered by the user, which is set on the
+// request's pattern when the key is matched. If the key can't be added,
+// a *RouteError is returned before the tree is modified.
+func (n *node) add(key, route []byte, value HandlerFunc, flags methodFlag) (*node, error) {
+	c := &n.config
+
 	// validate params
-	params := countParams(key)
+	params := c.countParams(key)
 	if params != 0 {
-		if err := validateParams(key, params); err != nil {
-			panic(err)
+		if err := c.validateParams(key, params); err != nil {
+			return nil, routeError(ErrInvalidRoute, route, err.Error())
 		}
 	}
 
-	insKeyFull := key
+	if c.maxParams > 0 && params > c.maxParams {
+		return nil, routeError(ErrInvalidRoute, route, "too many variables in path:\n'"+
+			string(key)+
+			"'; got["+strconv.Itoa(params)+"]; max["+strconv.Itoa(c.maxParams)+"]")
+	}
+
 	cKeyFull := bytes.NewBuffer(make([]byte, 0, len(key)))
 
 	current := n
@@ -104,18 +271,19 @@ func (n *node) insert(key []byte, value HandlerFunc, flags methodFlag) {
 		child, ok := current.edges.get(firstChar)
 		if !ok {
 			// no matching edge, create a new node
+			leaf := &node{
+				key:     key,
+				route:   route,
+				value:   value,
+				param:   c.countParams(key) != 0,
+				leaf:    true,
+				allowed: flags,
+			}
 			current.edges = current.edges.add(edge{
 				label: firstChar,
-				node: &node{
-					key:     key,
-					route:   insKeyFull,
-					value:   value,
-					param:   countParams(key) != 0,
-					leaf:    true,
-					allowed: flags,
-				},
+				node:  leaf,
 			})
-			return
+			return leaf, nil
 		}
 
 		cKeyLen := len(child.key)
@@ -130,32 +298,42 @@ func (n *node) insert(key []byte, value HandlerFunc, flags methodFlag) {
 
 		// mismatch on param, check for conflict.
 		if child.param && params != 0 {
-			v := (key[prefixLen-1] == ':' && child.key[prefixLen-1] == ':')
-			wc := (key[prefixLen-1] == '*' && child.key[prefixLen-1] == '*')
+			v := (key[prefixLen-1] == c.param && child.key[prefixLen-1] == c.param)
+			wc := (key[prefixLen-1] == c.wildcard && child.key[prefixLen-1] == c.wildcard)
 
 			if v || wc {
 				cKeyFull.Write(child.key)
-				panic("Only one path variable and wildcard can be registered per path segment: \n" +
-					"Route: '" + string(insKeyFull) + "'\n" +
-					"Conflicts with: '" + cKeyFull.String() + "'")
+				return nil, routeError(ErrRouteConflict, route,
+					"Only one path variable and wildcard can be registered per path segment: \n"+
+						"Route: '"+string(route)+"'\n"+
+						"Conflicts with: '"+cKeyFull.String()+"'")
 			}
 		}
 
 		// partial, split and update node
 		splitNode := &node{
-			key:     child.key[prefixLen:],
-			value:   child.value,
-			route:   child.route,
-			param:   child.param,
-			leaf:    child.leaf,
-			edges:   child.edges,
-			allowed: child.allowed,
+			key:      child.key[prefixLen:],
+			value:    child.value,
+			route:    child.route,
+			param:    child.param,
+			leaf:     child.leaf,
+			implicit: child.implicit,
+			edges:    child.edges,
+			allowed:  child.allowed,
+			priority: child.priority,
+			shared:   child.shared,
+
+			constraints: child.constraints,
 		}
 
 		// update child node
 		child.key = child.key[:prefixLen]
 		child.value = nil
 		child.leaf = false
+		child.implicit = false
+		child.constraints = nil
+		child.priority = 0
+		child.shared = nil
 		child.edges = edges{
 			edge{
 				label: splitNode.key[0],
@@ -165,54 +343,365 @@ func (n *node) insert(key []byte, value HandlerFunc, flags methodFlag) {
 
 		// add node for remainder
 		if len(key) > prefixLen {
+			leaf := &node{
+				key:     key[prefixLen:],
+				route:   route,
+				value:   value,
+				param:   c.countParams(key[prefixLen:]) != 0,
+				leaf:    true,
+				allowed: flags,
+			}
 			child.edges = child.edges.add(edge{
-				label: key[prefixLen:][0],
-				node: &node{
-					key:     key[prefixLen:],
-					route:   insKeyFull,
-					value:   value,
-					param:   countParams(key[prefixLen:]) != 0,
-					leaf:    true,
-					allowed: flags,
-				},
+				label: leaf.key[0],
+				node:  leaf,
 			})
-		} else {
-			// no remainder, set value on child
-			child.route = insKeyFull
-			child.value = value
-			child.leaf = true
-			child.allowed = flags
+			return leaf, nil
 		}
-		return
+
+		// no remainder, set value on child
+		child.route = route
+		child.value = value
+		child.leaf = true
+		child.allowed = flags
+		return child, nil
 	}
 
 	if current.leaf || current.value != nil {
-		pc, file, line, _ := runtime.Caller(3)
-		fn := filepath.Base(runtime.FuncForPC(pc).Name())
-
-		panic("Route '" + string(insKeyFull) +
-			"' registered in '" +
-			fmt.Sprintf("%s() %s:%d", fn, file, line) +
+		return nil, routeError(ErrRouteExists, route, "Route '"+string(route)+
+			"' registered in '"+
+			registrationCaller()+
 			"' has previously been registered.")
 	}
 
 	// fix registration bug.
+	current.route = route
 	current.value = value
 	current.leaf = true
+	current.allowed |= flags
+	return current, nil
+}
+
+// share adds route to the leaf at key, registered for a route with the
+// same path variables, and reports whether it was added.
+//
+// Routes sharing a leaf are tried from the highest priority down, the
+// first whose constraints match is served. So a request always matches
+// the same route, they must differ by their constraints and each have
+// a different priority. Routes with an optional final path segment
+// never share a leaf.
+func (n *node) share(key []byte, route *node) bool {
+	_, leaf := n.find(key)
+	if leaf == nil || !leaf.leaf || leaf.implicit || bytes.HasSuffix(leaf.route, []byte("?")) {
+		return false
+	}
+
+	routes := append([]*node{leaf.sharedRoute()}, leaf.shared...)
+	for _, r := range routes {
+		if r.priority == route.priority || sameConstraints(r.constraints, route.constraints) {
+			return false
+		}
+	}
+
+	routes = append(routes, route)
+	slices.SortStableFunc(routes, func(a, b *node) int {
+		return b.priority - a.priority
+	})
+	leaf.setShared(routes)
+	return true
 }
 
-// search returns the longest prefix match for a key.
+// sharedRoute returns the route registered at a leaf.
+func (n *node) sharedRoute() *node {
+	return &node{
+		route:       n.route,
+		value:       n.value,
+		leaf:        true,
+		allowed:     n.allowed,
+		constraints: n.constraints,
+		priority:    n.priority,
+	}
+}
+
+// setShared sets the routes of a leaf, ordered by priority.
+//
+// The slice of shared routes is replaced rather than modified, as it
+// may be shared with a clone of the tree.
+func (n *node) setShared(routes []*node) {
+	top := routes[0]
+	n.route = top.route
+	n.value = top.value
+	n.constraints = top.constraints
+	n.priority = top.priority
+
+	n.shared = nil
+	if len(routes) > 1 {
+		n.shared = routes[1:]
+	}
+}
+
+// sameConstraints reports whether a and b constrain the same path
+// variables with the same expressions.
+func sameConstraints(a, b []constraint) bool {
+	return slices.EqualFunc(a, b, func(x, y constraint) bool {
+		return x.name == y.name && x.re.String() == y.re.String()
+	})
+}
+
+// remove removes a route inserted at key from the tree, and reports
+// whether it was registered.
+//
+// Nodes left with a single child are merged with it, so the tree is
+// shaped as if the route had never been inserted.
+func (n *node) remove(key, route []byte) bool {
+	key, _, err := n.config.parseConstraints(key)
+	if err != nil {
+		return false
+	}
+
+	l := len(key)
+	if l == 0 || key[l-1] != '?' {
+		return n.removeKey(key, route)
+	}
+
+	// remove both leaves of an optional path variable.
+	key = key[:l-1]
+
+	idx := bytes.LastIndexByte(key, '/')
+	if idx < 0 {
+		return false
+	}
+
+	parent := key[:idx]
+	if len(parent) == 0 {
+		parent = key[:1]
+	}
+
+	if !n.removeKey(key, route) {
+		return false
+	}
+	n.removeKey(parent, route)
+	return true
+}
+
+// registered reports whether a route is registered at key, the path of
+// a route as passed to remove.
+func (n *node) registered(key []byte) bool {
+	key, _, err := n.config.parseConstraints(key)
+	if err != nil {
+		return false
+	}
+
+	_, leaf := n.find(key)
+	return leaf != nil && leaf.leaf && !leaf.implicit
+}
+
+// removeKey clears the leaf at key if it was registered for route,
+// and compacts the tree around it.
+func (n *node) removeKey(key, route []byte) bool {
+	parent, current := n.find(key)
+	if parent == nil || !current.leaf {
+		return false
+	}
+
+	if len(current.shared) != 0 {
+		routes := append([]*node{current.sharedRoute()}, current.shared...)
+		idx := slices.IndexFunc(routes, func(r *node) bool {
+			return bytes.Equal(r.route, route)
+		})
+		if idx < 0 {
+			return false
+		}
+
+		current.setShared(slices.Delete(routes, idx, idx+1))
+		return true
+	}
+
+	if !bytes.Equal(current.route, route) {
+		return false
+	}
+
+	current.value = nil
+	current.route = nil
+	current.leaf = false
+	current.implicit = false
+	current.constraints = nil
+	current.priority = 0
+
+	switch len(current.edges) {
+	case 0:
+		parent.edges = parent.edges.remove(current.key[0])
+
+		// the parent may now be redundant, the root is always kept.
+		if parent != n && !parent.leaf && len(parent.edges) == 1 {
+			parent.merge(&n.config)
+		}
+	case 1:
+		current.merge(&n.config)
+	}
+	return true
+}
+
+// find returns the node whose full key is exactly key, and its parent.
+//
+// If there's no such node, both are nil.
+func (n *node) find(key []byte) (parent, current *node) {
+	current = n
+	for len(key) > 0 {
+		child, ok := current.edges.get(key[0])
+		if !ok || !bytes.HasPrefix(key, child.key) {
+			return nil, nil
+		}
+
+		key = key[len(child.key):]
+		parent, current = current, child
+	}
+	return parent, current
+}
+
+// merge merges n with its only child.
+func (n *node) merge(c *treeConfig) {
+	child := n.edges[0].node
+
+	// keys may share memory with the registered paths, so never append.
+	key := make([]byte, 0, len(n.key)+len(child.key))
+	key = append(key, n.key...)
+	key = append(key, child.key...)
+
+	*n = node{
+		key:         key,
+		route:       child.route,
+		param:       c.countParams(key) != 0,
+		leaf:        child.leaf,
+		value:       child.value,
+		edges:       child.edges,
+		allowed:     child.allowed,
+		implicit:    child.implicit,
+		constraints: child.constraints,
+		priority:    child.priority,
+		shared:      child.shared,
+	}
+}
+
+// search returns the handler of the route matching key.
 func (n *node) search(key []byte, r *http.Request) (HandlerFunc, bool) {
+	current, ok := n.match(key, r)
+	return current.value, ok
+}
+
+// allow marks the methods as allowed for the route in a methods tree.
+//
+// A methods tree holds the routes of every method, with the path variables
+// renamed so routes of different methods never conflict. Its leaves record
+// the methods registered for the route.
+func (n *node) allow(route []byte, flags methodFlag) {
+	if n.config.param == 0 {
+		n.config = defaultTreeConfig
+	}
+
+	for _, key := range n.config.methodKeys(route) {
+		if _, leaf := n.find(key); leaf != nil && leaf.leaf {
+			leaf.allowed |= flags
+			continue
+		}
+		if _, err := n.add(key, key, nil, flags); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// disallow removes the methods allowed for the route from a methods tree,
+// removing the route once no method is allowed.
+func (n *node) disallow(route []byte, flags methodFlag) {
+	for _, key := range n.config.methodKeys(route) {
+		_, leaf := n.find(key)
+		if leaf == nil || !leaf.leaf {
+			continue
+		}
+
+		if leaf.allowed &^= flags; leaf.allowed == 0 {
+			n.removeKey(key, key)
+		}
+	}
+}
+
+// allowedMethods returns the methods of every route in a methods tree
+// matching key. The constraints of the routes are not checked.
+//
+// Unlike match, static edges, path variables and wildcards are all tried
+// at each node, as the tree of a single method may only hold one of them.
+// If a route is found behind a path variable or wildcard that has an edge
+// of a higher priority beside it, the tree of a method may not match key
+// the same way, so the result is reported as not exact and the methods
+// must be checked against their own trees.
+func (n *node) allowedMethods(key []byte) (methodFlag, bool) {
+	return n.config.matchAll(n, key, key)
+}
+
+// matchAll returns the methods of the leaves below n matching key,
+// the remainder of path, backtracking through every edge. It reports
+// whether the result is exact, see allowedMethods.
+func (c *treeConfig) matchAll(n *node, key, path []byte) (methodFlag, bool) {
+	var flags methodFlag
+	if len(key) == 0 && n.leaf {
+		flags = n.allowed
+	}
+
+	// edges in the order match tries them, an empty remainder can
+	// only be matched by a wildcard.
+	labels := [3]byte{c.wildcard, c.param, c.wildcard}
+	if len(key) != 0 {
+		labels[0] = key[0]
+	}
+
+	exact := true
+	shadowed := false
+	var tried [3]*node
+	for i, label := range labels {
+		child, ok := n.edges.get(label)
+		if !ok || slices.Contains(tried[:i], child) {
+			continue
+		}
+		tried[i] = child
+
+		var f methodFlag
+		var e bool
+		if l := len(child.key); len(key) >= l && prefixLength(key[:l], child.key) == l {
+			f, e = c.matchAll(child, key[l:], path)
+		} else if child.param {
+			prefixLen := prefixLength(key, child.key)
+			if lastIdx, ok := c.parseParams(child.key[prefixLen:], key[prefixLen:], path, nil); ok {
+				f, e = c.matchAll(child, key[prefixLen+lastIdx:], path)
+			}
+		}
+
+		if f != 0 {
+			flags |= f
+			exact = exact && e && !shadowed && len(key) != 0
+		}
+		shadowed = true
+	}
+	return flags, exact
+}
+
+// match returns the longest prefix match for a key, and reports whether
+// it's a leaf matching the full key.
+//
+// If r is non-nil, the path values of the match are set on r and the
+// constraints of the leaf are checked.
+func (n *node) match(key []byte, r *http.Request) (*node, bool) {
+	c := &n.config
+	path := key
 	current := n
 	keyLen := len(key)
+	params := 0
 	for keyLen > 0 {
 		firstChar := key[0]
 		child, ok := current.edges.get(firstChar)
 		if !ok {
 			// edge case: check if we're about to match a param
-			if child, ok = current.edges.get(':'); !ok {
+			if child, ok = current.edges.get(c.param); !ok {
 				// edgier case: check if we're about to match a wildcard
-				if child, ok = current.edges.get('*'); !ok {
+				if child, ok = current.edges.get(c.wildcard); !ok {
 					break
 				}
 			}
@@ -230,10 +719,19 @@ func (n *node) search(key []byte, r *http.Request) (HandlerFunc, bool) {
 		// check param match
 		if child.param {
 			prefixLen := prefixLength(key, child.key)
-			lastIdx, ok := parseParams(child.key[prefixLen:], key[prefixLen:], r)
+
+			// bail out of trees holding routes over the limit,
+			// such as ones combined from other muxes.
+			if c.maxParams > 0 {
+				if params += c.countParams(child.key[prefixLen:]); params > c.maxParams {
+					return current, false
+				}
+			}
+
+			lastIdx, ok := c.parseParams(child.key[prefixLen:], key[prefixLen:], path, r)
 			if !ok {
 				// no possible match, early return
-				return current.value, false
+				return current, false
 			}
 
 			current = child
@@ -243,7 +741,7 @@ func (n *node) search(key []byte, r *http.Request) (HandlerFunc, bool) {
 			if keyLen > 0 {
 				if len(child.edges) == 0 {
 					// path has unmatched remainder and no edges, not a match.
-					return current.value, false
+					return current, false
 				}
 				continue
 			}
@@ -254,43 +752,61 @@ func (n *node) search(key []byte, r *http.Request) (HandlerFunc, bool) {
 
 	// if the key hasn't been fully consumed, it's not a match.
 	if keyLen != 0 {
-		return current.value, false
+		return current, false
 	}
 
 	if r != nil {
+		leaf, ok := current.constrained(r)
+		if !ok {
+			return current, false
+		}
+
+		current = leaf
 		r.Pattern = toString(current.route)
 	}
 
-	return current.value, current.leaf
+	return current, current.leaf
 }
 
-// getNode returns the node for the provided key.
-//
-// If no node is found, it returns nil.
-func (n *node) getNode(key []byte) *node {
-	current := n
-	keyLen := len(key)
-	for keyLen > 0 {
-		firstChar := key[0]
-		child, ok := current.edges.get(firstChar)
-		if !ok {
-			// no node found.
-			return nil
-		}
+// constrained returns the route of a leaf whose constraints match the
+// path values of r, trying the routes sharing the leaf by priority.
+func (n *node) constrained(r *http.Request) (*node, bool) {
+	if n.matchConstraints(r) {
+		return n, true
+	}
 
-		// check full match
-		cKeyLen := len(child.key)
-		if keyLen >= cKeyLen && prefixLength(key[:cKeyLen], child.key) == cKeyLen {
-			key = key[cKeyLen:]
-			keyLen -= cKeyLen
-			current = child
-			continue
+	for _, s := range n.shared {
+		if s.matchConstraints(r) {
+			return s, true
 		}
+	}
+	return n, false
+}
 
-		break
+// matchConstraints reports whether the path values of r match the
+// constraints of a route.
+func (n *node) matchConstraints(r *http.Request) bool {
+	for _, c := range n.constraints {
+		if !c.re.MatchString(r.PathValue(c.name)) {
+			return false
+		}
 	}
+	return true
+}
 
-	return current
+// clone returns a deep copy of the tree rooted at n.
+//
+// Keys, routes and constraints are shared, as they are never
+// modified in place.
+func (n *node) clone() *node {
+	c := *n
+	if n.edges != nil {
+		c.edges = make(edges, len(n.edges))
+		for i, e := range n.edges {
+			c.edges[i] = edge{label: e.label, node: e.node.clone()}
+		}
+	}
+	return &c
 }
 
 // print recursively prints the tree nodes.
@@ -312,8 +828,11 @@ func (n *node) collectRoutes(routes *[]string) {
 		return
 	}
 
-	if n.leaf {
+	if n.leaf && !n.implicit {
 		*routes = append(*routes, string(n.route))
+		for _, s := range n.shared {
+			*routes = append(*routes, string(s.route))
+		}
 	}
 
 	for _, child := range n.edges {
@@ -321,6 +840,46 @@ func (n *node) collectRoutes(routes *[]string) {
 	}
 }
 
+// collectStats recursively adds the nodes of the tree at depth to s.
+func (n *node) collectStats(s *MethodStats, depth int) {
+	if n == nil {
+		return
+	}
+
+	s.Nodes++
+	s.MaxDepth = max(s.MaxDepth, depth)
+	if n.leaf {
+		s.Leaves++
+		if !n.implicit {
+			s.Routes += 1 + len(n.shared)
+		}
+	}
+
+	for _, child := range n.edges {
+		child.node.collectStats(s, depth+1)
+	}
+}
+
+// walk recursively calls fn for each registered route in the tree.
+//
+// Routes with an optional final path segment are only visited once.
+func (n *node) walk(fn func(route []byte, value HandlerFunc, priority int)) {
+	if n == nil {
+		return
+	}
+
+	if n.leaf && !n.implicit {
+		fn(n.route, n.value, n.priority)
+		for _, s := range n.shared {
+			fn(s.route, s.value, s.priority)
+		}
+	}
+
+	for _, child := range n.edges {
+		child.node.walk(fn)
+	}
+}
+
 // prefixLength calculates the common prefix length between s1 and s2.
 func prefixLength(s1, s2 []byte) (length int) {
 	l := len(s1)
@@ -335,8 +894,12 @@ func prefixLength(s1, s2 []byte) (length int) {
 // ----------------------------------------------------------------------
 // params
 
-// parseParams sets the path value for any registered path variables in b.
-func parseParams(b []byte, path []byte, r *http.Request) (int, bool) {
+// parseParams sets the path value for any registered path variables in b,
+// matched against path, the remainder of the full request path.
+//
+// A wildcard matching an empty remainder captures "/", or "" if
+// emptyWildcard is set.
+func (c *treeConfig) parseParams(b, path, full []byte, r *http.Request) (int, bool) {
 	lenB := len(b)
 	lenPath := len(path)
 
@@ -344,16 +907,22 @@ func parseParams(b []byte, path []byte, r *http.Request) (int, bool) {
 	if lenPath == 0 {
 		paramStart := 0
 		switch {
-		case isWildCard(b, 0, lenB):
+		case c.isWildCard(b, 0, lenB):
 			paramStart++
-		case isWildCard(b, 1, lenB):
+		case c.isWildCard(b, 1, lenB):
 			paramStart += 2
 		default:
 			return 0, false
 		}
 		paramName := b[paramStart:lenB]
 
-		r.SetPathValue(toString(paramName), "/")
+		if r != nil {
+			value := "/"
+			if c.emptyWildcard {
+				value = ""
+			}
+			r.SetPathValue(toString(paramName), value)
+		}
 
 		return 0, true
 	}
@@ -364,7 +933,7 @@ func parseParams(b []byte, path []byte, r *http.Request) (int, bool) {
 		char := b[i]
 
 		// check for param token
-		if char == ':' {
+		if char == c.param {
 			paramStart := i + 1
 			paramEnd := paramStart
 			for paramEnd < lenB && b[paramEnd] != '/' {
@@ -410,18 +979,22 @@ func parseParams(b []byte, path []byte, r *http.Request) (int, bool) {
 	}
 
 	// wildcard is the unlikely case, so check this last.
-	if isWildCard(b, i, lenB) {
+	if c.isWildCard(b, i, lenB) {
 		paramStart := i + 1
 		paramEnd := lenB
 
 		if r != nil && j < lenPath {
 			paramName := toString(b[paramStart:paramEnd])
 
-			// grab the path value
-			wcValue := make([]byte, 1+lenPath-j)
-			wcValue[0] = '/'
-			copy(wcValue[1:], path[j:])
-			r.SetPathValue(paramName, toString(wcValue))
+			// the value includes the '/' before the wildcard, slice it
+			// from the full path instead of allocating if it's there.
+			var wcValue string
+			if start := len(full) - lenPath + j - 1; start >= 0 && full[start] == '/' {
+				wcValue = toString(full[start:])
+			} else {
+				wcValue = "/" + toString(path[j:])
+			}
+			r.SetPathValue(paramName, wcValue)
 		}
 
 		// wildcards consume the rest of the path.
@@ -435,16 +1008,124 @@ func parseParams(b []byte, path []byte, r *http.Request) (int, bool) {
 	return j, (path[j-1] == b[i-1] && lenPath-1 != j-1)
 }
 
-func validateParams(b []byte, total int) error {
+// parseConstraints removes the regular expression constraints from the
+// path variables in b, returning the stripped path and the compiled
+// constraints.
+//
+// If b has no constraints, it is returned as is.
+func (c *treeConfig) parseConstraints(b []byte) ([]byte, []constraint, error) {
+	if bytes.IndexByte(b, '(') < 0 {
+		return b, nil, nil
+	}
+
+	var constraints []constraint
+	stripped := make([]byte, 0, len(b))
+
+	lenB := len(b)
+	for i := 0; i < lenB; i++ {
+		stripped = append(stripped, b[i])
+		if b[i] != c.param {
+			continue
+		}
+
+		nameStart := i + 1
+		nameEnd := nameStart
+		for nameEnd < lenB && b[nameEnd] != '/' && b[nameEnd] != '(' {
+			nameEnd++
+		}
+		stripped = append(stripped, b[nameStart:nameEnd]...)
+		i = nameEnd - 1
+
+		if nameEnd == lenB || b[nameEnd] != '(' {
+			continue
+		}
+
+		end := constraintEnd(b, nameEnd)
+		if end < 0 {
+			return nil, nil, errors.New("unterminated constraint for variable in path:\n" +
+				"path: '" + string(b) + "' is not valid.")
+		}
+
+		re, err := regexp.Compile("^(?:" + string(b[nameEnd+1:end]) + ")$")
+		if err != nil {
+			return nil, nil, errors.New("invalid constraint for variable in path:\n" +
+				"path: '" + string(b) + "': " + err.Error())
+		}
+
+		constraints = append(constraints, constraint{string(b[nameStart:nameEnd]), re})
+		i = end
+	}
+
+	return stripped, constraints, nil
+}
+
+// restoreValues copies the bytes of path matched by the path variables
+// and wildcard of pattern from orig to path, which must be the same length.
+func (c *treeConfig) restoreValues(path, orig []byte, pattern string) {
+	i := 0
+	for j := 0; j < len(pattern) && i < len(path); j++ {
+		switch pattern[j] {
+		case c.wildcard:
+			copy(path[i:], orig[i:])
+			return
+		case c.param:
+			// skip the name, constraint and optional marker.
+			for j+1 < len(pattern) && pattern[j+1] != '/' && pattern[j+1] != '(' && pattern[j+1] != '?' {
+				j++
+			}
+
+			if j+1 < len(pattern) && pattern[j+1] == '(' {
+				if j = constraintEnd(toBytes(pattern), j+1); j < 0 {
+					return
+				}
+			}
+
+			if j+1 < len(pattern) && pattern[j+1] == '?' {
+				j++
+			}
+
+			for i < len(path) && orig[i] != '/' {
+				path[i] = orig[i]
+				i++
+			}
+		default:
+			i++
+		}
+	}
+}
+
+// constraintEnd returns the index of the parenthesis closing the
+// constraint opened at b[start], or -1 if it is unterminated.
+func constraintEnd(b []byte, start int) int {
+	depth := 0
+	for i := start; i < len(b); i++ {
+		switch b[i] {
+		case '\\':
+			i++
+		case '(':
+			depth++
+		case ')':
+			depth--
+		}
+
+		if depth == 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+func (c *treeConfig) validateParams(b []byte, total int) error {
 	i, count := 0, 0
 	lenB := len(b)
+	names := make([]string, 0, total)
 	for ; i < lenB; i++ {
 		switch b[i] {
-		case ':':
-			param, _, valid := pathSegment(b, i+1, lenB)
+		case c.param:
+			param, _, valid := c.pathSegment(b, i+1, lenB)
 			if !valid {
 				return errors.New("path variables cannot contain the following characters: {" +
-					"':', '*'" +
+					"'" + string(c.param) + "', '" + string(c.wildcard) + "', '?'" +
 					"}\n" +
 					"path: '" + string(b) + "' is not valid.")
 			}
@@ -452,12 +1133,17 @@ func validateParams(b []byte, total int) error {
 				return errors.New("missing name for variable in path: \n" +
 					"'" + string(b) + "'")
 			}
+			if slices.Contains(names, string(param)) {
+				return errors.New("duplicate variable '" + string(param) + "' in path:\n" +
+					"'" + string(b) + "'")
+			}
+			names = append(names, string(param))
 			count++
-		case '*':
-			param, end, valid := pathSegment(b, i+1, lenB)
+		case c.wildcard:
+			param, end, valid := c.pathSegment(b, i+1, lenB)
 			if !valid {
 				return errors.New("path variables cannot contain the following characters: {" +
-					"':', '*'" +
+					"'" + string(c.param) + "', '" + string(c.wildcard) + "', '?'" +
 					"}\n" +
 					"path: '" + string(b) + "' is not valid.")
 			}
@@ -465,6 +1151,11 @@ func validateParams(b []byte, total int) error {
 				return errors.New("missing name for variable in path: \n" +
 					"'" + string(b) + "'")
 			}
+			if slices.Contains(names, string(param)) {
+				return errors.New("duplicate variable '" + string(param) + "' in path:\n" +
+					"'" + string(b) + "'")
+			}
+			names = append(names, string(param))
 			if end != lenB {
 				return errors.New("wildcard must be set at the end of the path:\n" +
 					"path: '" + string(b) + "' is not valid.")
@@ -484,25 +1175,86 @@ func validateParams(b []byte, total int) error {
 // ----------------------------------------------------------------------
 // Helper methods
 
-func isWildCard(b []byte, idx, l int) bool {
+// registrationCaller returns the function and location of the first caller
+// outside of the package, which is where the route was registered.
+func registrationCaller() string {
+	_, self, _, _ := runtime.Caller(0)
+	dir := filepath.Dir(self)
+
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+	for {
+		frame, more := frames.Next()
+		if filepath.Dir(frame.File) != dir || strings.HasSuffix(frame.File, "_test.go") {
+			return fmt.Sprintf("%s() %s:%d", filepath.Base(frame.Function), frame.File, frame.Line)
+		}
+
+		if !more {
+			return "unknown"
+		}
+	}
+}
+
+// methodKeys returns the keys of the route in a methods tree, without
+// constraints and with its path variables renamed, see anonymize. A route
+// with an optional final path segment has a key with and without the
+// segment.
+func (c *treeConfig) methodKeys(route []byte) [][]byte {
+	key, _, err := c.parseConstraints(route)
+	if err != nil {
+		return nil
+	}
+
+	var keys [][]byte
+	if l := len(key); l > 0 && key[l-1] == '?' {
+		key = key[:l-1]
+
+		parent := key[:max(bytes.LastIndexByte(key, '/'), 1)]
+		keys = append(keys, c.anonymize(parent))
+	}
+	return append(keys, c.anonymize(key))
+}
+
+// anonymize returns a copy of key with the names of its path
+// variables replaced by '_' and their index in the key, which keeps
+// the names unique within the key.
+func (c *treeConfig) anonymize(key []byte) []byte {
+	b := make([]byte, 0, len(key))
+	n := 0
+	for i := 0; i < len(key); i++ {
+		b = append(b, key[i])
+		if key[i] != c.param && key[i] != c.wildcard {
+			continue
+		}
+
+		b = strconv.AppendInt(append(b, '_'), int64(n), 10)
+		n++
+		for i+1 < len(key) && key[i+1] != '/' {
+			i++
+		}
+	}
+	return b
+}
+
+func (c *treeConfig) isWildCard(b []byte, idx, l int) bool {
 	if idx >= l {
 		return false
 	}
-	return b[idx] == '*'
+	return b[idx] == c.wildcard
 }
 
-func countParams(b []byte) (count int) {
+func (c *treeConfig) countParams(b []byte) (count int) {
 	lenB := len(b)
 	for i := 0; i < lenB; i++ {
 		switch b[i] {
-		case ':', '*':
+		case c.param, c.wildcard:
 			count++
 		}
 	}
 	return count
 }
 
-func pathSegment(b []byte, start, length int) ([]byte, int, bool) {
+func (c *treeConfig) pathSegment(b []byte, start, length int) ([]byte, int, bool) {
 	if length == 0 || start >= length {
 		return nil, -1, false
 	}
@@ -512,7 +1264,7 @@ func pathSegment(b []byte, start, length int) ([]byte, int, bool) {
 		switch b[end] {
 		case '/':
 			return b[start:end], end, true
-		case '*', ':':
+		case c.wildcard, c.param, '?':
 			return nil, -1, false
 		}
 	}
@@ -112,7 +112,7 @@ func Test_ParseParams(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			req, _ := http.NewRequest("GET", tt.path, nil)
-			lastIdx, ok := parseParams([]byte(tt.wcPath), toBytes(req.URL.Path), req)
+			lastIdx, ok := defaultTreeConfig.parseParams([]byte(tt.wcPath), toBytes(req.URL.Path), toBytes(req.URL.Path), req)
 			if ok != tt.ok {
 				t.Errorf("expected: [%v]; got [%v]", tt.ok, ok)
 			}
@@ -165,7 +165,7 @@ func Benchmark_ParseParams(b *testing.B) {
 		req, _ := http.NewRequest("GET", tt.path, nil)
 		b.Run(tt.name, func(b *testing.B) {
 			for i := 0; i < b.N; i++ {
-				_, _ = parseParams(toBytes(tt.wcPath), toBytes(req.URL.Path), req)
+				_, _ = defaultTreeConfig.parseParams(toBytes(tt.wcPath), toBytes(req.URL.Path), toBytes(req.URL.Path), req)
 			}
 		})
 	}
@@ -191,7 +191,7 @@ func Test_CountParams(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if count := countParams([]byte(tt.path)); count != tt.total {
+			if count := defaultTreeConfig.countParams([]byte(tt.path)); count != tt.total {
 				t.Errorf("expected: [%d]; got [%d]", tt.total, count)
 			}
 		})
@@ -483,3 +483,295 @@ func Test_Tree(t *testing.T) {
 		}
 	}
 }
+
+func Test_OptionalParam(t *testing.T) {
+	tree := &node{}
+	tree.insert([]byte("/articles/:id?"), emptyHandler, GET)
+	tree.insert([]byte("/articles/:id/comments"), emptyHandler, GET)
+	tree.insert([]byte("/:lang?"), emptyHandler, GET)
+
+	searchTests := []struct {
+		name    string
+		path    string
+		pattern string
+		id      string
+		found   bool
+	}{
+		{"Absent", "/articles", "/articles/:id?", "", true},
+		{"Present", "/articles/42", "/articles/:id?", "42", true},
+		{"Child", "/articles/42/comments", "/articles/:id/comments", "42", true},
+		{"Remainder", "/articles/42/foo", "", "", false},
+		{"RootAbsent", "/", "/:lang?", "", true},
+		{"RootPresent", "/en", "/:lang?", "", true},
+	}
+
+	for _, tt := range searchTests {
+		t.Run(fmt.Sprintf("Search-%s", tt.name), func(t *testing.T) {
+			req := &http.Request{}
+			if _, ok := tree.search([]byte(tt.path), req); ok != tt.found {
+				t.Errorf("expected: [%v]; got: [%v]", tt.found, ok)
+			}
+
+			if tt.found && req.Pattern != tt.pattern {
+				t.Errorf("expected pattern: [%s]; got: [%s]", tt.pattern, req.Pattern)
+			}
+
+			if id := req.PathValue("id"); tt.found && id != tt.id {
+				t.Errorf("expected id: [%s]; got: [%s]", tt.id, id)
+			}
+		})
+	}
+
+	var routes []string
+	tree.collectRoutes(&routes)
+	if len(routes) != 3 {
+		t.Errorf("expected optional routes to be collected once; got: %v", routes)
+	}
+
+	insertTests := []struct {
+		name string
+		path string
+	}{
+		{"MidPath", "/articles/:id?/comments"},
+		{"Static", "/articles?"},
+		{"Wildcard", "/files/*file?"},
+		{"Duplicate", "/articles"},
+	}
+
+	for _, tt := range insertTests {
+		t.Run(fmt.Sprintf("Insert-%s", tt.name), func(t *testing.T) {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Errorf("no panic when one was expected.")
+				}
+			}()
+
+			tree.insert([]byte(tt.path), emptyHandler, GET)
+		})
+	}
+}
+
+func Test_ParamConstraints(t *testing.T) {
+	tree := &node{}
+	tree.insert([]byte(`/user/:id(\d+)`), emptyHandler, GET)
+	tree.insert([]byte(`/user/:id(\d+)/posts/:slug([a-z-]+)`), emptyHandler, GET)
+	tree.insert([]byte(`/files/:name(.*\.(txt|md))`), emptyHandler, GET)
+	tree.insert([]byte(`/pages/:page([0-9]{1,3})?`), emptyHandler, GET)
+
+	searchTests := []struct {
+		name    string
+		path    string
+		pattern string
+		found   bool
+	}{
+		{"Match", "/user/42", `/user/:id(\d+)`, true},
+		{"NoMatch", "/user/abc", "", false},
+		{"NestedMatch", "/user/42/posts/hello-world", `/user/:id(\d+)/posts/:slug([a-z-]+)`, true},
+		{"NestedNoMatch", "/user/42/posts/Hello", "", false},
+		{"NestedParentNoMatch", "/user/abc/posts/hello", "", false},
+		{"Group", "/files/notes.md", `/files/:name(.*\.(txt|md))`, true},
+		{"GroupNoMatch", "/files/notes.pdf", "", false},
+		{"OptionalAbsent", "/pages", `/pages/:page([0-9]{1,3})?`, true},
+		{"OptionalMatch", "/pages/12", `/pages/:page([0-9]{1,3})?`, true},
+		{"OptionalNoMatch", "/pages/1234", "", false},
+	}
+
+	for _, tt := range searchTests {
+		t.Run(fmt.Sprintf("Search-%s", tt.name), func(t *testing.T) {
+			req := &http.Request{}
+			if _, ok := tree.search([]byte(tt.path), req); ok != tt.found {
+				t.Errorf("expected: [%v]; got: [%v]", tt.found, ok)
+			}
+
+			if req.Pattern != tt.pattern {
+				t.Errorf("expected pattern: [%s]; got: [%s]", tt.pattern, req.Pattern)
+			}
+		})
+	}
+
+	insertTests := []struct {
+		name string
+		path string
+	}{
+		{"Unterminated", `/bad/:id(\d+`},
+		{"InvalidRegexp", `/bad/:id([)`},
+		{"MissingName", `/bad/:(\d+)`},
+	}
+
+	for _, tt := range insertTests {
+		t.Run(fmt.Sprintf("Insert-%s", tt.name), func(t *testing.T) {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Errorf("no panic when one was expected.")
+				}
+			}()
+
+			tree.insert([]byte(tt.path), emptyHandler, GET)
+		})
+	}
+}
+
+// treeString returns the keys and leaves of the tree, one node per line.
+func treeString(n *node, level int) string {
+	s := strings.Repeat(" ", level*2) + string(n.key)
+	if n.leaf {
+		s += " (" + string(n.route) + ")"
+	}
+	s += "\n"
+
+	for _, e := range n.edges {
+		s += treeString(e.node, level+1)
+	}
+	return s
+}
+
+func Test_TreeRemove(t *testing.T) {
+	routes := []string{
+		"/",
+		"/users",
+		"/users/:id",
+		"/users/:id/posts",
+		"/users/new",
+		"/userspace",
+		`/tags/:tag([a-z]+)`,
+		"/files/*file",
+		"/items/:id?",
+	}
+
+	tests := []struct {
+		name    string
+		remove  string
+		removed bool
+	}{
+		{"Root", "/", true},
+		{"SharedPrefix", "/users", true},
+		{"ParamWithChild", "/users/:id", true},
+		{"ParamChild", "/users/:id/posts", true},
+		{"Sibling", "/users/new", true},
+		{"LongerSibling", "/userspace", true},
+		{"Constraint", `/tags/:tag([a-z]+)`, true},
+		{"Wildcard", "/files/*file", true},
+		{"Optional", "/items/:id?", true},
+		{"NotRegistered", "/users/old", false},
+		{"Prefix", "/user", false},
+		{"ImplicitLeaf", "/items", false},
+		{"WrongConstraint", `/tags/:tag(\d+)`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := &node{}
+			want := &node{}
+			for _, r := range routes {
+				tree.insert([]byte(r), emptyHandler, GET)
+				if r != tt.remove {
+					want.insert([]byte(r), emptyHandler, GET)
+				}
+			}
+
+			if removed := tree.remove([]byte(tt.remove), []byte(tt.remove)); removed != tt.removed {
+				t.Fatalf("expected: [%v]; got: [%v]", tt.removed, removed)
+			}
+
+			// the tree is compacted as if the route was never inserted.
+			if got, exp := treeString(tree, 0), treeString(want, 0); got != exp {
+				t.Errorf("expected:\n%s\ngot:\n%s", exp, got)
+			}
+
+			for _, r := range routes {
+				if r == tt.remove {
+					continue
+				}
+
+				// routes are searched with their path values filled in.
+				path := strings.NewReplacer(":id?", "1", ":id", "1", `:tag([a-z]+)`, "go", "*file", "a.txt").Replace(r)
+				req, _ := http.NewRequest("GET", path, nil)
+				if _, found := tree.search([]byte(path), req); !found {
+					t.Errorf("expected route [%s] to match [%s]", r, path)
+				}
+			}
+		})
+	}
+}
+
+func Test_AllowedMethodsTree(t *testing.T) {
+	tree := &node{}
+	tree.allow([]byte("/users/:id"), GET)
+	tree.allow([]byte("/users/:name"), PUT)
+	tree.allow([]byte(`/users/:id(\d+)/posts`), POST)
+	tree.allow([]byte("/articles/:page?"), GET)
+	tree.allow([]byte("/files/*path"), DELETE)
+	tree.allow([]byte("/users/new/edit"), PATCH)
+
+	tests := []struct {
+		name    string
+		path    string
+		allowed methodFlag
+		exact   bool
+	}{
+		{"ParamNames", "/users/42", GET | PUT, true},
+		{"Constraint", "/users/abc/posts", POST, true},
+		{"OptionalParent", "/articles", GET, true},
+		{"Optional", "/articles/2", GET, true},
+		{"Wildcard", "/files/a/b", DELETE, true},
+		{"EmptyWildcard", "/files/", DELETE, true},
+		{"Backtrack", "/users/new", GET | PUT, false},
+		{"NotFound", "/posts", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed, exact := tree.allowedMethods([]byte(tt.path))
+			if allowed != tt.allowed {
+				t.Errorf("expected: [%v]; got: [%v]", tt.allowed, allowed)
+			}
+
+			if exact != tt.exact {
+				t.Errorf("expected: [%v]; got: [%v]", tt.exact, exact)
+			}
+		})
+	}
+
+	t.Run("Disallow", func(t *testing.T) {
+		tree.disallow([]byte("/users/:name"), PUT)
+		if allowed, _ := tree.allowedMethods([]byte("/users/42")); allowed != GET {
+			t.Errorf("expected: [%v]; got: [%v]", GET, allowed)
+		}
+
+		tree.disallow([]byte("/articles/:page?"), GET)
+		for _, path := range []string{"/articles", "/articles/2"} {
+			if allowed, _ := tree.allowedMethods([]byte(path)); allowed != 0 {
+				t.Errorf("expected: [%v]; got: [%v]", methodFlag(0), allowed)
+			}
+		}
+	})
+}
+
+func Test_MaxParamsSearch(t *testing.T) {
+	tree := &node{config: treeConfig{param: ':', wildcard: '*'}}
+	tree.insert([]byte("/:a/:b/:c"), emptyHandler, GET)
+	tree.insert([]byte("/static/:a"), emptyHandler, GET)
+
+	tests := []struct {
+		name      string
+		maxParams int
+		path      string
+		found     bool
+	}{
+		{"NoLimit", 0, "/x/y/z", true},
+		{"AtLimit", 3, "/x/y/z", true},
+		{"OverLimit", 2, "/x/y/z", false},
+		{"OtherRoute", 2, "/static/x", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree.config.maxParams = tt.maxParams
+
+			req, _ := http.NewRequest("GET", tt.path, nil)
+			if _, found := tree.search([]byte(tt.path), req); found != tt.found {
+				t.Errorf("expected: [%v]; got: [%v]", tt.found, found)
+			}
+		})
+	}
+}
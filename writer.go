@@ -0,0 +1,175 @@
+// Copyright 2025 Brandon Epperson
+// SPDX-License-Identifier: Apache-2.0
+
+package roxi
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+)
+
+// Stats describes the response written by a handler, see WrapWriter.
+type Stats struct {
+	// Status is the status code of the response, or 0 if the
+	// handler has not written one yet.
+	Status int
+
+	// Bytes is the number of body bytes written.
+	Bytes int
+}
+
+// status returns the status the mux responds with when the handler
+// returns err: the written status, the code of an *AbortError,
+// 500 Internal Server Error for other errors, or 200 OK.
+func (s *Stats) status(err error) int {
+	if s.Status != 0 {
+		return s.Status
+	}
+
+	if err != nil {
+		var ae *AbortError
+		if errors.As(err, &ae) {
+			return ae.Code
+		}
+		return http.StatusInternalServerError
+	}
+	return http.StatusOK
+}
+
+// WrapWriter replaces the http.ResponseWriter in the context with one that
+// records the response status and body size to the returned *Stats, which
+// can be read after the handler returns:
+//
+//	ctx, stats := roxi.WrapWriter(ctx)
+//	err := next(ctx, r)
+//	log.Println(stats.Status, stats.Bytes)
+//
+// The writer supports flushing and hijacking if the wrapped writer does,
+// and passes io.ReaderFrom through to it.
+func WrapWriter(ctx context.Context) (context.Context, *Stats) {
+	sw := &statsWriter{ResponseWriter: GetWriter(ctx)}
+	return SetWriter(ctx, sw), &sw.stats
+}
+
+// Hijack takes over the connection of the writer in the context, such as
+// to upgrade the request to a websocket.
+//
+// The writers installed by Compress, WrapWriter, ServerTimingMiddleware
+// and the mux itself implement Unwrap, so the connection is reached
+// through any of them with http.ResponseController. Writers set with
+// SetWriter should implement Unwrap as well. Timeout's writer can't be
+// hijacked.
+//
+// The hijack is recorded by the writers of Compress, WrapWriter and
+// ServerTimingMiddleware, which don't write anything afterwards, so a
+// handler can return nil once it's done with the connection.
+//
+// The returned error wraps http.ErrNotSupported if the writer can't be
+// hijacked, or there's no writer in the context.
+func Hijack(ctx context.Context) (net.Conn, *bufio.ReadWriter, error) {
+	w := GetWriter(ctx)
+	if w == nil {
+		return nil, nil, fmt.Errorf("hijack: no writer in context: %w", http.ErrNotSupported)
+	}
+	return http.NewResponseController(w).Hijack()
+}
+
+// statsWriter is a http.ResponseWriter that records the Stats
+// of the response.
+type statsWriter struct {
+	http.ResponseWriter
+	stats Stats
+
+	// hijacked is set once the connection has been taken over, after
+	// which nothing is written.
+	hijacked bool
+}
+
+// WriteHeader implements the http.ResponseWriter interface.
+func (w *statsWriter) WriteHeader(code int) {
+	if w.hijacked {
+		return
+	}
+
+	// informational responses are followed by the final status.
+	if w.stats.Status == 0 && (code < 100 || code >= 200) {
+		w.stats.Status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write implements the http.ResponseWriter interface.
+func (w *statsWriter) Write(b []byte) (int, error) {
+	if w.hijacked {
+		return 0, http.ErrHijacked
+	}
+
+	if w.stats.Status == 0 {
+		w.stats.Status = http.StatusOK
+	}
+
+	n, err := w.ResponseWriter.Write(b)
+	w.stats.Bytes += n
+	return n, err
+}
+
+// ReadFrom implements the io.ReaderFrom interface, so io.Copy keeps using
+// the wrapped writer's ReadFrom, such as the sendfile path of net/http.
+func (w *statsWriter) ReadFrom(src io.Reader) (int64, error) {
+	if w.hijacked {
+		return 0, http.ErrHijacked
+	}
+
+	if w.stats.Status == 0 {
+		w.stats.Status = http.StatusOK
+	}
+
+	var n int64
+	var err error
+	if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(src)
+	} else {
+		n, err = io.Copy(w.ResponseWriter, src)
+	}
+	w.stats.Bytes += int(n)
+	return n, err
+}
+
+// Flush implements the http.Flusher interface.
+func (w *statsWriter) Flush() {
+	_ = w.FlushError()
+}
+
+// FlushError flushes the response like Flush, returning an error wrapping
+// http.ErrNotSupported if the wrapped writer can't be flushed. It is
+// used by http.ResponseController.
+func (w *statsWriter) FlushError() error {
+	if w.hijacked {
+		return http.ErrHijacked
+	}
+
+	if w.stats.Status == 0 {
+		w.stats.Status = http.StatusOK
+	}
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Hijack implements the http.Hijacker interface.
+func (w *statsWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil {
+		w.hijacked = true
+	}
+	return conn, rw, err
+}
+
+// Unwrap returns the underlying http.ResponseWriter for use
+// with http.ResponseController.
+func (w *statsWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,226 @@
+// Copyright 2025 Brandon Epperson
+// SPDX-License-Identifier: Apache-2.0
+
+package roxi
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_WrapWriter(t *testing.T) {
+	tests := []struct {
+		name   string
+		h      HandlerFunc
+		status int
+		bytes  int
+	}{
+		{"Nothing", func(ctx context.Context, r *http.Request) error { return nil }, 0, 0},
+		{"Write", func(ctx context.Context, r *http.Request) error {
+			_, err := GetWriter(ctx).Write([]byte("hello"))
+			return err
+		}, http.StatusOK, 5},
+		{"WriteHeader", func(ctx context.Context, r *http.Request) error {
+			GetWriter(ctx).WriteHeader(http.StatusNotFound)
+			_, err := GetWriter(ctx).Write([]byte("missing"))
+			return err
+		}, http.StatusNotFound, 7},
+		{"Informational", func(ctx context.Context, r *http.Request) error {
+			GetWriter(ctx).WriteHeader(http.StatusEarlyHints)
+			GetWriter(ctx).WriteHeader(http.StatusCreated)
+			return nil
+		}, http.StatusCreated, 0},
+		{"Respond", func(ctx context.Context, r *http.Request) error {
+			return Respond(ctx, JSON(http.StatusAccepted, []int{1, 2}))
+		}, http.StatusAccepted, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stats *Stats
+
+			mux := New()
+			mux.GET("/", tt.h, func(next HandlerFunc) HandlerFunc {
+				return func(ctx context.Context, r *http.Request) error {
+					ctx, stats = WrapWriter(ctx)
+					return next(ctx, r)
+				}
+			})
+			mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+			if stats.Status != tt.status {
+				t.Errorf("expected status: [%d]; got: [%d]", tt.status, stats.Status)
+			}
+
+			if stats.Bytes != tt.bytes {
+				t.Errorf("expected bytes: [%d]; got: [%d]", tt.bytes, stats.Bytes)
+			}
+		})
+	}
+}
+
+// hijackRecorder is a httptest.ResponseRecorder that can be hijacked.
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+	hijacked bool
+}
+
+func (w *hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.hijacked = true
+	return nil, nil, nil
+}
+
+func Test_WrapWriterPassthrough(t *testing.T) {
+	rec := &hijackRecorder{ResponseRecorder: httptest.NewRecorder()}
+	ctx, stats := WrapWriter(SetWriter(context.Background(), rec))
+	w := GetWriter(ctx)
+
+	if _, ok := w.(http.Flusher); !ok {
+		t.Fatal("expected writer to implement http.Flusher")
+	}
+
+	w.(http.Flusher).Flush()
+	if !rec.Flushed {
+		t.Error("expected flush to reach the underlying writer")
+	}
+
+	if stats.Status != http.StatusOK {
+		t.Errorf("expected: [%d]; got: [%d]", http.StatusOK, stats.Status)
+	}
+
+	if _, _, err := w.(http.Hijacker).Hijack(); err != nil || !rec.hijacked {
+		t.Errorf("expected hijack to reach the underlying writer; got: [%v]", err)
+	}
+
+	// writers that can't be hijacked report it.
+	ctx, _ = WrapWriter(SetWriter(context.Background(), httptest.NewRecorder()))
+	if _, _, err := GetWriter(ctx).(http.Hijacker).Hijack(); !errors.Is(err, http.ErrNotSupported) {
+		t.Errorf("expected: [%v]; got: [%v]", http.ErrNotSupported, err)
+	}
+}
+
+func Test_Hijack(t *testing.T) {
+	var hijackErr error
+	handler := func(ctx context.Context, r *http.Request) error {
+		_, _, hijackErr = Hijack(ctx)
+		return nil
+	}
+	stats := func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, r *http.Request) error {
+			ctx, _ = WrapWriter(ctx)
+			return next(ctx, r)
+		}
+	}
+
+	tests := []struct {
+		name string
+		mw   []MiddlewareFunc
+	}{
+		{"Unwrapped", nil},
+		{"Compress", []MiddlewareFunc{Compress()}},
+		{"Stacked", []MiddlewareFunc{stats, ServerTimingMiddleware, Compress(), stats}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := New()
+			mux.GET("/ws", handler, tt.mw...)
+
+			r := httptest.NewRequest("GET", "/ws", nil)
+			r.Header.Set("Accept-Encoding", "gzip")
+			rec := &hijackRecorder{ResponseRecorder: httptest.NewRecorder()}
+
+			hijackErr = errors.New("not called")
+			mux.ServeHTTP(rec, r)
+
+			if hijackErr != nil || !rec.hijacked {
+				t.Errorf("expected hijack to reach the underlying writer; got: [%v]", hijackErr)
+			}
+		})
+	}
+
+	if _, _, err := Hijack(context.Background()); !errors.Is(err, http.ErrNotSupported) {
+		t.Errorf("expected: [%v]; got: [%v]", http.ErrNotSupported, err)
+	}
+}
+
+func Test_HijackServer(t *testing.T) {
+	handler := func(ctx context.Context, r *http.Request) error {
+		conn, rw, err := Hijack(ctx)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		_, _ = rw.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 2\r\nConnection: close\r\n\r\nok")
+		return rw.Flush()
+	}
+	stats := func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, r *http.Request) error {
+			ctx, _ = WrapWriter(ctx)
+			return next(ctx, r)
+		}
+	}
+
+	mux := New(WithLogger(func(string, ...any) {}))
+	mux.GET("/compress", handler, Compress())
+	mux.GET("/stacked", handler, stats, ServerTimingMiddleware, Compress(), stats)
+	mux.GET("/panic", func(ctx context.Context, r *http.Request) error {
+		if err := handler(ctx, r); err != nil {
+			return err
+		}
+		panic("after hijack")
+	}, Compress())
+
+	var logs bytes.Buffer
+	done := make(chan struct{}, 1)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() { done <- struct{}{} }()
+		mux.ServeHTTP(w, r)
+	}))
+	srv.Config.ErrorLog = log.New(&logs, "", 0)
+	srv.Start()
+	defer srv.Close()
+
+	tests := []struct {
+		name string
+		path string
+	}{
+		{"Compress", "/compress"},
+		{"Stacked", "/stacked"},
+		{"Panic", "/panic"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs.Reset()
+
+			r, _ := http.NewRequest("GET", srv.URL+tt.path, nil)
+			r.Header.Set("Accept-Encoding", "gzip")
+
+			resp, err := http.DefaultClient.Do(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			<-done
+
+			if string(body) != "ok" {
+				t.Errorf("expected: [%s]; got: [%s]", "ok", body)
+			}
+
+			if logs.Len() != 0 {
+				t.Errorf("expected no server errors; got: [%s]", logs.String())
+			}
+		})
+	}
+}